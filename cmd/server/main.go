@@ -1,46 +1,111 @@
 package main
 
 import (
+	"context"
 	"html/template"
 	"log"
 	"net/http"
+	"os"
 
+	"github.com/g-linville/budgeting/internal/attachments"
+	"github.com/g-linville/budgeting/internal/backup"
+	"github.com/g-linville/budgeting/internal/config"
+	"github.com/g-linville/budgeting/internal/currency"
 	"github.com/g-linville/budgeting/internal/database"
 	"github.com/g-linville/budgeting/internal/handlers"
+	"github.com/g-linville/budgeting/internal/trash"
 	"github.com/g-linville/budgeting/internal/utils"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 )
 
 func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+
+	// Subcommands
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrate(cfg, os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "rates" {
+		runRates(cfg, os.Args[2:])
+		return
+	}
+
 	// Initialize database
-	db, err := database.InitDB("./budgeting.db")
+	db, err := database.InitDB(cfg.DBDriver, cfg.DBDSN)
 	if err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
 
+	// Start periodic backups (SQLite only; use pg_dump for PostgreSQL)
+	var backups *backup.Manager
+	if cfg.BackupDir != "" && database.IsSQLite(db) {
+		backups = backup.NewManager(db, cfg.BackupDir, cfg.BackupInterval, backup.Retention{
+			Daily:   cfg.BackupKeepDaily,
+			Weekly:  cfg.BackupKeepWeekly,
+			Monthly: cfg.BackupKeepMonthly,
+		})
+		go backups.Run(context.Background())
+	}
+
+	// Permanently remove expired trash
+	go trash.Run(context.Background(), db, cfg.TrashRetention)
+
+	// Attachment files are removed once no expense refers to them
+	attachmentStore := attachments.NewStore(cfg.AttachmentDir, int64(cfg.AttachmentMaxMB)<<20)
+	go attachmentStore.Run(context.Background(), db)
+
 	// Parse templates with custom functions
 	funcMap := template.FuncMap{
-		"formatCents": utils.CentsToUSD,
-		"divf": func(a int, b float64) float64 {
-			return float64(a) / b
+		// Amounts are in minor units of the base currency unless a
+		// currency code is given
+		"formatCents": func(minor int) string {
+			return cfg.Locale.Format(minor, cfg.BaseCurrency)
+		},
+		"formatMoney": func(m currency.Money) string {
+			return cfg.Locale.Format(m.Minor, m.Currency)
 		},
+		"amountValue": func(minor int, code string) string {
+			return cfg.Locale.Plain(minor, currency.Of(code, cfg.BaseCurrency))
+		},
+		"currencies":   currency.All,
+		"baseCurrency": func() currency.Currency { return cfg.BaseCurrency },
+		"formatBytes":  utils.FormatBytes,
 		"derefUint": func(p *uint) uint {
 			if p == nil {
 				return 0
 			}
 			return *p
 		},
+		"derefInt": func(p *int) int {
+			if p == nil {
+				return 0
+			}
+			return *p
+		},
 	}
 
-	templates, err := template.New("").Funcs(funcMap).ParseGlob("web/templates/*.html")
+	templates, err := template.New("").Funcs(funcMap).ParseGlob("web/templates/partials/*.html")
 	if err != nil {
-		log.Fatalf("Failed to parse templates: %v", err)
+		log.Fatalf("Failed to parse partial templates: %v", err)
 	}
 
-	templates, err = templates.ParseGlob("web/templates/partials/*.html")
-	if err != nil {
-		log.Fatalf("Failed to parse partial templates: %v", err)
+	// Each full page gets its own copy of the partials plus the layout, since
+	// every page defines the layout's "content" block
+	pages := make(map[string]*template.Template)
+	for _, page := range []string{"dashboard", "transactions", "reports"} {
+		pageTemplates, err := templates.Clone()
+		if err != nil {
+			log.Fatalf("Failed to clone templates: %v", err)
+		}
+		pages[page], err = pageTemplates.ParseFiles("web/templates/layout.html", "web/templates/"+page+".html")
+		if err != nil {
+			log.Fatalf("Failed to parse %s page templates: %v", page, err)
+		}
 	}
 
 	// Setup router
@@ -51,7 +116,7 @@ func main() {
 	r.Use(middleware.Recoverer)
 
 	// Initialize handlers with DB dependency and templates
-	h := handlers.New(db, templates)
+	h := handlers.New(db, templates, pages, backups, attachmentStore, cfg.BaseCurrency, cfg.Locale)
 
 	// Static files
 	fileServer := http.FileServer(http.Dir("./web/static"))
@@ -64,6 +129,10 @@ func main() {
 	// Expense routes
 	r.Post("/expenses", h.CreateExpense)
 	r.Get("/expenses/{id}/edit", h.GetExpenseEditForm)
+	r.Get("/expenses/{id}/attachments", h.ListAttachments)
+	r.Post("/expenses/{id}/attachments", h.UploadAttachment)
+	r.Get("/expenses/{id}/refunds", h.ListRefunds)
+	r.Post("/expenses/{id}/refunds", h.CreateRefund)
 	r.Put("/expenses/{id}", h.UpdateExpense)
 	r.Delete("/expenses/{id}", h.DeleteExpense)
 
@@ -78,11 +147,71 @@ func main() {
 	r.Post("/categories", h.CreateCategory)
 	r.Get("/categories/{id}/edit", h.GetCategoryEditForm)
 	r.Put("/categories/{id}", h.UpdateCategory)
+	r.Post("/categories/{id}/archive", h.ArchiveCategory)
+	r.Post("/categories/{id}/unarchive", h.UnarchiveCategory)
+	r.Get("/categories/{id}/delete", h.GetCategoryDeleteForm)
+	r.Post("/categories/{id}/merge", h.MergeCategory)
 	r.Delete("/categories/{id}", h.DeleteCategory)
 
+	// Attachment routes
+	r.Get("/attachments/{id}", h.GetAttachment)
+	r.Delete("/attachments/{id}", h.DeleteAttachment)
+
+	// Refund routes
+	r.Delete("/refunds/{id}", h.DeleteRefund)
+
+	// Expense template routes
+	r.Get("/expense-templates", h.ListExpenseTemplates)
+	r.Post("/expense-templates", h.CreateExpenseTemplate)
+	r.Delete("/expense-templates/{id}", h.DeleteExpenseTemplate)
+
+	// Payee routes
+	r.Get("/payees", h.ListPayees)
+	r.Post("/payees", h.CreatePayee)
+	r.Post("/payees/{id}/aliases", h.AddPayeeAlias)
+	r.Post("/payees/{id}/merge", h.MergePayee)
+	r.Delete("/payees/{id}", h.DeletePayee)
+	r.Delete("/payee-aliases/{id}", h.DeletePayeeAlias)
+
+	// Categorization rule routes
+	r.Get("/rules", h.ListRules)
+	r.Post("/rules", h.CreateRule)
+	r.Get("/rules/preview", h.PreviewRules)
+	r.Post("/rules/apply", h.ApplyRules)
+	r.Post("/rules/{id}/toggle", h.ToggleRule)
+	r.Delete("/rules/{id}", h.DeleteRule)
+
+	// Category suggestion routes
+	r.Get("/suggest/category", h.SuggestCategory)
+	r.Get("/suggest/accuracy", h.SuggestionAccuracy)
+
+	// Exchange rate routes
+	r.Get("/exchange-rates", h.ListExchangeRates)
+	r.Post("/exchange-rates", h.CreateExchangeRate)
+	r.Post("/exchange-rates/import", h.ImportExchangeRates)
+	r.Delete("/exchange-rates/{id}", h.DeleteExchangeRate)
+
+	// Transaction list routes
+	r.Get("/transactions", h.ListTransactions)
+	r.Post("/transactions/bulk", h.BulkUpdateTransactions)
+	r.Get("/search", h.SearchTransactions)
+	r.Get("/reports", h.Reports)
+
+	// Trash routes
+	r.Get("/trash", h.ListTrash)
+	r.Post("/trash/{type}/{id}/restore", h.RestoreTrashItem)
+	r.Delete("/trash/{type}/{id}", h.PurgeTrashItem)
+
+	// Activity routes
+	r.Get("/activity", h.ListActivity)
+	r.Post("/activity/{id}/undo", h.UndoActivity)
+
 	// Partial routes
 	r.Get("/partials/recent-transactions", h.GetRecentTransactions)
 	r.Get("/partials/overview", h.GetOverview)
+	r.Get("/partials/split-row", h.GetSplitRow)
+	r.Get("/partials/expense-form", h.GetExpenseForm)
+	r.Get("/partials/amount-preview", h.GetAmountPreview)
 
 	// Start server
 	log.Println("Server starting on http://localhost:8080")
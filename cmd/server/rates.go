@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/g-linville/budgeting/internal/config"
+	"github.com/g-linville/budgeting/internal/currency"
+	"github.com/g-linville/budgeting/internal/database"
+	"github.com/g-linville/budgeting/internal/exchange"
+	"github.com/g-linville/budgeting/internal/models"
+	"gorm.io/gorm"
+)
+
+const ratesUsage = `usage: server rates import FILE
+
+Imports exchange rates from a CSV file of date,currency,rate lines, where
+the rate is the number of base currency units per unit of the currency, and
+recalculates transactions entered in those currencies.`
+
+// runRates handles the "rates" subcommand
+func runRates(cfg config.Config, args []string) {
+	if len(args) != 2 || args[0] != "import" {
+		fmt.Fprintln(os.Stderr, ratesUsage)
+		os.Exit(2)
+	}
+
+	file, err := os.Open(args[1])
+	if err != nil {
+		log.Fatalf("Failed to open rates file: %v", err)
+	}
+	defer file.Close()
+
+	rates, err := currency.ParseRatesCSV(file, cfg.BaseCurrency)
+	if err != nil {
+		log.Fatalf("Invalid rates file: %v", err)
+	}
+
+	db, err := database.InitDB(cfg.DBDriver, cfg.DBDSN)
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+
+	var saved, changed int
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if saved, err = exchange.SaveRates(tx, rates, models.RateSourceCSV); err != nil {
+			return err
+		}
+		changed, err = exchange.Reconvert(tx, cfg.BaseCurrency)
+		return err
+	})
+	if err != nil {
+		log.Fatalf("Import failed: %v", err)
+	}
+	fmt.Printf("Imported %d rate(s); recalculated %d transaction(s) in %s\n", saved, changed, cfg.BaseCurrency.Code)
+}
@@ -0,0 +1,227 @@
+package attachments
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// orphanGracePeriod protects files that were just written from being swept
+// before the attachment row referencing them is committed
+const orphanGracePeriod = time.Hour
+
+// AllowedTypes are the content types accepted for attachments, detected from
+// the file contents rather than trusted from the upload
+var AllowedTypes = map[string]bool{
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"application/pdf": true,
+}
+
+var (
+	// ErrTooLarge is returned when a file exceeds the store's size limit
+	ErrTooLarge = errors.New("file is too large")
+	// ErrUnsupportedType is returned for files that are not images or PDFs
+	ErrUnsupportedType = errors.New("only images (JPEG, PNG, GIF, WebP) and PDFs can be attached")
+)
+
+// Store keeps attachment files in a content-addressed directory: each file is
+// named by the SHA-256 of its contents, so identical uploads share one file
+type Store struct {
+	dir     string
+	maxSize int64
+}
+
+// NewStore creates a store in dir accepting files up to maxSize bytes
+func NewStore(dir string, maxSize int64) *Store {
+	return &Store{dir: dir, maxSize: maxSize}
+}
+
+// MaxSize returns the largest accepted file size in bytes
+func (s *Store) MaxSize() int64 {
+	return s.maxSize
+}
+
+// path returns where the file with the given hash is stored. Files are
+// sharded by the first two hex digits to keep directories small.
+func (s *Store) path(hash string) string {
+	return filepath.Join(s.dir, hash[:2], hash)
+}
+
+// Save stores the contents of r and returns its hash, size and detected
+// content type
+func (s *Store) Save(r io.Reader) (hash string, size int64, contentType string, err error) {
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return "", 0, "", err
+	}
+
+	tmp, err := os.CreateTemp(s.dir, ".upload-*")
+	if err != nil {
+		return "", 0, "", err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	// Read one byte past the limit to detect oversized files
+	hasher := sha256.New()
+	size, err = io.Copy(io.MultiWriter(tmp, hasher), io.LimitReader(r, s.maxSize+1))
+	if err != nil {
+		return "", 0, "", err
+	}
+	if size > s.maxSize {
+		return "", 0, "", ErrTooLarge
+	}
+
+	head := make([]byte, 512)
+	n, err := tmp.ReadAt(head, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", 0, "", err
+	}
+	contentType = http.DetectContentType(head[:n])
+	if !AllowedTypes[contentType] {
+		return "", 0, "", ErrUnsupportedType
+	}
+
+	if err := tmp.Sync(); err != nil {
+		return "", 0, "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", 0, "", err
+	}
+
+	hash = hex.EncodeToString(hasher.Sum(nil))
+	dest := s.path(hash)
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return "", 0, "", err
+	}
+	if _, err := os.Stat(dest); err == nil {
+		// Already stored; refresh the timestamp so the sweep leaves it alone
+		now := time.Now()
+		return hash, size, contentType, os.Chtimes(dest, now, now)
+	}
+	if err := os.Rename(tmp.Name(), dest); err != nil {
+		return "", 0, "", err
+	}
+
+	return hash, size, contentType, nil
+}
+
+// Open opens the stored file with the given hash
+func (s *Store) Open(hash string) (*os.File, error) {
+	if !validHash(hash) {
+		return nil, fmt.Errorf("invalid attachment hash %q", hash)
+	}
+	return os.Open(s.path(hash))
+}
+
+// RemoveIfUnreferenced deletes the file with the given hash if no attachment
+// row refers to it any more
+func (s *Store) RemoveIfUnreferenced(db *gorm.DB, hash string) error {
+	if !validHash(hash) {
+		return fmt.Errorf("invalid attachment hash %q", hash)
+	}
+
+	var count int64
+	if err := db.Table("attachments").Where("hash = ?", hash).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	if err := os.Remove(s.path(hash)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// RemoveOrphans deletes stored files that no attachment refers to, such as
+// those of expenses purged from the trash, and returns how many were removed
+func (s *Store) RemoveOrphans(db *gorm.DB) (int, error) {
+	var hashes []string
+	if err := db.Table("attachments").Distinct("hash").Pluck("hash", &hashes).Error; err != nil {
+		return 0, err
+	}
+	referenced := make(map[string]bool, len(hashes))
+	for _, hash := range hashes {
+		referenced[hash] = true
+	}
+
+	cutoff := time.Now().Add(-orphanGracePeriod)
+	count := 0
+	err := filepath.WalkDir(s.dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+
+		// Only unreferenced content files and temp files left behind by
+		// interrupted uploads are removed; anything else is not ours
+		name := entry.Name()
+		isUpload := strings.HasPrefix(name, ".upload-")
+		if !isUpload && (!validHash(name) || referenced[name]) {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		if info.ModTime().After(cutoff) {
+			return nil
+		}
+
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		count++
+		return nil
+	})
+	return count, err
+}
+
+// Run sweeps orphaned files once an hour until ctx is cancelled
+func (s *Store) Run(ctx context.Context, db *gorm.DB) {
+	for {
+		count, err := s.RemoveOrphans(db)
+		if err != nil {
+			log.Printf("Error removing orphaned attachments: %v", err)
+		} else if count > 0 {
+			log.Printf("Removed %d orphaned attachment file(s)", count)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Hour):
+		}
+	}
+}
+
+// validHash reports whether s is a hex SHA-256, so it is safe to use in a path
+func validHash(s string) bool {
+	if len(s) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
@@ -0,0 +1,245 @@
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/g-linville/budgeting/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Audited entity types
+const (
+	EntityExpense          = "expense"
+	EntityIncome           = "income"
+	EntityCategory         = "category"
+	EntityRecurringExpense = "recurring_expense"
+	EntityRecurringIncome  = "recurring_income"
+	EntityRefund           = "refund"
+)
+
+// Actions recorded in the audit log
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+	ActionUndo   = "undo"
+)
+
+var (
+	// ErrAlreadyUndone is returned when undoing a change twice
+	ErrAlreadyUndone = errors.New("change has already been undone")
+	// ErrNotUndoable is returned for entries that cannot be undone (e.g. undos)
+	ErrNotUndoable = errors.New("change cannot be undone")
+	// ErrConflict is returned when the record changed after the audited change
+	ErrConflict = errors.New("record has changed since; undo the later change first")
+)
+
+// entities maps each entity type to a constructor for its model
+var entities = map[string]func() interface{}{
+	EntityExpense:          func() interface{} { return &models.Expense{} },
+	EntityIncome:           func() interface{} { return &models.Income{} },
+	EntityCategory:         func() interface{} { return &models.Category{} },
+	EntityRecurringExpense: func() interface{} { return &models.RecurringExpense{} },
+	EntityRecurringIncome:  func() interface{} { return &models.RecurringIncome{} },
+	EntityRefund:           func() interface{} { return &models.Refund{} },
+}
+
+// Snapshot returns the record's current columns as JSON, or "" if it does not exist
+func Snapshot(tx *gorm.DB, entityType string, id uint) (string, error) {
+	newModel, ok := entities[entityType]
+	if !ok {
+		return "", fmt.Errorf("unknown audit entity %q", entityType)
+	}
+
+	// Tags and split lines are part of the record as far as the user is
+	// concerned, so they are included in snapshots (ordered so that snapshots
+	// compare equal) and restored by Undo
+	query := tx.Unscoped()
+	switch entityType {
+	case EntityExpense:
+		query = query.Preload("Tags", orderBy("tags.name")).Preload("Splits", orderBy("id"))
+	case EntityIncome:
+		query = query.Preload("Tags", orderBy("tags.name"))
+	}
+
+	record := newModel()
+	result := query.Limit(1).Find(record, id)
+	if result.Error != nil {
+		return "", result.Error
+	}
+	if result.RowsAffected == 0 {
+		return "", nil
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// Record stores an audit entry for a change that has just been made in tx.
+// before is the Snapshot taken prior to the change ("" for creates); the
+// after snapshot is taken here.
+func Record(tx *gorm.DB, entityType string, id uint, action, before string) error {
+	after, err := Snapshot(tx, entityType, id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Create(&models.AuditEntry{
+		EntityType: entityType,
+		EntityID:   id,
+		Action:     action,
+		Before:     before,
+		After:      after,
+	}).Error
+}
+
+// Undo restores the record to its state before the given audit entry.
+// The undo is itself recorded in the audit log.
+func Undo(db *gorm.DB, entryID uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var entry models.AuditEntry
+		if err := tx.First(&entry, entryID).Error; err != nil {
+			return err
+		}
+		if entry.Action == ActionUndo {
+			return ErrNotUndoable
+		}
+		if entry.UndoneAt != nil {
+			return ErrAlreadyUndone
+		}
+
+		newModel, ok := entities[entry.EntityType]
+		if !ok {
+			return fmt.Errorf("unknown audit entity %q", entry.EntityType)
+		}
+
+		current, err := Snapshot(tx, entry.EntityType, entry.EntityID)
+		if err != nil {
+			return err
+		}
+		if !Matches(current, entry.After) {
+			return ErrConflict
+		}
+
+		if entry.Before == "" {
+			// Undoing a create removes the record entirely
+			if err := tx.Unscoped().Delete(newModel(), entry.EntityID).Error; err != nil {
+				return err
+			}
+		} else {
+			record := newModel()
+			if err := json.Unmarshal([]byte(entry.Before), record); err != nil {
+				return fmt.Errorf("decoding snapshot: %w", err)
+			}
+			// Save updates the row, or re-inserts it with its original ID if deleted
+			if err := tx.Unscoped().Omit(clause.Associations).Save(record).Error; err != nil {
+				return err
+			}
+			if err := restoreAssociations(tx, record, entry.Before); err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		if err := tx.Model(&entry).Update("undone_at", now).Error; err != nil {
+			return err
+		}
+
+		return Record(tx, entry.EntityType, entry.EntityID, ActionUndo, current)
+	})
+}
+
+// orderBy returns a preload condition that sorts the associated records
+func orderBy(column string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Order(column)
+	}
+}
+
+// restoreAssociations sets a record's tags and split lines back to those in
+// the snapshot it was decoded from. Snapshots taken before an association
+// existed leave it alone.
+func restoreAssociations(tx *gorm.DB, record interface{}, snapshot string) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(snapshot), &fields); err != nil {
+		return err
+	}
+	_, hasTags := fields["Tags"]
+	_, hasSplits := fields["Splits"]
+
+	switch r := record.(type) {
+	case *models.Expense:
+		if hasTags {
+			if err := tx.Model(r).Association("Tags").Replace(r.Tags); err != nil {
+				return err
+			}
+		}
+		if hasSplits {
+			if err := tx.Where("expense_id = ?", r.ID).Delete(&models.ExpenseSplit{}).Error; err != nil {
+				return err
+			}
+			if len(r.Splits) > 0 {
+				return tx.Create(&r.Splits).Error
+			}
+		}
+	case *models.Income:
+		if hasTags {
+			return tx.Model(r).Association("Tags").Replace(r.Tags)
+		}
+	}
+	return nil
+}
+
+// Matches reports whether the current snapshot still agrees with an earlier
+// one. Only fields present in the earlier snapshot are compared, so columns
+// added by later migrations do not block undoing old changes.
+func Matches(current, earlier string) bool {
+	if current == "" || earlier == "" {
+		return current == earlier
+	}
+
+	var currentFields, earlierFields map[string]interface{}
+	if json.Unmarshal([]byte(current), &currentFields) != nil ||
+		json.Unmarshal([]byte(earlier), &earlierFields) != nil {
+		return false
+	}
+
+	for key, value := range earlierFields {
+		if !reflect.DeepEqual(currentFields[key], value) {
+			return false
+		}
+	}
+	return true
+}
+
+// ChangedFields lists the top-level fields that differ between two snapshots
+func ChangedFields(before, after string) []string {
+	var beforeFields, afterFields map[string]interface{}
+	json.Unmarshal([]byte(before), &beforeFields)
+	json.Unmarshal([]byte(after), &afterFields)
+
+	var changed []string
+	for key, value := range afterFields {
+		if old, ok := beforeFields[key]; ok && !reflect.DeepEqual(old, value) {
+			changed = append(changed, key)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// SnapshotName extracts the Name field from a snapshot for display
+func SnapshotName(snapshot string) string {
+	var fields struct{ Name string }
+	json.Unmarshal([]byte(snapshot), &fields)
+	return fields.Name
+}
@@ -0,0 +1,292 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+const (
+	filePrefix = "budgeting-"
+	fileSuffix = ".db"
+	timeLayout = "20060102-150405"
+)
+
+// Retention is how many backups to keep per period (grandfather-father-son).
+// The newest backup of each of the most recent N days, weeks and months is kept.
+type Retention struct {
+	Daily   int
+	Weekly  int
+	Monthly int
+}
+
+// Status is a snapshot of the backup manager's state for health reporting
+type Status struct {
+	Dir         string
+	LastAttempt time.Time
+	LastSuccess time.Time
+	LastFile    string
+	LastError   string
+	Count       int
+}
+
+// Manager takes periodic online backups of the SQLite database
+type Manager struct {
+	db        *gorm.DB
+	dir       string
+	interval  time.Duration
+	retention Retention
+
+	mu     sync.Mutex
+	status Status
+}
+
+// NewManager creates a backup manager writing into dir
+func NewManager(db *gorm.DB, dir string, interval time.Duration, retention Retention) *Manager {
+	return &Manager{
+		db:        db,
+		dir:       dir,
+		interval:  interval,
+		retention: retention,
+		status:    Status{Dir: dir},
+	}
+}
+
+// Status returns the current backup status
+func (m *Manager) Status() Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.status
+}
+
+// Run takes a backup whenever the newest one is older than the interval,
+// until ctx is cancelled
+func (m *Manager) Run(ctx context.Context) {
+	if err := os.MkdirAll(m.dir, 0o755); err != nil {
+		m.recordFailure(fmt.Errorf("creating backup directory: %w", err))
+		log.Printf("Backups disabled: %v", err)
+		return
+	}
+
+	backups, err := m.list()
+	if err != nil {
+		log.Printf("Error listing backups: %v", err)
+	}
+	if len(backups) > 0 {
+		newest := backups[0]
+		m.mu.Lock()
+		m.status.LastSuccess = newest.takenAt
+		m.status.LastFile = newest.path
+		m.status.Count = len(backups)
+		m.mu.Unlock()
+	}
+
+	for {
+		wait := time.Until(m.Status().LastSuccess.Add(m.interval))
+		if wait > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(wait):
+			}
+		}
+
+		if _, err := m.BackupNow(); err != nil {
+			log.Printf("Backup failed: %v", err)
+			// Retry after a short delay rather than spinning
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Hour):
+			}
+		}
+	}
+}
+
+// BackupNow writes a new backup, verifies it and applies the retention policy
+func (m *Manager) BackupNow() (string, error) {
+	now := time.Now()
+	m.mu.Lock()
+	m.status.LastAttempt = now
+	m.mu.Unlock()
+
+	path, err := m.backup(now)
+	if err != nil {
+		m.recordFailure(err)
+		return "", err
+	}
+
+	pruneErr := m.prune()
+
+	backups, _ := m.list()
+	m.mu.Lock()
+	m.status.LastSuccess = now
+	m.status.LastFile = path
+	m.status.LastError = ""
+	m.status.Count = len(backups)
+	m.mu.Unlock()
+
+	log.Printf("Database backed up to %s", path)
+	if pruneErr != nil {
+		log.Printf("Error pruning old backups: %v", pruneErr)
+	}
+
+	return path, nil
+}
+
+// backup copies the live database with VACUUM INTO, which produces a
+// consistent snapshot without blocking writers for the whole copy
+func (m *Manager) backup(now time.Time) (string, error) {
+	final := filepath.Join(m.dir, filePrefix+now.Format(timeLayout)+fileSuffix)
+	tmp := final + ".tmp"
+	os.Remove(tmp)
+
+	if err := m.db.Exec("VACUUM INTO ?", tmp).Error; err != nil {
+		os.Remove(tmp)
+		return "", fmt.Errorf("vacuum into %s: %w", tmp, err)
+	}
+
+	if err := verify(tmp); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+
+	if err := os.Rename(tmp, final); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+
+	return final, nil
+}
+
+// verify runs PRAGMA integrity_check against a backup file
+func verify(path string) error {
+	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{})
+	if err != nil {
+		return fmt.Errorf("opening backup for verification: %w", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	defer sqlDB.Close()
+
+	var results []string
+	if err := db.Raw("PRAGMA integrity_check").Scan(&results).Error; err != nil {
+		return fmt.Errorf("integrity check: %w", err)
+	}
+	if len(results) != 1 || results[0] != "ok" {
+		return fmt.Errorf("integrity check failed: %s", strings.Join(results, "; "))
+	}
+
+	return nil
+}
+
+func (m *Manager) recordFailure(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.status.LastError = err.Error()
+}
+
+// backupFile is a backup on disk along with the time encoded in its name
+type backupFile struct {
+	path    string
+	takenAt time.Time
+}
+
+// list returns the backups in the directory, newest first
+func (m *Manager) list() ([]backupFile, error) {
+	entries, err := os.ReadDir(m.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []backupFile
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, filePrefix) || !strings.HasSuffix(name, fileSuffix) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimPrefix(name, filePrefix), fileSuffix)
+		takenAt, err := time.ParseInLocation(timeLayout, stamp, time.Local)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backupFile{path: filepath.Join(m.dir, name), takenAt: takenAt})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].takenAt.After(backups[j].takenAt)
+	})
+
+	return backups, nil
+}
+
+// prune deletes backups not selected by the retention policy
+func (m *Manager) prune() error {
+	backups, err := m.list()
+	if err != nil {
+		return err
+	}
+
+	keep := selectRetained(backups, m.retention)
+	for _, b := range backups {
+		if keep[b.path] {
+			continue
+		}
+		if err := os.Remove(b.path); err != nil {
+			return err
+		}
+		log.Printf("Removed old backup %s", b.path)
+	}
+
+	return nil
+}
+
+// selectRetained picks which backups to keep. backups must be sorted newest
+// first. The newest backup is always kept.
+func selectRetained(backups []backupFile, r Retention) map[string]bool {
+	keep := make(map[string]bool)
+	if len(backups) == 0 {
+		return keep
+	}
+	keep[backups[0].path] = true
+
+	periods := []struct {
+		limit int
+		key   func(time.Time) string
+	}{
+		{r.Daily, func(t time.Time) string { return t.Format("2006-01-02") }},
+		{r.Weekly, func(t time.Time) string {
+			year, week := t.ISOWeek()
+			return fmt.Sprintf("%d-W%02d", year, week)
+		}},
+		{r.Monthly, func(t time.Time) string { return t.Format("2006-01") }},
+	}
+
+	for _, period := range periods {
+		seen := make(map[string]bool)
+		for _, b := range backups {
+			if len(seen) >= period.limit {
+				break
+			}
+			key := period.key(b.takenAt)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			keep[b.path] = true
+		}
+	}
+
+	return keep
+}
@@ -0,0 +1,137 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/g-linville/budgeting/internal/currency"
+	"github.com/g-linville/budgeting/internal/locale"
+)
+
+// Config holds runtime settings, read from BUDGETING_* environment variables
+type Config struct {
+	// DBDriver is "sqlite" or "postgres". For SQLite, DBDSN is a file path;
+	// for PostgreSQL it is a connection string.
+	DBDriver string
+	DBDSN    string
+
+	// Backups are disabled when BackupDir is empty
+	BackupDir         string
+	BackupInterval    time.Duration
+	BackupKeepDaily   int
+	BackupKeepWeekly  int
+	BackupKeepMonthly int
+
+	// Trashed records are purged permanently after TrashRetention
+	TrashRetention time.Duration
+
+	// Receipts and other attachments are stored under AttachmentDir, up to
+	// AttachmentMaxMB megabytes each
+	AttachmentDir   string
+	AttachmentMaxMB int
+
+	// Amounts are stored and reported in BaseCurrency; transactions entered
+	// in other currencies are converted with stored exchange rates
+	BaseCurrency currency.Currency
+
+	// Locale sets how amounts are written and read, e.g. "1.234,56 €" for
+	// de-DE; NegativeStyle in it may be overridden on its own
+	Locale locale.Locale
+}
+
+// Load reads the configuration from the environment, applying defaults
+func Load() (Config, error) {
+	cfg := Config{
+		DBDriver:      getEnv("BUDGETING_DB_DRIVER", "sqlite"),
+		DBDSN:         getEnv("BUDGETING_DB_DSN", "./budgeting.db"),
+		BackupDir:     getEnv("BUDGETING_BACKUP_DIR", "./backups"),
+		AttachmentDir: getEnv("BUDGETING_ATTACHMENT_DIR", "./attachments"),
+	}
+
+	if cfg.DBDriver != "sqlite" && cfg.DBDriver != "postgres" {
+		return Config{}, fmt.Errorf("BUDGETING_DB_DRIVER must be \"sqlite\" or \"postgres\", got %q", cfg.DBDriver)
+	}
+
+	var err error
+	if cfg.BackupInterval, err = getDuration("BUDGETING_BACKUP_INTERVAL", 24*time.Hour); err != nil {
+		return Config{}, err
+	}
+	if cfg.TrashRetention, err = getDuration("BUDGETING_TRASH_RETENTION", 30*24*time.Hour); err != nil {
+		return Config{}, err
+	}
+	if cfg.BackupKeepDaily, err = getInt("BUDGETING_BACKUP_KEEP_DAILY", 7); err != nil {
+		return Config{}, err
+	}
+	if cfg.BackupKeepWeekly, err = getInt("BUDGETING_BACKUP_KEEP_WEEKLY", 4); err != nil {
+		return Config{}, err
+	}
+	if cfg.BackupKeepMonthly, err = getInt("BUDGETING_BACKUP_KEEP_MONTHLY", 12); err != nil {
+		return Config{}, err
+	}
+
+	if cfg.AttachmentMaxMB, err = getInt("BUDGETING_ATTACHMENT_MAX_MB", 10); err != nil {
+		return Config{}, err
+	}
+	if cfg.AttachmentMaxMB == 0 {
+		return Config{}, fmt.Errorf("BUDGETING_ATTACHMENT_MAX_MB must be at least 1")
+	}
+	if cfg.AttachmentDir == "" {
+		return Config{}, fmt.Errorf("BUDGETING_ATTACHMENT_DIR must not be empty")
+	}
+
+	baseCode := getEnv("BUDGETING_BASE_CURRENCY", "USD")
+	base, ok := currency.Lookup(baseCode)
+	if !ok {
+		return Config{}, fmt.Errorf("BUDGETING_BASE_CURRENCY must be a supported ISO 4217 code, got %q", baseCode)
+	}
+	cfg.BaseCurrency = base
+
+	tag := getEnv("BUDGETING_LOCALE", locale.Default.Tag)
+	if cfg.Locale, ok = locale.Lookup(tag); !ok {
+		return Config{}, fmt.Errorf("BUDGETING_LOCALE must be one of %s, got %q", strings.Join(locale.Tags(), ", "), tag)
+	}
+	if style := getEnv("BUDGETING_NEGATIVE_STYLE", ""); style != "" {
+		if cfg.Locale.Negative, ok = locale.ParseNegativeStyle(style); !ok {
+			return Config{}, fmt.Errorf("BUDGETING_NEGATIVE_STYLE must be \"minus\" or \"parentheses\", got %q", style)
+		}
+	}
+
+	return cfg, nil
+}
+
+// getEnv returns the environment variable or a default if unset
+func getEnv(key, def string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return def
+}
+
+// getDuration parses a duration such as "24h" from the environment
+func getDuration(key string, def time.Duration) (time.Duration, error) {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("%s must be a positive duration (e.g. 24h), got %q", key, value)
+	}
+	return d, nil
+}
+
+// getInt parses a non-negative integer from the environment
+func getInt(key string, def int) (int, error) {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%s must be a non-negative integer, got %q", key, value)
+	}
+	return n, nil
+}
@@ -0,0 +1,115 @@
+// Package currency handles ISO 4217 currencies: exact amounts in minor units
+// (cents, pence, or whole yen), converting them at a given exchange rate and
+// parsing rates. Looking up stored rates is in the exchange package, and
+// writing and reading amounts for a locale in the locale package.
+package currency
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+)
+
+// Currency is an ISO 4217 currency
+type Currency struct {
+	Code     string // e.g. "EUR"
+	Name     string
+	Exponent int    // Digits after the decimal point: 2 for EUR, 0 for JPY
+	Symbol   string // e.g. "€"; "" to write the code instead
+}
+
+// currencies are the currencies that can be used, keyed by code
+var currencies = map[string]Currency{
+	"AUD": {"AUD", "Australian Dollar", 2, "A$"},
+	"BHD": {"BHD", "Bahraini Dinar", 3, ""},
+	"BRL": {"BRL", "Brazilian Real", 2, "R$"},
+	"CAD": {"CAD", "Canadian Dollar", 2, "CA$"},
+	"CHF": {"CHF", "Swiss Franc", 2, ""},
+	"CLP": {"CLP", "Chilean Peso", 0, ""},
+	"CNY": {"CNY", "Chinese Yuan", 2, "CN¥"},
+	"CZK": {"CZK", "Czech Koruna", 2, ""},
+	"DKK": {"DKK", "Danish Krone", 2, ""},
+	"EUR": {"EUR", "Euro", 2, "€"},
+	"GBP": {"GBP", "British Pound", 2, "£"},
+	"HKD": {"HKD", "Hong Kong Dollar", 2, "HK$"},
+	"HUF": {"HUF", "Hungarian Forint", 2, ""},
+	"IDR": {"IDR", "Indonesian Rupiah", 2, ""},
+	"ILS": {"ILS", "Israeli New Shekel", 2, "₪"},
+	"INR": {"INR", "Indian Rupee", 2, "₹"},
+	"ISK": {"ISK", "Icelandic Króna", 0, ""},
+	"JOD": {"JOD", "Jordanian Dinar", 3, ""},
+	"JPY": {"JPY", "Japanese Yen", 0, "¥"},
+	"KRW": {"KRW", "South Korean Won", 0, "₩"},
+	"KWD": {"KWD", "Kuwaiti Dinar", 3, ""},
+	"MXN": {"MXN", "Mexican Peso", 2, "MX$"},
+	"NOK": {"NOK", "Norwegian Krone", 2, ""},
+	"NZD": {"NZD", "New Zealand Dollar", 2, "NZ$"},
+	"PHP": {"PHP", "Philippine Peso", 2, "₱"},
+	"PLN": {"PLN", "Polish Złoty", 2, ""},
+	"SEK": {"SEK", "Swedish Krona", 2, ""},
+	"SGD": {"SGD", "Singapore Dollar", 2, "S$"},
+	"THB": {"THB", "Thai Baht", 2, "฿"},
+	"TRY": {"TRY", "Turkish Lira", 2, "₺"},
+	"TWD": {"TWD", "New Taiwan Dollar", 2, "NT$"},
+	"USD": {"USD", "US Dollar", 2, "$"},
+	"VND": {"VND", "Vietnamese Đồng", 0, "₫"},
+	"ZAR": {"ZAR", "South African Rand", 2, ""},
+}
+
+// Lookup returns the currency with the given code, ignoring case
+func Lookup(code string) (Currency, bool) {
+	c, ok := currencies[strings.ToUpper(strings.TrimSpace(code))]
+	return c, ok
+}
+
+// All returns every supported currency, sorted by code
+func All() []Currency {
+	list := make([]Currency, 0, len(currencies))
+	for _, c := range currencies {
+		list = append(list, c)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Code < list[j].Code })
+	return list
+}
+
+// Decimal writes an amount in minor units as a plain decimal number with a
+// decimal point
+// Examples: 1234 EUR -> "12.34", 1200 JPY -> "1200", 5 USD -> "0.05"
+func Decimal(minor int, c Currency) string {
+	sign := ""
+	if minor < 0 {
+		sign = "-"
+		minor = -minor
+	}
+	digits := fmt.Sprintf("%0*d", c.Exponent+1, minor)
+	if c.Exponent == 0 {
+		return sign + digits
+	}
+	split := len(digits) - c.Exponent
+	return sign + digits[:split] + "." + digits[split:]
+}
+
+// pow10 returns 10^n as a rational
+func pow10(n int) *big.Rat {
+	return new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil))
+}
+
+// round rounds to the nearest integer, half away from zero. It reports
+// false if the result does not fit in an int.
+func round(r *big.Rat) (int, bool) {
+	num, den := new(big.Int).Set(r.Num()), r.Denom()
+	negative := num.Sign() < 0
+	num.Abs(num)
+
+	// floor((2*num + den) / (2*den))
+	num.Mul(num, big.NewInt(2)).Add(num, den)
+	num.Quo(num, new(big.Int).Mul(den, big.NewInt(2)))
+	if negative {
+		num.Neg(num)
+	}
+	if !num.IsInt64() || int64(int(num.Int64())) != num.Int64() {
+		return 0, false
+	}
+	return int(num.Int64()), true
+}
@@ -0,0 +1,181 @@
+package currency
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+)
+
+var (
+	// ErrOverflow is returned when an amount does not fit in an int
+	ErrOverflow = errors.New("amount is too large")
+
+	// ErrPrecision is returned when an amount has more decimal places than
+	// its currency allows
+	ErrPrecision = errors.New("too many decimal places")
+
+	// ErrMismatch is returned when amounts in different currencies are
+	// combined
+	ErrMismatch = errors.New("currencies do not match")
+)
+
+// Money is an exact amount in minor units of a currency. Arithmetic on it
+// fails rather than wrapping around or mixing currencies.
+//
+// Models and SQL query rows keep amounts as plain int minor units, of the
+// base currency or of their Currency column, as they are stored. Amounts are
+// wrapped in Money once read, wherever they are added up or shared out in Go
+// (overview and report totals, split lines, refund shares and validation
+// sums) and in the report data handed to templates.
+type Money struct {
+	Minor    int // e.g. 1234 for €12.34 or for ¥1,234
+	Currency Currency
+}
+
+// New returns minor units of c as Money
+func New(minor int, c Currency) Money {
+	return Money{Minor: minor, Currency: c}
+}
+
+// IsPositive reports whether m is greater than zero
+func (m Money) IsPositive() bool {
+	return m.Minor > 0
+}
+
+// Add returns m + o
+func (m Money) Add(o Money) (Money, error) {
+	if m.Currency.Code != o.Currency.Code {
+		return Money{}, fmt.Errorf("%w: %s and %s", ErrMismatch, m.Currency.Code, o.Currency.Code)
+	}
+	if (o.Minor > 0 && m.Minor > math.MaxInt-o.Minor) || (o.Minor < 0 && m.Minor < math.MinInt-o.Minor) {
+		return Money{}, ErrOverflow
+	}
+	return Money{Minor: m.Minor + o.Minor, Currency: m.Currency}, nil
+}
+
+// Sub returns m - o
+func (m Money) Sub(o Money) (Money, error) {
+	if o.Minor == math.MinInt {
+		return Money{}, ErrOverflow
+	}
+	return m.Add(o.Neg())
+}
+
+// Neg returns -m
+func (m Money) Neg() Money {
+	return Money{Minor: -m.Minor, Currency: m.Currency}
+}
+
+// Sum adds amounts in c
+func Sum(c Currency, amounts ...int) (Money, error) {
+	total := New(0, c)
+	for _, amount := range amounts {
+		var err error
+		if total, err = total.Add(New(amount, c)); err != nil {
+			return Money{}, err
+		}
+	}
+	return total, nil
+}
+
+// Allocate divides m into parts proportional to weights without gaining or
+// losing a minor unit. Each part is first rounded toward zero, then the
+// units left over go one at a time to the parts that lost the most, the
+// earliest first among equals.
+// Example: $10.00 allocated 1:1:1 -> $3.34, $3.33, $3.33
+func (m Money) Allocate(weights []int) ([]Money, error) {
+	if m.Minor == math.MinInt {
+		return nil, ErrOverflow
+	}
+	sum := new(big.Int)
+	for _, w := range weights {
+		if w < 0 {
+			return nil, errors.New("allocation weights must not be negative")
+		}
+		sum.Add(sum, big.NewInt(int64(w)))
+	}
+	if sum.Sign() == 0 {
+		return nil, errors.New("allocation weights must not all be zero")
+	}
+
+	total := big.NewInt(int64(m.Minor))
+	negative := total.Sign() < 0
+	total.Abs(total)
+
+	parts := make([]Money, len(weights))
+	remainders := make([]*big.Int, len(weights))
+	left := m.Minor
+	if negative {
+		left = -left
+	}
+	for i, w := range weights {
+		share, remainder := new(big.Int).QuoRem(new(big.Int).Mul(total, big.NewInt(int64(w))), sum, new(big.Int))
+		parts[i] = Money{Minor: int(share.Int64()), Currency: m.Currency}
+		remainders[i] = remainder
+		left -= parts[i].Minor
+	}
+
+	for ; left > 0; left-- {
+		largest := 0
+		for i := range remainders {
+			if remainders[i].Cmp(remainders[largest]) > 0 {
+				largest = i
+			}
+		}
+		parts[largest].Minor++
+		remainders[largest].SetInt64(-1)
+	}
+
+	if negative {
+		for i := range parts {
+			parts[i].Minor = -parts[i].Minor
+		}
+	}
+	return parts, nil
+}
+
+// Split divides m into n parts that differ by at most one minor unit, the
+// larger ones first
+// Example: $10.00 split 3 ways -> $3.34, $3.33, $3.33
+func (m Money) Split(n int) ([]Money, error) {
+	weights := make([]int, n)
+	for i := range weights {
+		weights[i] = 1
+	}
+	return m.Allocate(weights)
+}
+
+// Convert converts m into currency to, where rate is the number of units of
+// to per unit of m's currency. Unlike parsing, conversion has to round: the
+// result is rounded half away from zero.
+func (m Money) Convert(to Currency, rate *big.Rat) (Money, error) {
+	value := new(big.Rat).SetInt64(int64(m.Minor))
+	value.Mul(value, rate)
+	if shift := to.Exponent - m.Currency.Exponent; shift >= 0 {
+		value.Mul(value, pow10(shift))
+	} else {
+		value.Quo(value, pow10(-shift))
+	}
+	converted, ok := round(value)
+	if !ok {
+		return Money{}, ErrOverflow
+	}
+	return Money{Minor: converted, Currency: to}, nil
+}
+
+// FromMajor rounds an exact amount in major units (dollars rather than
+// cents) to the minor units of c, half away from zero
+func FromMajor(value *big.Rat, c Currency) (Money, error) {
+	minor, ok := round(new(big.Rat).Mul(value, pow10(c.Exponent)))
+	if !ok {
+		return Money{}, ErrOverflow
+	}
+	return Money{Minor: minor, Currency: c}, nil
+}
+
+// String writes m as a plain decimal followed by its code, e.g. "12.34 EUR".
+// Use locale.Locale to format amounts for display.
+func (m Money) String() string {
+	return Decimal(m.Minor, m.Currency) + " " + m.Currency.Code
+}
@@ -0,0 +1,182 @@
+package currency
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"testing"
+)
+
+func mustLookup(t *testing.T, code string) Currency {
+	t.Helper()
+	c, ok := Lookup(code)
+	if !ok {
+		t.Fatalf("unknown currency %s", code)
+	}
+	return c
+}
+
+func TestAddSub(t *testing.T) {
+	usd, eur := mustLookup(t, "USD"), mustLookup(t, "EUR")
+
+	tests := []struct {
+		name    string
+		op      func() (Money, error)
+		want    int
+		wantErr error
+	}{
+		{"add", func() (Money, error) { return New(1050, usd).Add(New(250, usd)) }, 1300, nil},
+		{"sub below zero", func() (Money, error) { return New(250, usd).Sub(New(1050, usd)) }, -800, nil},
+		{"add overflow", func() (Money, error) { return New(math.MaxInt, usd).Add(New(1, usd)) }, 0, ErrOverflow},
+		{"add underflow", func() (Money, error) { return New(math.MinInt, usd).Add(New(-1, usd)) }, 0, ErrOverflow},
+		{"sub overflow", func() (Money, error) { return New(math.MaxInt, usd).Sub(New(-1, usd)) }, 0, ErrOverflow},
+		{"sub underflow", func() (Money, error) { return New(math.MinInt, usd).Sub(New(1, usd)) }, 0, ErrOverflow},
+		{"sub min int", func() (Money, error) { return New(0, usd).Sub(New(math.MinInt, usd)) }, 0, ErrOverflow},
+		{"mixed currencies", func() (Money, error) { return New(100, usd).Add(New(100, eur)) }, 0, ErrMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.op()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && got.Minor != tt.want {
+				t.Errorf("got %d, want %d", got.Minor, tt.want)
+			}
+		})
+	}
+}
+
+func TestSum(t *testing.T) {
+	usd := mustLookup(t, "USD")
+
+	if got, err := Sum(usd, 334, 333, 333); err != nil || got.Minor != 1000 {
+		t.Errorf("Sum = %v, %v; want 1000", got.Minor, err)
+	}
+	if _, err := Sum(usd, math.MaxInt, 1); !errors.Is(err, ErrOverflow) {
+		t.Errorf("Sum overflow error = %v, want %v", err, ErrOverflow)
+	}
+}
+
+func TestAllocate(t *testing.T) {
+	usd, jpy := mustLookup(t, "USD"), mustLookup(t, "JPY")
+
+	tests := []struct {
+		name    string
+		amount  Money
+		weights []int
+		want    []int
+		wantErr bool
+	}{
+		{"$10 three ways", New(1000, usd), []int{1, 1, 1}, []int{334, 333, 333}, false},
+		{"$10 three ways refund", New(-1000, usd), []int{1, 1, 1}, []int{-334, -333, -333}, false},
+		{"largest remainder wins", New(100, usd), []int{1, 2, 3}, []int{17, 33, 50}, false},
+		{"ties go to the earliest", New(5, usd), []int{1, 1, 1, 1}, []int{2, 1, 1, 1}, false},
+		{"zero weight gets nothing", New(1001, usd), []int{1, 0, 1}, []int{501, 0, 500}, false},
+		{"yen by original amounts", New(1000, jpy), []int{1250, 750, 500}, []int{500, 300, 200}, false},
+		{"zero amount", New(0, usd), []int{1, 1}, []int{0, 0}, false},
+		{"large amount", New(math.MaxInt, usd), []int{1, 1}, []int{math.MaxInt/2 + 1, math.MaxInt / 2}, false},
+		{"negative weight", New(1000, usd), []int{1, -1}, nil, true},
+		{"all zero weights", New(1000, usd), []int{0, 0}, nil, true},
+		{"min int", New(math.MinInt, usd), []int{1, 1}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parts, err := tt.amount.Allocate(tt.weights)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if len(parts) != len(tt.want) {
+				t.Fatalf("got %d parts, want %d", len(parts), len(tt.want))
+			}
+			sum := 0
+			for i, part := range parts {
+				if part.Minor != tt.want[i] {
+					t.Errorf("part %d = %d, want %d", i, part.Minor, tt.want[i])
+				}
+				if part.Currency != tt.amount.Currency {
+					t.Errorf("part %d currency = %s, want %s", i, part.Currency.Code, tt.amount.Currency.Code)
+				}
+				sum += part.Minor
+			}
+			if sum != tt.amount.Minor {
+				t.Errorf("parts add up to %d, want %d", sum, tt.amount.Minor)
+			}
+		})
+	}
+}
+
+func TestSplit(t *testing.T) {
+	parts, err := New(1000, mustLookup(t, "USD")).Split(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []int{334, 333, 333} {
+		if parts[i].Minor != want {
+			t.Errorf("part %d = %d, want %d", i, parts[i].Minor, want)
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	usd, eur, jpy, bhd := mustLookup(t, "USD"), mustLookup(t, "EUR"), mustLookup(t, "JPY"), mustLookup(t, "BHD")
+
+	tests := []struct {
+		name    string
+		amount  Money
+		to      Currency
+		rate    string
+		want    int
+		wantErr error
+	}{
+		{"EUR to USD", New(1000, eur), usd, "1.0845", 1085, nil},
+		{"rounds half away from zero", New(50, eur), usd, "1.01", 51, nil},
+		{"rounds negative half away from zero", New(-50, eur), usd, "1.01", -51, nil},
+		{"JPY to USD", New(1500, jpy), usd, "0.0067", 1005, nil},
+		{"USD to JPY", New(1234, usd), jpy, "149.5", 1845, nil},
+		{"USD to BHD", New(1000, usd), bhd, "0.376", 3760, nil},
+		{"exact fraction", New(100, usd), eur, "1/3", 33, nil},
+		{"overflow", New(math.MaxInt, eur), usd, "2", 0, ErrOverflow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rate, ok := new(big.Rat).SetString(tt.rate)
+			if !ok {
+				t.Fatalf("bad rate %q", tt.rate)
+			}
+			got, err := tt.amount.Convert(tt.to, rate)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got.Minor != tt.want || got.Currency != tt.to {
+				t.Errorf("got %d %s, want %d %s", got.Minor, got.Currency.Code, tt.want, tt.to.Code)
+			}
+		})
+	}
+}
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		rate    string
+		wantErr bool
+	}{
+		{"1.0845", false},
+		{" 149.5 ", false},
+		{"0", true},
+		{"-1.2", true},
+		{"1e3", true},
+		{"1/3", true},
+		{"abc", true},
+	}
+	for _, tt := range tests {
+		if _, err := ParseRate(tt.rate); (err != nil) != tt.wantErr {
+			t.Errorf("ParseRate(%q) error = %v, wantErr %v", tt.rate, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package currency
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// Rate is an exchange rate read from a form or a CSV file
+type Rate struct {
+	Currency Currency
+	Date     time.Time
+	Rate     string // Exact positive decimal
+}
+
+// Of returns the currency a transaction was entered in. An empty or unknown
+// code is the base currency.
+func Of(code string, base Currency) Currency {
+	if c, ok := Lookup(code); ok {
+		return c
+	}
+	return base
+}
+
+// ParseRate parses a positive decimal exchange rate such as "1.0845"
+func ParseRate(s string) (*big.Rat, error) {
+	rate, ok := new(big.Rat).SetString(strings.TrimSpace(s))
+	if !ok || strings.ContainsAny(s, "/eE") {
+		return nil, fmt.Errorf("invalid rate %q", s)
+	}
+	if rate.Sign() <= 0 {
+		return nil, fmt.Errorf("rate must be positive")
+	}
+	return rate, nil
+}
+
+// ParseRatesCSV reads exchange rates from CSV with the columns
+// date,currency,rate, e.g. "2026-03-01,EUR,1.0845". A header row is
+// skipped. Rates for the base currency are rejected.
+func ParseRatesCSV(r io.Reader, base Currency) ([]Rate, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 3
+	reader.TrimLeadingSpace = true
+
+	var rates []Rate
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if line == 1 && strings.EqualFold(strings.TrimSpace(record[0]), "date") {
+			continue
+		}
+
+		date, err := time.ParseInLocation("2006-01-02", strings.TrimSpace(record[0]), time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: date must be YYYY-MM-DD, got %q", line, record[0])
+		}
+		c, ok := Lookup(record[1])
+		if !ok {
+			return nil, fmt.Errorf("line %d: unknown currency %q", line, record[1])
+		}
+		if c.Code == base.Code {
+			return nil, fmt.Errorf("line %d: %s is the base currency", line, c.Code)
+		}
+		if _, err := ParseRate(record[2]); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		rates = append(rates, Rate{Currency: c, Date: date, Rate: strings.TrimSpace(record[2])})
+	}
+	return rates, nil
+}
@@ -1,30 +1,51 @@
 package database
 
 import (
-	"github.com/g-linville/budgeting/internal/models"
+	"fmt"
+	"strings"
+
+	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
-// InitDB initializes the database connection and runs auto-migrations
-func InitDB(dbPath string) (*gorm.DB, error) {
-	db, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{})
+// Open connects to the database without touching the schema.
+// driver is "sqlite" (dsn is a file path) or "postgres" (dsn is a
+// connection string such as "host=localhost user=budget dbname=budgeting").
+func Open(driver, dsn string) (*gorm.DB, error) {
+	var dialector gorm.Dialector
+	switch driver {
+	case DriverSQLite:
+		// Foreign key enforcement is per connection in SQLite, so it is
+		// requested in the DSN to apply to every pooled connection
+		dialector = sqlite.Open(withSQLiteParams(dsn))
+	case DriverPostgres:
+		dialector = postgres.Open(dsn)
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q (use %q or %q)", driver, DriverSQLite, DriverPostgres)
+	}
+
+	return gorm.Open(dialector, &gorm.Config{})
+}
+
+// withSQLiteParams appends connection parameters to a SQLite DSN
+func withSQLiteParams(dsn string) string {
+	separator := "?"
+	if strings.Contains(dsn, "?") {
+		separator = "&"
+	}
+	return dsn + separator + "_foreign_keys=on&_busy_timeout=5000"
+}
+
+// InitDB initializes the database connection and applies pending migrations.
+// It refuses to start if the schema was migrated by a newer version of the app.
+func InitDB(driver, dsn string) (*gorm.DB, error) {
+	db, err := Open(driver, dsn)
 	if err != nil {
 		return nil, err
 	}
 
-	// Enable foreign key constraints (SQLite requires explicit enablement)
-	db.Exec("PRAGMA foreign_keys = ON;")
-
-	// Auto-migrate schema - ORDER MATTERS (parent tables first)
-	err = db.AutoMigrate(
-		&models.Category{},
-		&models.RecurringExpense{},
-		&models.RecurringIncome{},
-		&models.Expense{},
-		&models.Income{},
-	)
-	if err != nil {
+	if _, err := MigrateUp(db); err != nil {
 		return nil, err
 	}
 
@@ -0,0 +1,127 @@
+// Package dbtest runs tests against every supported database backend.
+//
+// SQLite always runs, in a temporary file, with full-text search when the
+// tests are built with -tags sqlite_fts5 and the LIKE fallback otherwise.
+// PostgreSQL runs when BUDGET_TEST_POSTGRES_DSN is set to a connection string
+// such as "host=localhost user=budget dbname=budgeting_test". Each test gets
+// its own schema there, dropped when the test ends, so tests never share data.
+package dbtest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/g-linville/budgeting/internal/database"
+	"gorm.io/gorm"
+)
+
+// PostgresDSNEnv names the environment variable holding the PostgreSQL
+// connection string; PostgreSQL tests are skipped when it is unset
+const PostgresDSNEnv = "BUDGET_TEST_POSTGRES_DSN"
+
+// Run calls fn in a subtest per backend with an empty database
+func Run(t *testing.T, fn func(t *testing.T, db *gorm.DB)) {
+	t.Helper()
+	for _, driver := range []string{database.DriverSQLite, database.DriverPostgres} {
+		t.Run(driver, func(t *testing.T) {
+			fn(t, Open(t, driver))
+		})
+	}
+}
+
+// RunMigrated calls fn in a subtest per backend with every migration applied.
+// The migration log is silenced, as it would repeat for every test.
+func RunMigrated(t *testing.T, fn func(t *testing.T, db *gorm.DB)) {
+	t.Helper()
+	Run(t, func(t *testing.T, db *gorm.DB) {
+		log.SetOutput(io.Discard)
+		_, err := database.MigrateUp(db)
+		log.SetOutput(os.Stderr)
+		if err != nil {
+			t.Fatalf("migrate up: %v", err)
+		}
+		fn(t, db)
+	})
+}
+
+// Open returns an empty database for driver, skipping the test if the
+// backend is unavailable
+func Open(t *testing.T, driver string) *gorm.DB {
+	t.Helper()
+
+	var db *gorm.DB
+	var err error
+	switch driver {
+	case database.DriverSQLite:
+		db, err = database.Open(driver, filepath.Join(t.TempDir(), "budget.db"))
+	case database.DriverPostgres:
+		dsn := os.Getenv(PostgresDSNEnv)
+		if dsn == "" {
+			t.Skipf("%s is not set", PostgresDSNEnv)
+		}
+		db, err = openPostgresSchema(t, dsn)
+	default:
+		t.Fatalf("unsupported driver %q", driver)
+	}
+	if err != nil {
+		t.Fatalf("open %s: %v", driver, err)
+	}
+
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// openPostgresSchema creates a schema with a random name and connects with
+// it as the search path, so migrations create their tables inside it
+func openPostgresSchema(t *testing.T, dsn string) (*gorm.DB, error) {
+	t.Helper()
+
+	admin, err := database.Open(database.DriverPostgres, dsn)
+	if err != nil {
+		return nil, err
+	}
+	adminDB, err := admin.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	suffix := make([]byte, 6)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, err
+	}
+	schema := "budget_test_" + hex.EncodeToString(suffix)
+	if err := admin.Exec("CREATE SCHEMA " + schema).Error; err != nil {
+		adminDB.Close()
+		return nil, err
+	}
+	t.Cleanup(func() {
+		if err := admin.Exec("DROP SCHEMA " + schema + " CASCADE").Error; err != nil {
+			t.Errorf("drop schema %s: %v", schema, err)
+		}
+		adminDB.Close()
+	})
+
+	return database.Open(database.DriverPostgres, withSearchPath(dsn, schema))
+}
+
+// withSearchPath adds a search_path parameter to a key=value or URL DSN
+func withSearchPath(dsn, schema string) string {
+	if !strings.HasPrefix(dsn, "postgres://") && !strings.HasPrefix(dsn, "postgresql://") {
+		return dsn + " search_path=" + schema
+	}
+	separator := "?"
+	if strings.Contains(dsn, "?") {
+		separator = "&"
+	}
+	return dsn + separator + "search_path=" + schema
+}
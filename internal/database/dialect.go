@@ -0,0 +1,29 @@
+package database
+
+import "gorm.io/gorm"
+
+// Supported database drivers
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+)
+
+// IsSQLite reports whether db is backed by SQLite
+func IsSQLite(db *gorm.DB) bool {
+	return db.Dialector.Name() == DriverSQLite
+}
+
+// IsPostgres reports whether db is backed by PostgreSQL
+func IsPostgres(db *gorm.DB) bool {
+	return db.Dialector.Name() == DriverPostgres
+}
+
+// DateString returns a SQL expression that renders a DATE column as
+// YYYY-MM-DD text. SQLite stores dates as "YYYY-MM-DD HH:MM:SS+ZZ:ZZ" strings
+// (local midnight), so the date is the first ten characters.
+func DateString(db *gorm.DB, column string) string {
+	if IsPostgres(db) {
+		return "to_char(" + column + ", 'YYYY-MM-DD')"
+	}
+	return "substr(" + column + ", 1, 10)"
+}
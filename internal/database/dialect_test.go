@@ -0,0 +1,70 @@
+package database_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/g-linville/budgeting/internal/database"
+	"github.com/g-linville/budgeting/internal/database/dbtest"
+	"github.com/g-linville/budgeting/internal/models"
+	"gorm.io/gorm"
+)
+
+// Dates are stored at local midnight and compared as YYYY-MM-DD strings, so
+// month boundaries must hold whatever the backend stores underneath
+func TestDateString(t *testing.T) {
+	dbtest.RunMigrated(t, func(t *testing.T, db *gorm.DB) {
+		for _, date := range []string{"2024-02-29", "2024-03-01", "2024-03-15", "2024-03-31", "2024-04-01"} {
+			day, err := time.ParseInLocation("2006-01-02", date, time.Local)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := db.Create(&models.Expense{Name: date, Amount: 100, ExpenseDate: day}).Error; err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		column := database.DateString(db, "expense_date")
+
+		var dates []string
+		if err := db.Model(&models.Expense{}).Order("expense_date").Pluck(column, &dates).Error; err != nil {
+			t.Fatal(err)
+		}
+		for i, want := range []string{"2024-02-29", "2024-03-01", "2024-03-15", "2024-03-31", "2024-04-01"} {
+			if i >= len(dates) || dates[i] != want {
+				t.Fatalf("dates = %v, want them to read back as inserted", dates)
+			}
+		}
+
+		tests := []struct {
+			name     string
+			from, to string
+			want     []string
+		}{
+			{"month", "2024-03-01", "2024-03-31", []string{"2024-03-01", "2024-03-15", "2024-03-31"}},
+			{"single day", "2024-02-29", "2024-02-29", []string{"2024-02-29"}},
+			{"across months", "2024-03-31", "2024-04-30", []string{"2024-03-31", "2024-04-01"}},
+			{"empty", "2024-05-01", "2024-05-31", nil},
+		}
+		for _, tt := range tests {
+			var names []string
+			err := db.Model(&models.Expense{}).
+				Where(column+" >= ? AND "+column+" <= ?", tt.from, tt.to).
+				Order("expense_date").
+				Pluck("name", &names).Error
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(names) != len(tt.want) {
+				t.Errorf("%s: got %v, want %v", tt.name, names, tt.want)
+				continue
+			}
+			for i := range names {
+				if names[i] != tt.want[i] {
+					t.Errorf("%s: got %v, want %v", tt.name, names, tt.want)
+					break
+				}
+			}
+		}
+	})
+}
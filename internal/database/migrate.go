@@ -0,0 +1,261 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/g-linville/budgeting/internal/database/migrations"
+	"gorm.io/gorm"
+)
+
+// ErrSchemaTooNew is returned when the database has migrations applied that
+// this binary does not know about (i.e. it was migrated by a newer version)
+var ErrSchemaTooNew = errors.New("database schema is newer than this binary supports")
+
+// schemaMigration is a row in the schema_migrations table
+type schemaMigration struct {
+	Version   int `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// MigrationStatus describes whether a known migration has been applied
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+}
+
+// ensureMigrationsTable creates schema_migrations if needed. Databases created
+// before versioned migrations existed (by AutoMigrate) are adopted as version 1.
+func ensureMigrationsTable(db *gorm.DB) error {
+	if db.Migrator().HasTable("schema_migrations") {
+		return nil
+	}
+
+	legacy := db.Migrator().HasTable("categories")
+
+	if err := db.Exec(`CREATE TABLE schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMP NOT NULL
+	)`).Error; err != nil {
+		return err
+	}
+
+	if legacy {
+		all, err := migrations.All(db.Dialector.Name())
+		if err != nil {
+			return err
+		}
+		log.Printf("Adopting existing database as schema version %d", all[0].Version)
+		return db.Create(&schemaMigration{
+			Version:   all[0].Version,
+			Name:      all[0].Name,
+			AppliedAt: time.Now(),
+		}).Error
+	}
+
+	return nil
+}
+
+// appliedMigrations returns the applied migrations keyed by version
+func appliedMigrations(db *gorm.DB) (map[int]schemaMigration, error) {
+	if err := ensureMigrationsTable(db); err != nil {
+		return nil, err
+	}
+
+	var rows []schemaMigration
+	if err := db.Order("version").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int]schemaMigration, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
+
+// CheckSchemaVersion refuses to continue if the database contains migrations
+// that are unknown to this binary
+func CheckSchemaVersion(db *gorm.DB) error {
+	all, err := migrations.All(db.Dialector.Name())
+	if err != nil {
+		return err
+	}
+
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return err
+	}
+
+	known := make(map[int]bool, len(all))
+	for _, m := range all {
+		known[m.Version] = true
+	}
+
+	for version := range applied {
+		if !known[version] {
+			return fmt.Errorf("%w: found migration %d, latest known is %d",
+				ErrSchemaTooNew, version, all[len(all)-1].Version)
+		}
+	}
+
+	return nil
+}
+
+// MigrateUp applies all pending migrations in order and returns how many ran.
+// On SQLite it then creates or drops the full-text search index to match
+// whether this binary has FTS5.
+func MigrateUp(db *gorm.DB) (int, error) {
+	if err := CheckSchemaVersion(db); err != nil {
+		return 0, err
+	}
+
+	all, err := migrations.All(db.Dialector.Name())
+	if err != nil {
+		return 0, err
+	}
+
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, m := range all {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+
+		err := runMigration(db, m.Up, func(tx *gorm.DB) error {
+			return tx.Create(&schemaMigration{
+				Version:   m.Version,
+				Name:      m.Name,
+				AppliedAt: time.Now(),
+			}).Error
+		})
+		if err != nil {
+			return count, fmt.Errorf("migration %04d_%s up: %w", m.Version, m.Name, err)
+		}
+
+		log.Printf("Applied migration %04d_%s", m.Version, m.Name)
+		count++
+	}
+
+	if err := syncSearchIndex(db); err != nil {
+		return count, fmt.Errorf("search index: %w", err)
+	}
+
+	return count, nil
+}
+
+// MigrateDown reverts the most recently applied migrations, up to steps of them
+func MigrateDown(db *gorm.DB, steps int) (int, error) {
+	if err := CheckSchemaVersion(db); err != nil {
+		return 0, err
+	}
+
+	all, err := migrations.All(db.Dialector.Name())
+	if err != nil {
+		return 0, err
+	}
+
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for i := len(all) - 1; i >= 0 && count < steps; i-- {
+		m := all[i]
+		if _, ok := applied[m.Version]; !ok {
+			continue
+		}
+
+		err := runMigration(db, m.Down, func(tx *gorm.DB) error {
+			return tx.Delete(&schemaMigration{}, m.Version).Error
+		})
+		if err != nil {
+			return count, fmt.Errorf("migration %04d_%s down: %w", m.Version, m.Name, err)
+		}
+
+		log.Printf("Reverted migration %04d_%s", m.Version, m.Name)
+		count++
+	}
+
+	return count, nil
+}
+
+// Status lists every known migration and whether it has been applied
+func Status(db *gorm.DB) ([]MigrationStatus, error) {
+	all, err := migrations.All(db.Dialector.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []MigrationStatus
+	for _, m := range all {
+		status := MigrationStatus{Version: m.Version, Name: m.Name}
+		if row, ok := applied[m.Version]; ok {
+			status.Applied = true
+			appliedAt := row.AppliedAt
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// runMigration executes a migration's SQL and its bookkeeping in a single
+// transaction. SQLite table rebuilds require foreign key enforcement to be off,
+// which can only be changed outside a transaction, so a single connection is
+// pinned for the duration and checked for violations before committing.
+// PostgreSQL supports transactional DDL directly.
+func runMigration(db *gorm.DB, sql string, record func(tx *gorm.DB) error) error {
+	if !IsSQLite(db) {
+		return db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(sql).Error; err != nil {
+				return err
+			}
+			return record(tx)
+		})
+	}
+
+	return db.Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("PRAGMA foreign_keys = OFF").Error; err != nil {
+			return err
+		}
+		defer conn.Exec("PRAGMA foreign_keys = ON")
+
+		return conn.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(sql).Error; err != nil {
+				return err
+			}
+
+			var violations []map[string]interface{}
+			if err := tx.Raw("PRAGMA foreign_key_check").Scan(&violations).Error; err != nil {
+				return err
+			}
+			if len(violations) > 0 {
+				return fmt.Errorf("foreign key check failed: %d violation(s)", len(violations))
+			}
+
+			return record(tx)
+		})
+	})
+}
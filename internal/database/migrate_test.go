@@ -0,0 +1,166 @@
+package database_test
+
+import (
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/g-linville/budgeting/internal/database"
+	"github.com/g-linville/budgeting/internal/database/dbtest"
+	"github.com/g-linville/budgeting/internal/database/migrations"
+	"github.com/g-linville/budgeting/internal/models"
+	"gorm.io/gorm"
+)
+
+func appliedCount(t *testing.T, db *gorm.DB) int {
+	t.Helper()
+	statuses, err := database.Status(db)
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	count := 0
+	for _, status := range statuses {
+		if status.Applied {
+			count++
+		}
+	}
+	return count
+}
+
+// userTables lists the tables left in the database, ignoring bookkeeping
+func userTables(t *testing.T, db *gorm.DB) []string {
+	t.Helper()
+	tables, err := db.Migrator().GetTables()
+	if err != nil {
+		t.Fatalf("list tables: %v", err)
+	}
+	var names []string
+	for _, table := range tables {
+		if table != "schema_migrations" && table != "sqlite_sequence" {
+			names = append(names, table)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestMigrationsAlignAcrossDialects(t *testing.T) {
+	sqlite, err := migrations.All(database.DriverSQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	postgres, err := migrations.All(database.DriverPostgres)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sqlite) != len(postgres) {
+		t.Fatalf("%d SQLite migrations, %d PostgreSQL migrations", len(sqlite), len(postgres))
+	}
+	for i := range sqlite {
+		if sqlite[i].Version != postgres[i].Version || sqlite[i].Name != postgres[i].Name {
+			t.Errorf("migration %d: SQLite has %04d_%s, PostgreSQL has %04d_%s", i,
+				sqlite[i].Version, sqlite[i].Name, postgres[i].Version, postgres[i].Name)
+		}
+	}
+}
+
+// Every migration must apply, revert one step at a time and apply again
+func TestMigrateUpDown(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		all, err := migrations.All(db.Dialector.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		count, err := database.MigrateUp(db)
+		if err != nil {
+			t.Fatalf("up: %v", err)
+		}
+		if count != len(all) {
+			t.Fatalf("up applied %d migrations, want %d", count, len(all))
+		}
+		if count, err := database.MigrateUp(db); err != nil || count != 0 {
+			t.Fatalf("second up applied %d migrations, %v; want 0", count, err)
+		}
+
+		for i := len(all) - 1; i >= 0; i-- {
+			count, err := database.MigrateDown(db, 1)
+			if err != nil {
+				t.Fatalf("down: %v", err)
+			}
+			if count != 1 {
+				t.Fatalf("down from %04d_%s reverted %d migrations, want 1", all[i].Version, all[i].Name, count)
+			}
+			if got := appliedCount(t, db); got != i {
+				t.Fatalf("after reverting %04d_%s, %d migrations applied, want %d", all[i].Version, all[i].Name, got, i)
+			}
+		}
+		if tables := userTables(t, db); len(tables) != 0 {
+			t.Errorf("tables left after reverting everything: %v", tables)
+		}
+
+		if count, err := database.MigrateUp(db); err != nil || count != len(all) {
+			t.Fatalf("up after down applied %d migrations, %v; want %d", count, err, len(all))
+		}
+	})
+}
+
+// Databases created by AutoMigrate before versioned migrations existed have
+// the initial schema but no schema_migrations table
+func TestMigrateUpAdoptsLegacyDatabase(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		all, err := migrations.All(db.Dialector.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Exec(all[0].Up).Error; err != nil {
+			t.Fatalf("create legacy schema: %v", err)
+		}
+		if err := db.Exec("INSERT INTO categories (name, color, created_at) VALUES (?, ?, ?)",
+			"Groceries", "#22c55e", time.Now()).Error; err != nil {
+			t.Fatalf("create legacy category: %v", err)
+		}
+
+		count, err := database.MigrateUp(db)
+		if err != nil {
+			t.Fatalf("up: %v", err)
+		}
+		if count != len(all)-1 {
+			t.Errorf("up applied %d migrations, want %d", count, len(all)-1)
+		}
+
+		statuses, err := database.Status(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, status := range statuses {
+			if !status.Applied {
+				t.Errorf("migration %04d_%s not applied", status.Version, status.Name)
+			}
+		}
+
+		var categories []models.Category
+		if err := db.Find(&categories).Error; err != nil {
+			t.Fatal(err)
+		}
+		if len(categories) != 1 || categories[0].Name != "Groceries" {
+			t.Errorf("categories after adoption = %+v, want the legacy Groceries", categories)
+		}
+	})
+}
+
+func TestMigrateUpRefusesNewerSchema(t *testing.T) {
+	dbtest.RunMigrated(t, func(t *testing.T, db *gorm.DB) {
+		if err := db.Exec("INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)",
+			9999, "from_the_future", time.Now()).Error; err != nil {
+			t.Fatal(err)
+		}
+		if _, err := database.MigrateUp(db); !errors.Is(err, database.ErrSchemaTooNew) {
+			t.Errorf("up error = %v, want %v", err, database.ErrSchemaTooNew)
+		}
+		if _, err := database.MigrateDown(db, 1); !errors.Is(err, database.ErrSchemaTooNew) {
+			t.Errorf("down error = %v, want %v", err, database.ErrSchemaTooNew)
+		}
+	})
+}
@@ -0,0 +1,5 @@
+DROP TABLE incomes;
+DROP TABLE expenses;
+DROP TABLE recurring_incomes;
+DROP TABLE recurring_expenses;
+DROP TABLE categories;
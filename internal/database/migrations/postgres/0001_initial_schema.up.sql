@@ -0,0 +1,72 @@
+-- Initial schema (PostgreSQL). Kept structurally identical to the SQLite
+-- version so both backends share migration numbering.
+
+CREATE TABLE categories (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    color VARCHAR(7),
+    created_at TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX idx_categories_name ON categories(name);
+
+CREATE TABLE recurring_expenses (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    amount BIGINT NOT NULL,
+    category_id BIGINT,
+    cadence TEXT NOT NULL,
+    start_date DATE NOT NULL,
+    next_date DATE NOT NULL,
+    end_date DATE,
+    active BOOLEAN DEFAULT true,
+    created_at TIMESTAMPTZ,
+    CONSTRAINT fk_categories_recurring_expenses FOREIGN KEY (category_id) REFERENCES categories(id)
+);
+
+CREATE INDEX idx_recurring_expenses_next_date ON recurring_expenses(next_date);
+
+CREATE TABLE recurring_incomes (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    amount BIGINT NOT NULL,
+    cadence TEXT NOT NULL,
+    start_date DATE NOT NULL,
+    next_date DATE NOT NULL,
+    end_date DATE,
+    active BOOLEAN DEFAULT true,
+    created_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_recurring_incomes_next_date ON recurring_incomes(next_date);
+
+CREATE TABLE expenses (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    amount BIGINT NOT NULL,
+    category_id BIGINT,
+    expense_date DATE NOT NULL,
+    notes TEXT,
+    recurring_id BIGINT,
+    created_at TIMESTAMPTZ,
+    CONSTRAINT fk_recurring_expenses_expenses FOREIGN KEY (recurring_id) REFERENCES recurring_expenses(id),
+    CONSTRAINT fk_categories_expenses FOREIGN KEY (category_id) REFERENCES categories(id)
+);
+
+CREATE INDEX idx_expenses_recurring_id ON expenses(recurring_id);
+CREATE INDEX idx_expenses_expense_date ON expenses(expense_date);
+CREATE INDEX idx_expenses_category_id ON expenses(category_id);
+
+CREATE TABLE incomes (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    amount BIGINT NOT NULL,
+    income_date DATE NOT NULL,
+    notes TEXT,
+    recurring_id BIGINT,
+    created_at TIMESTAMPTZ,
+    CONSTRAINT fk_recurring_incomes_incomes FOREIGN KEY (recurring_id) REFERENCES recurring_incomes(id)
+);
+
+CREATE INDEX idx_incomes_recurring_id ON incomes(recurring_id);
+CREATE INDEX idx_incomes_income_date ON incomes(income_date);
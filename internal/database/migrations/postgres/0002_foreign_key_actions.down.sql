@@ -0,0 +1,13 @@
+ALTER TABLE recurring_expenses
+    DROP CONSTRAINT fk_categories_recurring_expenses,
+    ADD CONSTRAINT fk_categories_recurring_expenses FOREIGN KEY (category_id) REFERENCES categories(id);
+
+ALTER TABLE expenses
+    DROP CONSTRAINT fk_recurring_expenses_expenses,
+    ADD CONSTRAINT fk_recurring_expenses_expenses FOREIGN KEY (recurring_id) REFERENCES recurring_expenses(id),
+    DROP CONSTRAINT fk_categories_expenses,
+    ADD CONSTRAINT fk_categories_expenses FOREIGN KEY (category_id) REFERENCES categories(id);
+
+ALTER TABLE incomes
+    DROP CONSTRAINT fk_recurring_incomes_incomes,
+    ADD CONSTRAINT fk_recurring_incomes_incomes FOREIGN KEY (recurring_id) REFERENCES recurring_incomes(id);
@@ -0,0 +1,16 @@
+-- Deleting a category or recurring rule sets the reference to NULL
+-- instead of failing (ON DELETE SET NULL).
+
+ALTER TABLE recurring_expenses
+    DROP CONSTRAINT fk_categories_recurring_expenses,
+    ADD CONSTRAINT fk_categories_recurring_expenses FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE SET NULL;
+
+ALTER TABLE expenses
+    DROP CONSTRAINT fk_recurring_expenses_expenses,
+    ADD CONSTRAINT fk_recurring_expenses_expenses FOREIGN KEY (recurring_id) REFERENCES recurring_expenses(id) ON DELETE SET NULL,
+    DROP CONSTRAINT fk_categories_expenses,
+    ADD CONSTRAINT fk_categories_expenses FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE SET NULL;
+
+ALTER TABLE incomes
+    DROP CONSTRAINT fk_recurring_incomes_incomes,
+    ADD CONSTRAINT fk_recurring_incomes_incomes FOREIGN KEY (recurring_id) REFERENCES recurring_incomes(id) ON DELETE SET NULL;
@@ -0,0 +1,15 @@
+-- Anything still in the trash is removed permanently
+DELETE FROM expenses WHERE deleted_at IS NOT NULL;
+DELETE FROM incomes WHERE deleted_at IS NOT NULL;
+DELETE FROM categories WHERE deleted_at IS NOT NULL;
+
+DROP INDEX idx_categories_name;
+CREATE UNIQUE INDEX idx_categories_name ON categories(name);
+
+DROP INDEX idx_expenses_deleted_at;
+DROP INDEX idx_incomes_deleted_at;
+DROP INDEX idx_categories_deleted_at;
+
+ALTER TABLE expenses DROP COLUMN deleted_at;
+ALTER TABLE incomes DROP COLUMN deleted_at;
+ALTER TABLE categories DROP COLUMN deleted_at;
@@ -0,0 +1,2 @@
+DROP INDEX idx_incomes_search;
+DROP INDEX idx_expenses_search;
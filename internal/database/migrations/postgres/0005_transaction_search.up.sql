@@ -0,0 +1,7 @@
+-- Full-text search uses expression indexes; the expressions must match the
+-- ones in internal/search exactly for the planner to use them.
+CREATE INDEX idx_expenses_search ON expenses
+    USING GIN (to_tsvector('simple', name || ' ' || COALESCE(notes, '')));
+
+CREATE INDEX idx_incomes_search ON incomes
+    USING GIN (to_tsvector('simple', name || ' ' || COALESCE(notes, '')));
@@ -0,0 +1,29 @@
+-- Free-form labels that cut across categories, e.g. "vacation-2026".
+-- Names are stored normalized (lowercase, no spaces).
+CREATE TABLE tags (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    created_at TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX idx_tags_name ON tags(name);
+
+CREATE TABLE expense_tags (
+    expense_id BIGINT NOT NULL,
+    tag_id BIGINT NOT NULL,
+    PRIMARY KEY (expense_id, tag_id),
+    CONSTRAINT fk_expense_tags_expense FOREIGN KEY (expense_id) REFERENCES expenses(id) ON DELETE CASCADE,
+    CONSTRAINT fk_expense_tags_tag FOREIGN KEY (tag_id) REFERENCES tags(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_expense_tags_tag_id ON expense_tags(tag_id);
+
+CREATE TABLE income_tags (
+    income_id BIGINT NOT NULL,
+    tag_id BIGINT NOT NULL,
+    PRIMARY KEY (income_id, tag_id),
+    CONSTRAINT fk_income_tags_income FOREIGN KEY (income_id) REFERENCES incomes(id) ON DELETE CASCADE,
+    CONSTRAINT fk_income_tags_tag FOREIGN KEY (tag_id) REFERENCES tags(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_income_tags_tag_id ON income_tags(tag_id);
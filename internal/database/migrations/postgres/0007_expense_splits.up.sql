@@ -0,0 +1,13 @@
+-- An expense can be split into lines with their own category and amount.
+-- The lines of a split expense always sum to the expense amount.
+CREATE TABLE expense_splits (
+    id BIGSERIAL PRIMARY KEY,
+    expense_id BIGINT NOT NULL,
+    category_id BIGINT,
+    amount BIGINT NOT NULL,
+    CONSTRAINT fk_expenses_splits FOREIGN KEY (expense_id) REFERENCES expenses(id) ON DELETE CASCADE,
+    CONSTRAINT fk_expense_splits_category FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE SET NULL
+);
+
+CREATE INDEX idx_expense_splits_expense_id ON expense_splits(expense_id);
+CREATE INDEX idx_expense_splits_category_id ON expense_splits(category_id);
@@ -0,0 +1,4 @@
+DROP INDEX idx_expenses_payee_id;
+ALTER TABLE expenses DROP COLUMN payee_id;
+DROP TABLE payee_aliases;
+DROP TABLE payees;
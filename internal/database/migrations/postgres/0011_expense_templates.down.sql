@@ -0,0 +1 @@
+DROP TABLE expense_templates;
@@ -0,0 +1,10 @@
+-- Saved favorites that fill in the quick-add expense form, e.g. "Morning
+-- coffee" at $4.50 in Dining. The amount is optional.
+CREATE TABLE expense_templates (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    amount BIGINT,
+    category_id BIGINT,
+    created_at TIMESTAMPTZ,
+    CONSTRAINT fk_categories_expense_templates FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE SET NULL
+);
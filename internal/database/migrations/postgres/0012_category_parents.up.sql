@@ -0,0 +1,4 @@
+-- Categories can be nested, e.g. "Transportation > Fuel". Reports roll child
+-- spending up into the parent.
+ALTER TABLE categories ADD COLUMN parent_id BIGINT REFERENCES categories(id) ON DELETE SET NULL;
+CREATE INDEX idx_categories_parent_id ON categories(parent_id);
@@ -0,0 +1,22 @@
+-- User-defined rules that categorize and tag expenses. Every condition that
+-- is set must match. Rules run in ascending priority; the first matching
+-- rule with a category sets it, and tags from all matching rules are added.
+CREATE TABLE category_rules (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    priority INTEGER NOT NULL DEFAULT 0,
+    enabled BOOLEAN NOT NULL DEFAULT true,
+    name_contains TEXT,
+    name_regex TEXT,
+    min_amount BIGINT,
+    max_amount BIGINT,
+    payee_id BIGINT,
+    weekday INTEGER,
+    category_id BIGINT,
+    tags TEXT,
+    created_at TIMESTAMPTZ,
+    CONSTRAINT fk_payees_category_rules FOREIGN KEY (payee_id) REFERENCES payees(id) ON DELETE CASCADE,
+    CONSTRAINT fk_categories_category_rules FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE SET NULL
+);
+
+CREATE INDEX idx_category_rules_priority ON category_rules(priority);
@@ -0,0 +1,3 @@
+-- Archived categories are hidden from entry forms but kept in reports and
+-- filters
+ALTER TABLE categories ADD COLUMN archived BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,3 @@
+DROP INDEX idx_incomes_category_id;
+ALTER TABLE incomes DROP COLUMN category_id;
+ALTER TABLE categories DROP COLUMN kind;
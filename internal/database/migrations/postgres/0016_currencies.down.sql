@@ -0,0 +1,7 @@
+DROP TABLE exchange_rates;
+
+ALTER TABLE incomes DROP COLUMN original_amount;
+ALTER TABLE incomes DROP COLUMN currency;
+ALTER TABLE expense_splits DROP COLUMN original_amount;
+ALTER TABLE expenses DROP COLUMN original_amount;
+ALTER TABLE expenses DROP COLUMN currency;
@@ -0,0 +1,72 @@
+-- Initial schema (SQLite). Mirrors the tables previously created by GORM's
+-- AutoMigrate so that existing databases can be adopted as version 1.
+
+CREATE TABLE categories (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    color TEXT,
+    created_at DATETIME
+);
+
+CREATE UNIQUE INDEX idx_categories_name ON categories(name);
+
+CREATE TABLE recurring_expenses (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    amount INTEGER NOT NULL,
+    category_id INTEGER,
+    cadence TEXT NOT NULL,
+    start_date DATE NOT NULL,
+    next_date DATE NOT NULL,
+    end_date DATE,
+    active NUMERIC DEFAULT true,
+    created_at DATETIME,
+    CONSTRAINT fk_categories_recurring_expenses FOREIGN KEY (category_id) REFERENCES categories(id)
+);
+
+CREATE INDEX idx_recurring_expenses_next_date ON recurring_expenses(next_date);
+
+CREATE TABLE recurring_incomes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    amount INTEGER NOT NULL,
+    cadence TEXT NOT NULL,
+    start_date DATE NOT NULL,
+    next_date DATE NOT NULL,
+    end_date DATE,
+    active NUMERIC DEFAULT true,
+    created_at DATETIME
+);
+
+CREATE INDEX idx_recurring_incomes_next_date ON recurring_incomes(next_date);
+
+CREATE TABLE expenses (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    amount INTEGER NOT NULL,
+    category_id INTEGER,
+    expense_date DATE NOT NULL,
+    notes TEXT,
+    recurring_id INTEGER,
+    created_at DATETIME,
+    CONSTRAINT fk_recurring_expenses_expenses FOREIGN KEY (recurring_id) REFERENCES recurring_expenses(id),
+    CONSTRAINT fk_categories_expenses FOREIGN KEY (category_id) REFERENCES categories(id)
+);
+
+CREATE INDEX idx_expenses_recurring_id ON expenses(recurring_id);
+CREATE INDEX idx_expenses_expense_date ON expenses(expense_date);
+CREATE INDEX idx_expenses_category_id ON expenses(category_id);
+
+CREATE TABLE incomes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    amount INTEGER NOT NULL,
+    income_date DATE NOT NULL,
+    notes TEXT,
+    recurring_id INTEGER,
+    created_at DATETIME,
+    CONSTRAINT fk_recurring_incomes_incomes FOREIGN KEY (recurring_id) REFERENCES recurring_incomes(id)
+);
+
+CREATE INDEX idx_incomes_recurring_id ON incomes(recurring_id);
+CREATE INDEX idx_incomes_income_date ON incomes(income_date);
@@ -0,0 +1,59 @@
+-- Rebuild tables so that deleting a category or recurring rule sets the
+-- reference to NULL instead of failing (ON DELETE SET NULL). SQLite cannot
+-- alter constraints in place, so each table is copied into a new one.
+
+CREATE TABLE recurring_expenses_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    amount INTEGER NOT NULL,
+    category_id INTEGER,
+    cadence TEXT NOT NULL,
+    start_date DATE NOT NULL,
+    next_date DATE NOT NULL,
+    end_date DATE,
+    active NUMERIC DEFAULT true,
+    created_at DATETIME,
+    CONSTRAINT fk_categories_recurring_expenses FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE SET NULL
+);
+
+INSERT INTO recurring_expenses_new SELECT id, name, amount, category_id, cadence, start_date, next_date, end_date, active, created_at FROM recurring_expenses;
+DROP TABLE recurring_expenses;
+ALTER TABLE recurring_expenses_new RENAME TO recurring_expenses;
+CREATE INDEX idx_recurring_expenses_next_date ON recurring_expenses(next_date);
+
+CREATE TABLE expenses_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    amount INTEGER NOT NULL,
+    category_id INTEGER,
+    expense_date DATE NOT NULL,
+    notes TEXT,
+    recurring_id INTEGER,
+    created_at DATETIME,
+    CONSTRAINT fk_recurring_expenses_expenses FOREIGN KEY (recurring_id) REFERENCES recurring_expenses(id) ON DELETE SET NULL,
+    CONSTRAINT fk_categories_expenses FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE SET NULL
+);
+
+INSERT INTO expenses_new SELECT id, name, amount, category_id, expense_date, notes, recurring_id, created_at FROM expenses;
+DROP TABLE expenses;
+ALTER TABLE expenses_new RENAME TO expenses;
+CREATE INDEX idx_expenses_recurring_id ON expenses(recurring_id);
+CREATE INDEX idx_expenses_expense_date ON expenses(expense_date);
+CREATE INDEX idx_expenses_category_id ON expenses(category_id);
+
+CREATE TABLE incomes_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    amount INTEGER NOT NULL,
+    income_date DATE NOT NULL,
+    notes TEXT,
+    recurring_id INTEGER,
+    created_at DATETIME,
+    CONSTRAINT fk_recurring_incomes_incomes FOREIGN KEY (recurring_id) REFERENCES recurring_incomes(id) ON DELETE SET NULL
+);
+
+INSERT INTO incomes_new SELECT id, name, amount, income_date, notes, recurring_id, created_at FROM incomes;
+DROP TABLE incomes;
+ALTER TABLE incomes_new RENAME TO incomes;
+CREATE INDEX idx_incomes_recurring_id ON incomes(recurring_id);
+CREATE INDEX idx_incomes_income_date ON incomes(income_date);
@@ -0,0 +1,14 @@
+-- Deleted expenses, incomes and categories are moved to the trash by setting
+-- deleted_at instead of removing the row.
+
+ALTER TABLE expenses ADD COLUMN deleted_at DATETIME;
+ALTER TABLE incomes ADD COLUMN deleted_at DATETIME;
+ALTER TABLE categories ADD COLUMN deleted_at DATETIME;
+
+CREATE INDEX idx_expenses_deleted_at ON expenses(deleted_at);
+CREATE INDEX idx_incomes_deleted_at ON incomes(deleted_at);
+CREATE INDEX idx_categories_deleted_at ON categories(deleted_at);
+
+-- A trashed category must not block creating a new one with the same name
+DROP INDEX idx_categories_name;
+CREATE UNIQUE INDEX idx_categories_name ON categories(name) WHERE deleted_at IS NULL;
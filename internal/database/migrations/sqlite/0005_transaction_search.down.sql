@@ -0,0 +1,10 @@
+-- Dropping the index tables needs FTS5; the triggers do not
+DROP TRIGGER IF EXISTS incomes_fts_update;
+DROP TRIGGER IF EXISTS incomes_fts_delete;
+DROP TRIGGER IF EXISTS incomes_fts_insert;
+DROP TRIGGER IF EXISTS expenses_fts_update;
+DROP TRIGGER IF EXISTS expenses_fts_delete;
+DROP TRIGGER IF EXISTS expenses_fts_insert;
+
+DROP TABLE IF EXISTS incomes_fts;
+DROP TABLE IF EXISTS expenses_fts;
@@ -0,0 +1,5 @@
+-- Full-text indexes over transaction names and notes. FTS5 is only compiled
+-- into SQLite with -tags sqlite_fts5, so the indexes are created outside of
+-- migrations, by database.syncSearchIndex after every MigrateUp, and only
+-- when the running binary has FTS5. Search falls back to LIKE otherwise.
+SELECT 1;
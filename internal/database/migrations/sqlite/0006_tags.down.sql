@@ -0,0 +1,3 @@
+DROP TABLE income_tags;
+DROP TABLE expense_tags;
+DROP TABLE tags;
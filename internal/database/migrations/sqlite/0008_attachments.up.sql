@@ -0,0 +1,15 @@
+-- Receipts and other documents attached to expenses. The file contents live
+-- on disk, named by their SHA-256 hash, so identical files are stored once.
+CREATE TABLE attachments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    expense_id INTEGER NOT NULL,
+    hash TEXT NOT NULL,
+    file_name TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    size INTEGER NOT NULL,
+    created_at DATETIME,
+    CONSTRAINT fk_expenses_attachments FOREIGN KEY (expense_id) REFERENCES expenses(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_attachments_expense_id ON attachments(expense_id);
+CREATE INDEX idx_attachments_hash ON attachments(hash);
@@ -0,0 +1,19 @@
+-- Money returned for an expense, either refunded by the merchant or
+-- reimbursed (e.g. by an employer). Refunds reduce spending as of their own
+-- date instead of being recorded as income.
+CREATE TABLE refunds (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    expense_id INTEGER NOT NULL,
+    kind TEXT NOT NULL,
+    amount INTEGER NOT NULL,
+    refund_date DATE NOT NULL,
+    notes TEXT,
+    created_at DATETIME,
+    CONSTRAINT fk_expenses_refunds FOREIGN KEY (expense_id) REFERENCES expenses(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_refunds_expense_id ON refunds(expense_id);
+CREATE INDEX idx_refunds_refund_date ON refunds(refund_date);
+
+-- Expenses expected to be paid back, tracked until reimbursed in full
+ALTER TABLE expenses ADD COLUMN reimbursable NUMERIC NOT NULL DEFAULT false;
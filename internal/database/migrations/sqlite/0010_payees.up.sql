@@ -0,0 +1,26 @@
+-- Merchants and other payees. Expense names stay free text; each expense is
+-- linked to the payee whose name or alias matches it, so "AMZN Mktp" and
+-- "Amazon.com" can both count as Amazon.
+CREATE TABLE payees (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    created_at DATETIME
+);
+
+CREATE UNIQUE INDEX idx_payees_name ON payees(name);
+
+-- Patterns are compared to normalized expense names (lowercase, punctuation
+-- removed); "*" matches any run of characters.
+CREATE TABLE payee_aliases (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    payee_id INTEGER NOT NULL,
+    pattern TEXT NOT NULL,
+    created_at DATETIME,
+    CONSTRAINT fk_payees_aliases FOREIGN KEY (payee_id) REFERENCES payees(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX idx_payee_aliases_pattern ON payee_aliases(pattern);
+CREATE INDEX idx_payee_aliases_payee_id ON payee_aliases(payee_id);
+
+ALTER TABLE expenses ADD COLUMN payee_id INTEGER REFERENCES payees(id) ON DELETE SET NULL;
+CREATE INDEX idx_expenses_payee_id ON expenses(payee_id);
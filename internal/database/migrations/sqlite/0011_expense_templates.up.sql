@@ -0,0 +1,10 @@
+-- Saved favorites that fill in the quick-add expense form, e.g. "Morning
+-- coffee" at $4.50 in Dining. The amount is optional.
+CREATE TABLE expense_templates (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    amount INTEGER,
+    category_id INTEGER,
+    created_at DATETIME,
+    CONSTRAINT fk_categories_expense_templates FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE SET NULL
+);
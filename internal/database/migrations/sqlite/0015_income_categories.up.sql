@@ -0,0 +1,5 @@
+-- Categories are either for expenses or for income, and income can be
+-- categorized (salary, freelance, interest, ...)
+ALTER TABLE categories ADD COLUMN kind TEXT NOT NULL DEFAULT 'expense';
+ALTER TABLE incomes ADD COLUMN category_id INTEGER REFERENCES categories(id) ON DELETE SET NULL;
+CREATE INDEX idx_incomes_category_id ON incomes(category_id);
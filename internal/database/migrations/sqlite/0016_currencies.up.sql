@@ -0,0 +1,28 @@
+-- Transactions keep the currency (ISO 4217 code) and amount, in that
+-- currency's minor units, they were entered in. amount stays in the base
+-- currency (BUDGETING_BASE_CURRENCY), converted with the exchange rates
+-- below, so totals and reports need no conversion. An empty currency is the
+-- base currency.
+ALTER TABLE expenses ADD COLUMN currency TEXT NOT NULL DEFAULT '';
+ALTER TABLE expenses ADD COLUMN original_amount INTEGER NOT NULL DEFAULT 0;
+UPDATE expenses SET original_amount = amount;
+
+ALTER TABLE expense_splits ADD COLUMN original_amount INTEGER NOT NULL DEFAULT 0;
+UPDATE expense_splits SET original_amount = amount;
+
+ALTER TABLE incomes ADD COLUMN currency TEXT NOT NULL DEFAULT '';
+ALTER TABLE incomes ADD COLUMN original_amount INTEGER NOT NULL DEFAULT 0;
+UPDATE incomes SET original_amount = amount;
+
+-- The value of one unit of a currency in the base currency as of a date.
+-- Rates are exact decimals, stored as text.
+CREATE TABLE exchange_rates (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    currency TEXT NOT NULL,
+    rate_date DATE NOT NULL,
+    rate TEXT NOT NULL,
+    source TEXT NOT NULL DEFAULT 'manual',
+    created_at DATETIME
+);
+
+CREATE UNIQUE INDEX idx_exchange_rates_currency_date ON exchange_rates(currency, rate_date);
@@ -0,0 +1,2 @@
+DROP INDEX idx_categories_kind_name;
+CREATE UNIQUE INDEX idx_categories_name ON categories(name) WHERE deleted_at IS NULL;
@@ -0,0 +1,4 @@
+-- Category names only need to be unique within a kind, ignoring case, so
+-- there can be an "Other" for expenses and another for income
+DROP INDEX idx_categories_name;
+CREATE UNIQUE INDEX idx_categories_kind_name ON categories(kind, LOWER(name)) WHERE deleted_at IS NULL;
@@ -0,0 +1,116 @@
+package database
+
+import (
+	"log"
+
+	"gorm.io/gorm"
+)
+
+// searchIndexSQL creates the SQLite full-text indexes over transaction names
+// and notes. These are external content tables: the text lives only in
+// expenses/incomes, and the triggers keep the indexes in sync.
+const searchIndexSQL = `
+CREATE VIRTUAL TABLE IF NOT EXISTS expenses_fts USING fts5(
+    name, notes,
+    content = 'expenses', content_rowid = 'id',
+    tokenize = 'unicode61 remove_diacritics 2',
+    prefix = '2 3'
+);
+
+CREATE VIRTUAL TABLE IF NOT EXISTS incomes_fts USING fts5(
+    name, notes,
+    content = 'incomes', content_rowid = 'id',
+    tokenize = 'unicode61 remove_diacritics 2',
+    prefix = '2 3'
+);
+
+CREATE TRIGGER IF NOT EXISTS expenses_fts_insert AFTER INSERT ON expenses BEGIN
+    INSERT INTO expenses_fts(rowid, name, notes) VALUES (new.id, new.name, new.notes);
+END;
+
+CREATE TRIGGER IF NOT EXISTS expenses_fts_delete AFTER DELETE ON expenses BEGIN
+    INSERT INTO expenses_fts(expenses_fts, rowid, name, notes) VALUES ('delete', old.id, old.name, old.notes);
+END;
+
+CREATE TRIGGER IF NOT EXISTS expenses_fts_update AFTER UPDATE OF name, notes ON expenses BEGIN
+    INSERT INTO expenses_fts(expenses_fts, rowid, name, notes) VALUES ('delete', old.id, old.name, old.notes);
+    INSERT INTO expenses_fts(rowid, name, notes) VALUES (new.id, new.name, new.notes);
+END;
+
+CREATE TRIGGER IF NOT EXISTS incomes_fts_insert AFTER INSERT ON incomes BEGIN
+    INSERT INTO incomes_fts(rowid, name, notes) VALUES (new.id, new.name, new.notes);
+END;
+
+CREATE TRIGGER IF NOT EXISTS incomes_fts_delete AFTER DELETE ON incomes BEGIN
+    INSERT INTO incomes_fts(incomes_fts, rowid, name, notes) VALUES ('delete', old.id, old.name, old.notes);
+END;
+
+CREATE TRIGGER IF NOT EXISTS incomes_fts_update AFTER UPDATE OF name, notes ON incomes BEGIN
+    INSERT INTO incomes_fts(incomes_fts, rowid, name, notes) VALUES ('delete', old.id, old.name, old.notes);
+    INSERT INTO incomes_fts(rowid, name, notes) VALUES (new.id, new.name, new.notes);
+END;
+
+INSERT INTO expenses_fts(expenses_fts) VALUES ('rebuild');
+INSERT INTO incomes_fts(incomes_fts) VALUES ('rebuild');
+`
+
+// searchIndexTriggers are the triggers created by searchIndexSQL
+var searchIndexTriggers = []string{
+	"expenses_fts_insert", "expenses_fts_delete", "expenses_fts_update",
+	"incomes_fts_insert", "incomes_fts_delete", "incomes_fts_update",
+}
+
+// HasFTS5 reports whether db is SQLite with the FTS5 extension compiled in
+// (go build -tags sqlite_fts5). Transaction search uses the full-text index
+// when it is, and falls back to LIKE matching when it is not.
+func HasFTS5(db *gorm.DB) bool {
+	if !IsSQLite(db) {
+		return false
+	}
+	var hasFTS5 bool
+	if err := db.Raw("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&hasFTS5).Error; err != nil {
+		return false
+	}
+	return hasFTS5
+}
+
+// syncSearchIndex matches the SQLite full-text index to the running binary.
+// With FTS5 it creates the index if it is missing and rebuilds it from the
+// transactions, which catches up on writes made while it was dropped.
+// Without FTS5 it drops the triggers, since every write to expenses or
+// incomes would fail on them; the index tables are left for a later build
+// with FTS5 to rebuild.
+func syncSearchIndex(db *gorm.DB) error {
+	if !IsSQLite(db) {
+		return nil
+	}
+
+	var triggers int64
+	if err := db.Raw("SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND name IN ?",
+		searchIndexTriggers).Scan(&triggers).Error; err != nil {
+		return err
+	}
+
+	if !HasFTS5(db) {
+		log.Printf("SQLite was built without FTS5; transaction search falls back to LIKE matching")
+		if triggers == 0 {
+			return nil
+		}
+		return db.Transaction(func(tx *gorm.DB) error {
+			for _, trigger := range searchIndexTriggers {
+				if err := tx.Exec("DROP TRIGGER IF EXISTS " + trigger).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+
+	if int(triggers) == len(searchIndexTriggers) {
+		return nil
+	}
+	log.Printf("Building the transaction search index")
+	return db.Transaction(func(tx *gorm.DB) error {
+		return tx.Exec(searchIndexSQL).Error
+	})
+}
@@ -0,0 +1,77 @@
+package database_test
+
+import (
+	"io"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/g-linville/budgeting/internal/database"
+	"github.com/g-linville/budgeting/internal/database/dbtest"
+	"github.com/g-linville/budgeting/internal/models"
+	"gorm.io/gorm"
+)
+
+func countSearchTriggers(t *testing.T, db *gorm.DB) int64 {
+	t.Helper()
+	var count int64
+	if err := db.Raw("SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND name LIKE '%_fts_%'").Scan(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+func migrateQuietly(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+	if _, err := database.MigrateUp(db); err != nil {
+		t.Fatalf("up: %v", err)
+	}
+}
+
+// A database written by a build without FTS5 has no search triggers, so the
+// next build with FTS5 must restore them and index what was missed
+func TestMigrateUpSyncsSearchIndex(t *testing.T) {
+	db := dbtest.Open(t, database.DriverSQLite)
+	migrateQuietly(t, db)
+
+	if !database.HasFTS5(db) {
+		if n := countSearchTriggers(t, db); n != 0 {
+			t.Fatalf("%d search triggers without FTS5, want 0", n)
+		}
+		if err := db.Create(&models.Expense{Name: "Kayak rental", Amount: 100, ExpenseDate: time.Now()}).Error; err != nil {
+			t.Fatalf("insert without FTS5: %v", err)
+		}
+		t.Skip("rebuilding the index needs -tags sqlite_fts5")
+	}
+
+	if n := countSearchTriggers(t, db); n != 6 {
+		t.Fatalf("%d search triggers, want 6", n)
+	}
+	for _, trigger := range []string{
+		"expenses_fts_insert", "expenses_fts_delete", "expenses_fts_update",
+		"incomes_fts_insert", "incomes_fts_delete", "incomes_fts_update",
+	} {
+		if err := db.Exec("DROP TRIGGER " + trigger).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Create(&models.Expense{Name: "Kayak rental", Amount: 100, ExpenseDate: time.Now()}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	migrateQuietly(t, db)
+
+	if n := countSearchTriggers(t, db); n != 6 {
+		t.Errorf("%d search triggers after sync, want 6", n)
+	}
+	var matches int64
+	if err := db.Raw("SELECT COUNT(*) FROM expenses_fts WHERE expenses_fts MATCH 'kayak'").Scan(&matches).Error; err != nil {
+		t.Fatal(err)
+	}
+	if matches != 1 {
+		t.Errorf("%d index matches for an expense added while the index was dropped, want 1", matches)
+	}
+}
@@ -0,0 +1,103 @@
+// Package duplicates decides whether a new transaction is probably one that
+// has already been recorded. The same rules apply to manual entry and to
+// importers, so a transaction flagged in one is flagged in the other.
+package duplicates
+
+import (
+	"sort"
+	"time"
+
+	"github.com/g-linville/budgeting/internal/models"
+	"github.com/g-linville/budgeting/internal/utils"
+	"gorm.io/gorm"
+)
+
+// WindowDays is how many days apart two transactions may be dated and still
+// match, to allow for the gap between purchase and posting dates
+const WindowDays = 3
+
+// Candidate is a transaction that is about to be recorded
+type Candidate struct {
+	Name   string
+	Amount int // Minor units of the base currency
+	Date   time.Time
+}
+
+// IsDuplicate reports whether two transactions are probably the same one:
+// equal amounts, equal normalized names and dates at most WindowDays apart.
+// Importers use it to compare rows within a file as well as against the
+// database.
+func IsDuplicate(a, b Candidate) bool {
+	if a.Amount != b.Amount || utils.NormalizeName(a.Name) != utils.NormalizeName(b.Name) {
+		return false
+	}
+	return dayDiff(a.Date, b.Date) <= WindowDays
+}
+
+// dayDiff returns the number of calendar days between a and b
+func dayDiff(a, b time.Time) int {
+	da := time.Date(a.Year(), a.Month(), a.Day(), 0, 0, 0, 0, time.UTC)
+	db := time.Date(b.Year(), b.Month(), b.Day(), 0, 0, 0, 0, time.UTC)
+	days := int(da.Sub(db).Hours() / 24)
+	if days < 0 {
+		days = -days
+	}
+	return days
+}
+
+// window returns the half-open YYYY-MM-DD range of dates that can match c
+func window(c Candidate) (start, end string) {
+	day := time.Date(c.Date.Year(), c.Date.Month(), c.Date.Day(), 0, 0, 0, 0, time.Local)
+	return day.AddDate(0, 0, -WindowDays).Format("2006-01-02"),
+		day.AddDate(0, 0, WindowDays+1).Format("2006-01-02")
+}
+
+// FindExpenses returns existing expenses (excluding the trash) that c
+// probably duplicates, closest date first
+func FindExpenses(db *gorm.DB, c Candidate) ([]models.Expense, error) {
+	start, end := window(c)
+	var nearby []models.Expense
+	if err := db.Where("amount = ? AND expense_date >= ? AND expense_date < ?", c.Amount, start, end).
+		Order("expense_date, id").
+		Find(&nearby).Error; err != nil {
+		return nil, err
+	}
+
+	var matches []models.Expense
+	for _, e := range nearby {
+		if IsDuplicate(c, Candidate{Name: e.Name, Amount: e.Amount, Date: e.ExpenseDate}) {
+			matches = append(matches, e)
+		}
+	}
+	sortByDistance(matches, c.Date, func(e models.Expense) time.Time { return e.ExpenseDate })
+	return matches, nil
+}
+
+// FindIncomes returns existing income (excluding the trash) that c probably
+// duplicates, closest date first
+func FindIncomes(db *gorm.DB, c Candidate) ([]models.Income, error) {
+	start, end := window(c)
+	var nearby []models.Income
+	if err := db.Where("amount = ? AND income_date >= ? AND income_date < ?", c.Amount, start, end).
+		Order("income_date, id").
+		Find(&nearby).Error; err != nil {
+		return nil, err
+	}
+
+	var matches []models.Income
+	for _, i := range nearby {
+		if IsDuplicate(c, Candidate{Name: i.Name, Amount: i.Amount, Date: i.IncomeDate}) {
+			matches = append(matches, i)
+		}
+	}
+	sortByDistance(matches, c.Date, func(i models.Income) time.Time { return i.IncomeDate })
+	return matches, nil
+}
+
+// sortByDistance orders records by how far their date is from date, keeping
+// the existing order for ties
+func sortByDistance[T any](records []T, date time.Time, dateOf func(T) time.Time) {
+	sort.SliceStable(records, func(i, j int) bool {
+		return dayDiff(dateOf(records[i]), date) < dayDiff(dateOf(records[j]), date)
+	})
+}
@@ -0,0 +1,222 @@
+// Package exchange converts transactions entered in other currencies to the
+// base currency with the exchange rates stored in the database, and
+// recalculates them when the rates change.
+package exchange
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/g-linville/budgeting/internal/audit"
+	"github.com/g-linville/budgeting/internal/currency"
+	"github.com/g-linville/budgeting/internal/models"
+	"gorm.io/gorm"
+)
+
+// ErrNoRate is returned when a currency has no stored exchange rate
+var ErrNoRate = errors.New("no exchange rate")
+
+// SaveRates stores rates, replacing any existing rate for the same currency
+// and date, and returns how many were saved
+func SaveRates(tx *gorm.DB, rates []currency.Rate, source string) (int, error) {
+	for _, r := range rates {
+		day := r.Date.Format("2006-01-02")
+		next := r.Date.AddDate(0, 0, 1).Format("2006-01-02")
+		if err := tx.Where("currency = ? AND rate_date >= ? AND rate_date < ?", r.Currency.Code, day, next).
+			Delete(&models.ExchangeRate{}).Error; err != nil {
+			return 0, err
+		}
+		rate := models.ExchangeRate{Currency: r.Currency.Code, RateDate: r.Date, Rate: r.Rate, Source: source}
+		if err := tx.Create(&rate).Error; err != nil {
+			return 0, err
+		}
+	}
+	return len(rates), nil
+}
+
+// RateFor returns the rate for a currency on a date: the latest one on or
+// before the date, or failing that the earliest one after it
+func RateFor(db *gorm.DB, code string, date time.Time) (*big.Rat, error) {
+	next := date.AddDate(0, 0, 1).Format("2006-01-02")
+
+	var rate models.ExchangeRate
+	result := db.Where("currency = ? AND rate_date < ?", code, next).Order("rate_date DESC").Limit(1).Find(&rate)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		result = db.Where("currency = ? AND rate_date >= ?", code, next).Order("rate_date").Limit(1).Find(&rate)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil, fmt.Errorf("%w for %s", ErrNoRate, code)
+		}
+	}
+	return currency.ParseRate(rate.Rate)
+}
+
+// ToBase converts an amount entered in c on date into the base currency
+func ToBase(db *gorm.DB, minor int, c, base currency.Currency, date time.Time) (int, error) {
+	if c.Code == base.Code {
+		return minor, nil
+	}
+	rate, err := RateFor(db, c.Code, date)
+	if err != nil {
+		return 0, err
+	}
+	converted, err := currency.New(minor, c).Convert(base, rate)
+	return converted.Minor, err
+}
+
+// BelowRefundedError is returned when converting an expense again would
+// make it smaller than the refunds already recorded against it
+type BelowRefundedError struct {
+	Name     string
+	Amount   currency.Money
+	Refunded currency.Money
+}
+
+func (e *BelowRefundedError) Error() string {
+	return fmt.Sprintf("%q would be %s, less than the %s already refunded", e.Name, e.Amount, e.Refunded)
+}
+
+// baseAmount returns what a transaction entered in another currency is
+// worth in the base currency at the rate for its date. It reports false for
+// transactions in the base currency or in a currency with no rate.
+func baseAmount(tx *gorm.DB, code string, original int, base currency.Currency, date time.Time) (int, bool, error) {
+	if code == "" || code == base.Code {
+		return 0, false, nil
+	}
+	amount, err := ToBase(tx, original, currency.Of(code, base), base, date)
+	if errors.Is(err, ErrNoRate) {
+		return 0, false, nil
+	}
+	return amount, err == nil, err
+}
+
+// setExpenseAmount changes an expense's base amount and shares it across its
+// split lines, which must be loaded. Refunds can never exceed the expense.
+func setExpenseAmount(tx *gorm.DB, e *models.Expense, amount int, base currency.Currency) error {
+	var refunded int
+	if err := tx.Model(&models.Refund{}).Where("expense_id = ?", e.ID).
+		Select("COALESCE(SUM(amount), 0)").Scan(&refunded).Error; err != nil {
+		return err
+	}
+	if amount < refunded {
+		return &BelowRefundedError{Name: e.Name, Amount: currency.New(amount, base), Refunded: currency.New(refunded, base)}
+	}
+
+	if err := tx.Unscoped().Model(e).Update("amount", amount).Error; err != nil {
+		return err
+	}
+	return allocateSplits(tx, e.Splits, currency.New(amount, base))
+}
+
+// ReconvertExpense recalculates the base amount of an expense entered in
+// another currency at the rate for its date, e.g. after the date moved. Its
+// split lines must be loaded. It reports whether the amount changed.
+func ReconvertExpense(tx *gorm.DB, e *models.Expense, base currency.Currency) (bool, error) {
+	amount, ok, err := baseAmount(tx, e.Currency, e.OriginalAmount, base, e.ExpenseDate)
+	if err != nil || !ok || amount == e.Amount {
+		return false, err
+	}
+	return true, setExpenseAmount(tx, e, amount, base)
+}
+
+// ReconvertIncome recalculates the base amount of income entered in another
+// currency at the rate for its date, reporting whether it changed
+func ReconvertIncome(tx *gorm.DB, i *models.Income, base currency.Currency) (bool, error) {
+	amount, ok, err := baseAmount(tx, i.Currency, i.OriginalAmount, base, i.IncomeDate)
+	if err != nil || !ok || amount == i.Amount {
+		return false, err
+	}
+	return true, tx.Unscoped().Model(i).Update("amount", amount).Error
+}
+
+// Reconvert recalculates the base amounts of transactions entered in other
+// currencies, after exchange rates have changed, and returns how many
+// changed. Transactions whose currency has no rate are left alone. Each
+// change is recorded in the audit log, so earlier changes can still be
+// undone. A rate that would leave an expense below its refunds fails with a
+// *BelowRefundedError.
+func Reconvert(tx *gorm.DB, base currency.Currency) (int, error) {
+	changed := 0
+
+	var expenses []models.Expense
+	if err := tx.Unscoped().Preload("Splits", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Where("currency <> '' AND currency <> ?", base.Code).Find(&expenses).Error; err != nil {
+		return 0, err
+	}
+	for i := range expenses {
+		e := &expenses[i]
+		amount, ok, err := baseAmount(tx, e.Currency, e.OriginalAmount, base, e.ExpenseDate)
+		if err != nil {
+			return 0, err
+		}
+		if !ok || amount == e.Amount {
+			continue
+		}
+		before, err := audit.Snapshot(tx, audit.EntityExpense, e.ID)
+		if err != nil {
+			return 0, err
+		}
+		if err := setExpenseAmount(tx, e, amount, base); err != nil {
+			return 0, err
+		}
+		if err := audit.Record(tx, audit.EntityExpense, e.ID, audit.ActionUpdate, before); err != nil {
+			return 0, err
+		}
+		changed++
+	}
+
+	var incomes []models.Income
+	if err := tx.Unscoped().Where("currency <> '' AND currency <> ?", base.Code).Find(&incomes).Error; err != nil {
+		return 0, err
+	}
+	for _, i := range incomes {
+		amount, ok, err := baseAmount(tx, i.Currency, i.OriginalAmount, base, i.IncomeDate)
+		if err != nil {
+			return 0, err
+		}
+		if !ok || amount == i.Amount {
+			continue
+		}
+		before, err := audit.Snapshot(tx, audit.EntityIncome, i.ID)
+		if err != nil {
+			return 0, err
+		}
+		if err := tx.Unscoped().Model(&i).Update("amount", amount).Error; err != nil {
+			return 0, err
+		}
+		if err := audit.Record(tx, audit.EntityIncome, i.ID, audit.ActionUpdate, before); err != nil {
+			return 0, err
+		}
+		changed++
+	}
+	return changed, nil
+}
+
+// allocateSplits shares an expense's base amount across its split lines in
+// proportion to their original amounts
+func allocateSplits(tx *gorm.DB, splits []models.ExpenseSplit, amount currency.Money) error {
+	if len(splits) == 0 {
+		return nil
+	}
+	weights := make([]int, len(splits))
+	for i, s := range splits {
+		weights[i] = s.OriginalAmount
+	}
+	parts, err := amount.Allocate(weights)
+	if err != nil {
+		return err
+	}
+	for i, part := range parts {
+		if err := tx.Model(&splits[i]).Update("amount", part.Minor).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
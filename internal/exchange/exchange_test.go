@@ -0,0 +1,111 @@
+package exchange
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/g-linville/budgeting/internal/currency"
+	"github.com/g-linville/budgeting/internal/database/dbtest"
+	"github.com/g-linville/budgeting/internal/models"
+	"gorm.io/gorm"
+)
+
+func day(t *testing.T, date string) time.Time {
+	t.Helper()
+	d, err := time.ParseInLocation("2006-01-02", date, time.Local)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func mustCurrency(t *testing.T, code string) currency.Currency {
+	t.Helper()
+	c, ok := currency.Lookup(code)
+	if !ok {
+		t.Fatalf("unknown currency %s", code)
+	}
+	return c
+}
+
+func TestRateFor(t *testing.T) {
+	dbtest.RunMigrated(t, func(t *testing.T, db *gorm.DB) {
+		eur := mustCurrency(t, "EUR")
+		if _, err := SaveRates(db, []currency.Rate{
+			{Currency: eur, Date: day(t, "2026-03-01"), Rate: "1.08"},
+			{Currency: eur, Date: day(t, "2026-03-10"), Rate: "1.10"},
+		}, models.RateSourceCSV); err != nil {
+			t.Fatal(err)
+		}
+
+		tests := []struct {
+			date string
+			want string
+		}{
+			{"2026-02-15", "1.08"}, // Earliest after, failing one before
+			{"2026-03-01", "1.08"},
+			{"2026-03-09", "1.08"},
+			{"2026-03-10", "1.10"},
+			{"2026-04-01", "1.10"},
+		}
+		for _, tt := range tests {
+			rate, err := RateFor(db, "EUR", day(t, tt.date))
+			if err != nil {
+				t.Fatalf("RateFor(%s): %v", tt.date, err)
+			}
+			want, _ := currency.ParseRate(tt.want)
+			if rate.Cmp(want) != 0 {
+				t.Errorf("RateFor(%s) = %s, want %s", tt.date, rate.FloatString(2), tt.want)
+			}
+		}
+
+		if _, err := RateFor(db, "GBP", day(t, "2026-03-01")); !errors.Is(err, ErrNoRate) {
+			t.Errorf("RateFor(GBP) error = %v, want ErrNoRate", err)
+		}
+	})
+}
+
+// Reconverting shares the new amount across split lines, and refuses to
+// leave an expense below what has been refunded on it
+func TestReconvert(t *testing.T) {
+	dbtest.RunMigrated(t, func(t *testing.T, db *gorm.DB) {
+		usd, eur := mustCurrency(t, "USD"), mustCurrency(t, "EUR")
+		saveRate := func(rate string) {
+			t.Helper()
+			if _, err := SaveRates(db, []currency.Rate{{Currency: eur, Date: day(t, "2026-03-01"), Rate: rate}}, models.RateSourceCSV); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		expense := models.Expense{
+			Name: "Hotel", Amount: 10000, Currency: "EUR", OriginalAmount: 10000, ExpenseDate: day(t, "2026-03-05"),
+			Splits: []models.ExpenseSplit{{Amount: 5000, OriginalAmount: 5000}, {Amount: 5000, OriginalAmount: 5000}},
+		}
+		if err := db.Create(&expense).Error; err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Create(&models.Refund{ExpenseID: expense.ID, Kind: models.RefundKindRefund, Amount: 9000, RefundDate: day(t, "2026-03-06")}).Error; err != nil {
+			t.Fatal(err)
+		}
+
+		saveRate("1.0001")
+		changed, err := Reconvert(db, usd)
+		if err != nil || changed != 1 {
+			t.Fatalf("Reconvert = %d, %v; want 1 change", changed, err)
+		}
+		var splits []models.ExpenseSplit
+		if err := db.Where("expense_id = ?", expense.ID).Order("id").Find(&splits).Error; err != nil {
+			t.Fatal(err)
+		}
+		if len(splits) != 2 || splits[0].Amount+splits[1].Amount != 10001 {
+			t.Errorf("splits = %+v, want them to add up to 10001", splits)
+		}
+
+		saveRate("0.5")
+		var belowRefunded *BelowRefundedError
+		if _, err := Reconvert(db, usd); !errors.As(err, &belowRefunded) {
+			t.Errorf("Reconvert below refunds error = %v, want *BelowRefundedError", err)
+		}
+	})
+}
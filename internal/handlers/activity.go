@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/g-linville/budgeting/internal/audit"
+	"github.com/g-linville/budgeting/internal/models"
+	"github.com/g-linville/budgeting/internal/validation"
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+)
+
+// ActivityItem is an audit log entry prepared for display
+type ActivityItem struct {
+	ID         uint
+	When       string // "2026-01-14 15:04"
+	EntityType string
+	EntityID   uint
+	Action     string
+	Name       string   // Name of the record from its snapshot
+	Changes    []string // Fields changed by an update
+	Undone     bool
+	Undoable   bool
+}
+
+// getActivityData loads the most recent audit entries
+func (h *Handler) getActivityData(limit int) ([]ActivityItem, error) {
+	var entries []models.AuditEntry
+	if err := h.db.Order("created_at DESC, id DESC").Limit(limit).Find(&entries).Error; err != nil {
+		return nil, err
+	}
+
+	var items []ActivityItem
+	for _, e := range entries {
+		name := audit.SnapshotName(e.After)
+		if name == "" {
+			name = audit.SnapshotName(e.Before)
+		}
+
+		var changes []string
+		if e.Action == audit.ActionUpdate || e.Action == audit.ActionUndo {
+			changes = audit.ChangedFields(e.Before, e.After)
+		}
+
+		items = append(items, ActivityItem{
+			ID:         e.ID,
+			When:       e.CreatedAt.Local().Format("2006-01-02 15:04"),
+			EntityType: e.EntityType,
+			EntityID:   e.EntityID,
+			Action:     e.Action,
+			Name:       name,
+			Changes:    changes,
+			Undone:     e.UndoneAt != nil,
+			Undoable:   e.UndoneAt == nil && e.Action != audit.ActionUndo,
+		})
+	}
+
+	return items, nil
+}
+
+// ListActivity handles GET /activity
+func (h *Handler) ListActivity(w http.ResponseWriter, r *http.Request) {
+	items, err := h.getActivityData(100)
+	if err != nil {
+		log.Printf("Error querying activity: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	data := struct {
+		Activity []ActivityItem
+	}{
+		Activity: items,
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := h.templates.ExecuteTemplate(w, "activity-modal", data); err != nil {
+		log.Printf("Error executing template: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// UndoActivity handles POST /activity/{id}/undo
+func (h *Handler) UndoActivity(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := audit.Undo(h.db, uint(id)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Activity not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, audit.ErrConflict) || errors.Is(err, audit.ErrAlreadyUndone) || errors.Is(err, audit.ErrNotUndoable) {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Header().Set("HX-Retarget", "#activity-errors")
+			w.Header().Set("HX-Reswap", "innerHTML")
+			w.WriteHeader(http.StatusConflict)
+			h.templates.ExecuteTemplate(w, "validation-errors", validation.ValidationErrors{
+				{Field: "undo", Message: err.Error()},
+			})
+			return
+		}
+		log.Printf("Error undoing change: %v", err)
+		http.Error(w, "Failed to undo change", http.StatusInternalServerError)
+		return
+	}
+
+	items, err := h.getActivityData(100)
+	if err != nil {
+		log.Printf("Error querying activity: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	data, err := h.getRefreshData()
+	if err != nil {
+		log.Printf("Error getting dashboard data: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	// Render activity list
+	activityData := struct {
+		Activity []ActivityItem
+	}{
+		Activity: items,
+	}
+	if err := h.templates.ExecuteTemplate(w, "activity-list", activityData); err != nil {
+		log.Printf("Error executing template: %v", err)
+		return
+	}
+
+	// Render OOB recent transactions and overview stats
+	oobBuf := new(bytes.Buffer)
+	if err := h.templates.ExecuteTemplate(oobBuf, "recent-transactions-oob", data); err != nil {
+		log.Printf("Error executing OOB template: %v", err)
+		return
+	}
+	if err := h.templates.ExecuteTemplate(oobBuf, "overview-stats-oob", data); err != nil {
+		log.Printf("Error executing OOB template: %v", err)
+		return
+	}
+	w.Write(oobBuf.Bytes())
+}
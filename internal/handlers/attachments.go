@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/g-linville/budgeting/internal/attachments"
+	"github.com/g-linville/budgeting/internal/models"
+	"github.com/g-linville/budgeting/internal/validation"
+	"github.com/go-chi/chi/v5"
+)
+
+// AttachmentsData holds the data for an expense's attachments modal
+type AttachmentsData struct {
+	Expense     models.Expense
+	Attachments []models.Attachment
+	MaxMB       int64
+}
+
+// getAttachmentsData loads an expense and its attachments
+func (h *Handler) getAttachmentsData(expenseID uint64) (AttachmentsData, error) {
+	var expense models.Expense
+	if err := h.db.First(&expense, expenseID).Error; err != nil {
+		return AttachmentsData{}, err
+	}
+
+	var list []models.Attachment
+	if err := h.db.Where("expense_id = ?", expense.ID).Order("created_at, id").Find(&list).Error; err != nil {
+		return AttachmentsData{}, err
+	}
+
+	return AttachmentsData{
+		Expense:     expense,
+		Attachments: list,
+		MaxMB:       h.attachments.MaxSize() >> 20,
+	}, nil
+}
+
+// ListAttachments handles GET /expenses/{id}/attachments
+func (h *Handler) ListAttachments(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	data, err := h.getAttachmentsData(id)
+	if err != nil {
+		http.Error(w, "Expense not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := h.templates.ExecuteTemplate(w, "attachments-modal", data); err != nil {
+		log.Printf("Error executing template: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// UploadAttachment handles POST /expenses/{id}/attachments
+func (h *Handler) UploadAttachment(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var expense models.Expense
+	if err := h.db.First(&expense, id).Error; err != nil {
+		http.Error(w, "Expense not found", http.StatusNotFound)
+		return
+	}
+
+	// Allow some room over the file limit for the rest of the multipart body
+	r.Body = http.MaxBytesReader(w, r.Body, h.attachments.MaxSize()+1<<20)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		message := "Choose a file to attach"
+		if errors.As(err, &maxBytesErr) {
+			message = fmt.Sprintf("Files must be %d MB or smaller", h.attachments.MaxSize()>>20)
+		}
+		h.writeAttachmentError(w, message)
+		return
+	}
+	defer file.Close()
+
+	hash, size, contentType, err := h.attachments.Save(file)
+	if err != nil {
+		switch {
+		case errors.Is(err, attachments.ErrTooLarge):
+			h.writeAttachmentError(w, fmt.Sprintf("Files must be %d MB or smaller", h.attachments.MaxSize()>>20))
+		case errors.Is(err, attachments.ErrUnsupportedType):
+			h.writeAttachmentError(w, "Only images (JPEG, PNG, GIF, WebP) and PDFs can be attached")
+		default:
+			log.Printf("Error saving attachment: %v", err)
+			http.Error(w, "Failed to save attachment", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	fileName := filepath.Base(strings.ReplaceAll(header.Filename, "\\", "/"))
+	if fileName == "." || fileName == "/" {
+		fileName = "attachment"
+	}
+
+	attachment := models.Attachment{
+		ExpenseID:   expense.ID,
+		Hash:        hash,
+		FileName:    fileName,
+		ContentType: contentType,
+		Size:        size,
+	}
+	if err := h.db.Create(&attachment).Error; err != nil {
+		log.Printf("Error creating attachment: %v", err)
+		http.Error(w, "Failed to save attachment", http.StatusInternalServerError)
+		return
+	}
+
+	h.renderAttachmentList(w, id)
+}
+
+// GetAttachment handles GET /attachments/{id}. Files are shown inline unless
+// ?download=1 is given.
+func (h *Handler) GetAttachment(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var attachment models.Attachment
+	if err := h.db.First(&attachment, id).Error; err != nil {
+		http.Error(w, "Attachment not found", http.StatusNotFound)
+		return
+	}
+
+	file, err := h.attachments.Open(attachment.Hash)
+	if err != nil {
+		log.Printf("Error opening attachment %d: %v", attachment.ID, err)
+		http.Error(w, "Attachment file is missing", http.StatusNotFound)
+		return
+	}
+	defer file.Close()
+
+	disposition := "inline"
+	if r.URL.Query().Get("download") != "" {
+		disposition = "attachment"
+	}
+
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": attachment.FileName}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	// Contents never change for a given hash
+	w.Header().Set("ETag", `"`+attachment.Hash+`"`)
+	w.Header().Set("Cache-Control", "private, max-age=31536000, immutable")
+	http.ServeContent(w, r, "", attachment.CreatedAt, file)
+}
+
+// DeleteAttachment handles DELETE /attachments/{id}
+func (h *Handler) DeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var attachment models.Attachment
+	if err := h.db.First(&attachment, id).Error; err != nil {
+		http.Error(w, "Attachment not found", http.StatusNotFound)
+		return
+	}
+
+	if err := h.db.Delete(&attachment).Error; err != nil {
+		log.Printf("Error deleting attachment: %v", err)
+		http.Error(w, "Failed to delete attachment", http.StatusInternalServerError)
+		return
+	}
+	if err := h.attachments.RemoveIfUnreferenced(h.db, attachment.Hash); err != nil {
+		log.Printf("Error removing attachment file %s: %v", attachment.Hash, err)
+	}
+
+	h.renderAttachmentList(w, uint64(attachment.ExpenseID))
+}
+
+// renderAttachmentList renders an expense's attachment list along with the
+// recent transactions, whose attachment counts may have changed
+func (h *Handler) renderAttachmentList(w http.ResponseWriter, expenseID uint64) {
+	data, err := h.getAttachmentsData(expenseID)
+	if err != nil {
+		log.Printf("Error querying attachments: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	refreshData, err := h.getRefreshData()
+	if err != nil {
+		log.Printf("Error getting dashboard data: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := h.templates.ExecuteTemplate(w, "attachment-list", data); err != nil {
+		log.Printf("Error executing template: %v", err)
+		return
+	}
+
+	// Render OOB recent transactions
+	oobBuf := new(bytes.Buffer)
+	if err := h.templates.ExecuteTemplate(oobBuf, "recent-transactions-oob", refreshData); err != nil {
+		log.Printf("Error executing OOB template: %v", err)
+		return
+	}
+	w.Write(oobBuf.Bytes())
+}
+
+// writeAttachmentError shows an upload error above the attachment list
+func (h *Handler) writeAttachmentError(w http.ResponseWriter, message string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("HX-Retarget", "#attachment-errors")
+	w.Header().Set("HX-Reswap", "innerHTML")
+	w.WriteHeader(http.StatusBadRequest)
+	h.templates.ExecuteTemplate(w, "validation-errors", validation.ValidationErrors{
+		{Field: "file", Message: message},
+	})
+}
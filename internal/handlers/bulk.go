@@ -0,0 +1,329 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/g-linville/budgeting/internal/audit"
+	"github.com/g-linville/budgeting/internal/currency"
+	"github.com/g-linville/budgeting/internal/exchange"
+	"github.com/g-linville/budgeting/internal/models"
+	"github.com/g-linville/budgeting/internal/validation"
+	"gorm.io/gorm"
+)
+
+// Bulk actions available on transaction lists
+const (
+	bulkSetCategory = "category"
+	bulkAddTags     = "tags"
+	bulkShiftDate   = "shift"
+	bulkDelete      = "delete"
+)
+
+// bulkSelection is a transaction picked in a list, submitted as "expense:12"
+type bulkSelection struct {
+	Type string
+	ID   uint
+}
+
+// parseBulkSelection reads the selected transactions from the form
+func parseBulkSelection(values []string) []bulkSelection {
+	var selected []bulkSelection
+	for _, value := range values {
+		txType, idStr, ok := strings.Cut(value, ":")
+		if !ok || (txType != audit.EntityExpense && txType != audit.EntityIncome) {
+			continue
+		}
+		id, err := strconv.ParseUint(idStr, 10, 32)
+		if err != nil {
+			continue
+		}
+		selected = append(selected, bulkSelection{Type: txType, ID: uint(id)})
+	}
+	return selected
+}
+
+// BulkUpdateTransactions handles POST /transactions/bulk. The action is
+// applied to every selected transaction in a single database transaction,
+// then the list it came from ("recent" on the dashboard, otherwise the
+// transactions page with its current filters) is re-rendered.
+func (h *Handler) BulkUpdateTransactions(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		return
+	}
+
+	action := r.FormValue("bulk_action")
+	selected := parseBulkSelection(r.Form["selected"])
+
+	var validationErrors validation.ValidationErrors
+	if len(selected) == 0 {
+		validationErrors = append(validationErrors, validation.ValidationError{
+			Field: "selected", Message: "Select at least one transaction",
+		})
+	}
+
+	var categoryID *uint
+	var categoryKind string
+	var tags []string
+	var days int
+	switch action {
+	case bulkSetCategory:
+		if idStr := r.FormValue("set_category_id"); idStr != "" {
+			id, err := strconv.ParseUint(idStr, 10, 32)
+			if err != nil {
+				validationErrors = append(validationErrors, validation.ValidationError{
+					Field: "category", Message: "Invalid category",
+				})
+			} else {
+				// Look the category up before opening the transaction, so a
+				// missing, trashed or archived one is reported rather than
+				// failing it
+				var category models.Category
+				err := h.db.Select("kind", "archived").First(&category, id).Error
+				switch {
+				case errors.Is(err, gorm.ErrRecordNotFound):
+					validationErrors = append(validationErrors, validation.ValidationError{
+						Field: "category", Message: "Category not found",
+					})
+				case err != nil:
+					log.Printf("Error loading category: %v", err)
+					http.Error(w, "Internal server error", http.StatusInternalServerError)
+					return
+				case category.Archived:
+					validationErrors = append(validationErrors, validation.ValidationError{
+						Field: "category", Message: "Category is archived",
+					})
+				default:
+					categoryIDUint := uint(id)
+					categoryID = &categoryIDUint
+					categoryKind = category.Kind
+				}
+			}
+		}
+	case bulkAddTags:
+		var tagErrors validation.ValidationErrors
+		tags, tagErrors = validation.ValidateTags(r.FormValue("add_tags"))
+		validationErrors = append(validationErrors, tagErrors...)
+		if len(tags) == 0 && len(tagErrors) == 0 {
+			validationErrors = append(validationErrors, validation.ValidationError{
+				Field: "tags", Message: "Enter at least one tag",
+			})
+		}
+	case bulkShiftDate:
+		var err error
+		days, err = strconv.Atoi(strings.TrimSpace(r.FormValue("shift_days")))
+		if err != nil || days == 0 {
+			validationErrors = append(validationErrors, validation.ValidationError{
+				Field: "days", Message: "Enter a non-zero number of days (negative moves earlier)",
+			})
+		}
+	case bulkDelete:
+	default:
+		validationErrors = append(validationErrors, validation.ValidationError{
+			Field: "action", Message: "Choose an action",
+		})
+	}
+
+	// A category only applies to transactions of its kind, so a mixed
+	// selection is rejected rather than partly applied
+	if categoryKind != "" {
+		var mismatched int
+		for _, sel := range selected {
+			if (sel.Type == audit.EntityExpense) != (categoryKind == models.CategoryKindExpense) {
+				mismatched++
+			}
+		}
+		if mismatched > 0 {
+			validationErrors = append(validationErrors, validation.ValidationError{
+				Field: "category", Message: bulkKindMismatchMessage(categoryKind, mismatched),
+			})
+		}
+	}
+
+	if validationErrors.HasErrors() {
+		h.writeBulkErrors(w, validationErrors)
+		return
+	}
+
+	if err := h.db.Transaction(func(tx *gorm.DB) error {
+		for _, sel := range selected {
+			if err := h.applyBulkAction(tx, sel, action, categoryID, tags, days); err != nil {
+				return fmt.Errorf("%s %d: %w", sel.Type, sel.ID, err)
+			}
+		}
+		return nil
+	}); err != nil {
+		var belowRefunded *exchange.BelowRefundedError
+		if errors.As(err, &belowRefunded) {
+			h.writeBulkErrors(w, validation.ValidationErrors{{
+				Field:   "days",
+				Message: h.belowRefundedMessage(belowRefunded),
+			}})
+			return
+		}
+		log.Printf("Error applying bulk %s: %v", action, err)
+		http.Error(w, "Failed to update transactions", http.StatusInternalServerError)
+		return
+	}
+
+	h.renderBulkResult(w, r)
+}
+
+// bulkKindMismatchMessage asks to deselect the n transactions that a
+// category of the given kind cannot be set on
+func bulkKindMismatchMessage(kind string, n int) string {
+	if kind == models.CategoryKindExpense {
+		noun := "income transactions"
+		if n == 1 {
+			noun = "income transaction"
+		}
+		return fmt.Sprintf("An expense category only applies to expenses; deselect the %d %s", n, noun)
+	}
+	noun := "expenses"
+	if n == 1 {
+		noun = "expense"
+	}
+	return fmt.Sprintf("An income category only applies to income; deselect the %d %s", n, noun)
+}
+
+// writeBulkErrors renders validation errors above the bulk action bar
+func (h *Handler) writeBulkErrors(w http.ResponseWriter, validationErrors validation.ValidationErrors) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("HX-Retarget", "#bulk-errors")
+	w.Header().Set("HX-Reswap", "innerHTML")
+	w.WriteHeader(http.StatusBadRequest)
+	h.templates.ExecuteTemplate(w, "validation-errors", validationErrors)
+}
+
+// applyBulkAction changes one transaction and records it in the audit log.
+// Transactions that no longer exist (or are in the trash) are skipped.
+func (h *Handler) applyBulkAction(tx *gorm.DB, sel bulkSelection, action string, categoryID *uint, tags []string, days int) error {
+	var record interface{}
+	var dateColumn string
+	if sel.Type == audit.EntityExpense {
+		record, dateColumn = &models.Expense{}, "expense_date"
+	} else {
+		record, dateColumn = &models.Income{}, "income_date"
+	}
+
+	if err := tx.First(record, sel.ID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	before, err := audit.Snapshot(tx, sel.Type, sel.ID)
+	if err != nil {
+		return err
+	}
+
+	auditAction := audit.ActionUpdate
+	switch action {
+	case bulkSetCategory:
+		// The selection was checked to match the category's kind; no
+		// category clears both
+		switch r := record.(type) {
+		case *models.Expense:
+			// Categorizing a split expense as a whole replaces its lines
+			if err := setSplits(tx, r.ID, nil, currency.Money{}); err != nil {
+				return err
+			}
+			if err := tx.Model(r).Update("category_id", categoryID).Error; err != nil {
+				return err
+			}
+		case *models.Income:
+			if err := tx.Model(r).Update("category_id", categoryID).Error; err != nil {
+				return err
+			}
+		}
+	case bulkAddTags:
+		newTags, err := resolveTags(tx, tags)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(record).Association("Tags").Append(newTags); err != nil {
+			return err
+		}
+	case bulkShiftDate:
+		// Amounts entered in another currency are converted again at the
+		// rate for the new date, as when the date is edited
+		switch r := record.(type) {
+		case *models.Expense:
+			r.ExpenseDate = r.ExpenseDate.AddDate(0, 0, days)
+			if err := tx.Model(r).Update(dateColumn, r.ExpenseDate).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("expense_id = ?", r.ID).Order("id").Find(&r.Splits).Error; err != nil {
+				return err
+			}
+			if _, err := exchange.ReconvertExpense(tx, r, h.base); err != nil {
+				return err
+			}
+		case *models.Income:
+			r.IncomeDate = r.IncomeDate.AddDate(0, 0, days)
+			if err := tx.Model(r).Update(dateColumn, r.IncomeDate).Error; err != nil {
+				return err
+			}
+			if _, err := exchange.ReconvertIncome(tx, r, h.base); err != nil {
+				return err
+			}
+		}
+	case bulkDelete:
+		auditAction = audit.ActionDelete
+		if err := tx.Delete(record).Error; err != nil {
+			return err
+		}
+	}
+
+	return audit.Record(tx, sel.Type, sel.ID, auditAction, before)
+}
+
+// renderBulkResult re-renders the list a bulk action was applied from, plus
+// the overview stats (OOB)
+func (h *Handler) renderBulkResult(w http.ResponseWriter, r *http.Request) {
+	refreshData, err := h.getRefreshData()
+	if err != nil {
+		log.Printf("Error getting dashboard data: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	if r.FormValue("list") == "recent" {
+		if err := h.templates.ExecuteTemplate(w, "recent-transactions", refreshData); err != nil {
+			log.Printf("Error executing template: %v", err)
+			return
+		}
+	} else {
+		// The filter form is submitted along with the action, so the list
+		// keeps its filters and sort order
+		filter := parseTransactionFilter(r.Form, h.base, h.locale)
+		filter.After = ""
+		data, err := h.getTransactionsPageData(filter)
+		if err != nil {
+			log.Printf("Error querying transactions: %v", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if err := h.templates.ExecuteTemplate(w, "transactions-results", data); err != nil {
+			log.Printf("Error executing template: %v", err)
+			return
+		}
+	}
+
+	// Render OOB overview stats
+	oobBuf := new(bytes.Buffer)
+	if err := h.templates.ExecuteTemplate(oobBuf, "overview-stats-oob", refreshData); err != nil {
+		log.Printf("Error executing OOB template: %v", err)
+		return
+	}
+	w.Write(oobBuf.Bytes())
+}
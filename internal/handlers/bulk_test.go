@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/g-linville/budgeting/internal/database/dbtest"
+	"github.com/g-linville/budgeting/internal/models"
+	"gorm.io/gorm"
+)
+
+// Setting a category on a selection that includes transactions of the other
+// kind must be rejected, not applied to part of it
+func TestBulkSetCategoryRejectsMixedKinds(t *testing.T) {
+	dbtest.RunMigrated(t, func(t *testing.T, db *gorm.DB) {
+		fixture := seedTransactions(t, db)
+		h := testHandler(t, db)
+		h.templates = template.Must(template.New("").Parse(`{{ define "validation-errors" }}{{ range . }}{{ .Message }}{{ end }}{{ end }}`))
+
+		var lunch models.Expense
+		var paycheck, refund models.Income
+		if err := db.Where("name = ?", "Lunch").First(&lunch).Error; err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Where("name = ?", "Paycheck").First(&paycheck).Error; err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Where("name = ?", "Refund check").First(&refund).Error; err != nil {
+			t.Fatal(err)
+		}
+
+		form := url.Values{
+			"bulk_action":     {bulkSetCategory},
+			"set_category_id": {fmt.Sprint(fixture.travel)},
+			"selected": {
+				"expense:" + fmt.Sprint(lunch.ID),
+				"income:" + fmt.Sprint(paycheck.ID),
+				"income:" + fmt.Sprint(refund.ID),
+			},
+		}
+		r := httptest.NewRequest(http.MethodPost, "/transactions/bulk", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		h.BulkUpdateTransactions(w, r)
+
+		want := "An expense category only applies to expenses; deselect the 2 income transactions"
+		if w.Code != http.StatusBadRequest || w.Body.String() != want {
+			t.Errorf("response = %d %q, want 400 %q", w.Code, w.Body.String(), want)
+		}
+		if err := db.First(&lunch, lunch.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		if lunch.CategoryID == nil || *lunch.CategoryID != fixture.dining {
+			t.Errorf("lunch category = %v, want it left at %d", lunch.CategoryID, fixture.dining)
+		}
+	})
+}
+
+func TestBulkKindMismatchMessage(t *testing.T) {
+	tests := []struct {
+		kind string
+		n    int
+		want string
+	}{
+		{models.CategoryKindExpense, 1, "An expense category only applies to expenses; deselect the 1 income transaction"},
+		{models.CategoryKindIncome, 1, "An income category only applies to income; deselect the 1 expense"},
+		{models.CategoryKindIncome, 3, "An income category only applies to income; deselect the 3 expenses"},
+	}
+	for _, tt := range tests {
+		if got := bulkKindMismatchMessage(tt.kind, tt.n); got != tt.want {
+			t.Errorf("bulkKindMismatchMessage(%q, %d) = %q, want %q", tt.kind, tt.n, got, tt.want)
+		}
+	}
+}
@@ -1,30 +1,230 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"slices"
+	"sort"
 	"strconv"
+	"strings"
 
+	"github.com/g-linville/budgeting/internal/audit"
 	"github.com/g-linville/budgeting/internal/models"
 	"github.com/g-linville/budgeting/internal/validation"
 	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
 )
 
-// ListCategories handles GET /categories
-func (h *Handler) ListCategories(w http.ResponseWriter, r *http.Request) {
+// CategoryNode is a category in its place in the category tree
+type CategoryNode struct {
+	models.Category
+	Depth     int
+	Path      string // Names from the top level down, e.g. "Transportation > Fuel"
+	ancestors []uint
+}
+
+// Under reports whether the node is the category with the given ID or one of
+// its descendants
+func (n CategoryNode) Under(id uint) bool {
+	if n.ID == id {
+		return true
+	}
+	for _, ancestor := range n.ancestors {
+		if ancestor == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Indent is the left margin for displaying the node, in pixels
+func (n CategoryNode) Indent() int {
+	return n.Depth * 24
+}
+
+// categoryTree orders categories depth-first with each level sorted by name.
+// A category whose parent is missing (e.g. in the trash) is shown at the top
+// level.
+func categoryTree(categories []models.Category) []CategoryNode {
+	sorted := make([]models.Category, len(categories))
+	copy(sorted, categories)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return strings.ToLower(sorted[i].Name) < strings.ToLower(sorted[j].Name)
+	})
+
+	present := make(map[uint]bool, len(sorted))
+	for _, c := range sorted {
+		present[c.ID] = true
+	}
+	children := make(map[uint][]models.Category)
+	var roots []models.Category
+	for _, c := range sorted {
+		if c.ParentID != nil && present[*c.ParentID] && *c.ParentID != c.ID {
+			children[*c.ParentID] = append(children[*c.ParentID], c)
+		} else {
+			roots = append(roots, c)
+		}
+	}
+
+	var tree []CategoryNode
+	visited := make(map[uint]bool, len(sorted))
+	var walk func(c models.Category, ancestors []uint, path string)
+	walk = func(c models.Category, ancestors []uint, path string) {
+		if visited[c.ID] {
+			return
+		}
+		visited[c.ID] = true
+		if path != "" {
+			path += " > "
+		}
+		path += c.Name
+		tree = append(tree, CategoryNode{Category: c, Depth: len(ancestors), Path: path, ancestors: ancestors})
+		next := append(append([]uint{}, ancestors...), c.ID)
+		for _, child := range children[c.ID] {
+			walk(child, next, path)
+		}
+	}
+	for _, c := range roots {
+		walk(c, nil, "")
+	}
+	// Anything left over is part of a cycle; show it rather than lose it
+	for _, c := range sorted {
+		walk(c, nil, "")
+	}
+	return tree
+}
+
+// CategoryListData holds the data for the category list
+type CategoryListData struct {
+	Categories []models.Category
+	Tree       []CategoryNode
+}
+
+// Active returns the categories of a kind that are offered when entering
+// transactions
+func (d CategoryListData) Active(kind string) []CategoryNode {
+	var nodes []CategoryNode
+	for _, node := range d.Tree {
+		if !node.Archived && node.Kind == kind {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+// Archived returns the archived categories
+func (d CategoryListData) Archived() []CategoryNode {
+	var nodes []CategoryNode
+	for _, node := range d.Tree {
+		if node.Archived {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+// getCategoryListData loads the categories, archived ones included, and
+// arranges them as a tree
+func (h *Handler) getCategoryListData() (CategoryListData, error) {
 	var categories []models.Category
 	if err := h.db.Find(&categories).Error; err != nil {
+		return CategoryListData{}, err
+	}
+	return CategoryListData{Categories: categories, Tree: categoryTree(categories)}, nil
+}
+
+// getCategories loads the categories of a kind, archived ones included, for
+// the category choices on transaction forms
+func (h *Handler) getCategories(kind string) ([]models.Category, error) {
+	var categories []models.Category
+	err := h.db.Where("kind = ?", kind).Order("name").Find(&categories).Error
+	return categories, err
+}
+
+// parseCategoryParent reads the optional parent_id form value for the
+// category with the given ID (0 when creating one) and kind, and validates it
+func (h *Handler) parseCategoryParent(categoryID uint, kind, value string) (*uint, validation.ValidationErrors, error) {
+	if value == "" {
+		return nil, nil, nil
+	}
+	id, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return nil, validation.ValidationErrors{{Field: "parent_id", Message: "Invalid parent category"}}, nil
+	}
+
+	var categories []models.Category
+	if err := h.db.Select("id", "parent_id", "kind").Find(&categories).Error; err != nil {
+		return nil, nil, err
+	}
+	parentOf := make(map[uint]*uint, len(categories))
+	kindOf := make(map[uint]string, len(categories))
+	for _, c := range categories {
+		parentOf[c.ID] = c.ParentID
+		kindOf[c.ID] = c.Kind
+	}
+
+	parentID := uint(id)
+	if errs := validation.ValidateCategoryParent(categoryID, parentID, parentOf); errs.HasErrors() {
+		return nil, errs, nil
+	}
+	if kindOf[parentID] != kind {
+		return nil, validation.ValidationErrors{{Field: "parent_id", Message: "A category must have a parent of the same kind (expense or income)"}}, nil
+	}
+	return &parentID, nil, nil
+}
+
+// checkCategory validates the optional category of a transaction, template
+// or rule of the given kind: it must exist, be out of the trash and be of
+// that kind, and not be archived unless it is one of the categories in kept,
+// which an edited item already has. label begins the error message, e.g.
+// "Category".
+func (h *Handler) checkCategory(id *uint, kind string, kept []uint, field, label string) (validation.ValidationErrors, error) {
+	if id == nil {
+		return nil, nil
+	}
+	var category models.Category
+	err := h.db.Select("kind", "archived").First(&category, *id).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return validation.ValidationErrors{{Field: field, Message: label + " not found"}}, nil
+	case err != nil:
+		return nil, err
+	case category.Kind != kind:
+		return validation.ValidationErrors{{Field: field, Message: label + " must be an " + kind + " category"}}, nil
+	case category.Archived && !slices.Contains(kept, *id):
+		return validation.ValidationErrors{{Field: field, Message: label + " is archived"}}, nil
+	}
+	return nil, nil
+}
+
+// checkExpenseCategories validates the category of an expense and those of
+// its split lines. kept lists the categories the expense already has.
+func (h *Handler) checkExpenseCategories(categoryID *uint, splits []validation.Split, kept []uint) (validation.ValidationErrors, error) {
+	errs, err := h.checkCategory(categoryID, models.CategoryKindExpense, kept, "category_id", "Category")
+	if err != nil {
+		return nil, err
+	}
+	for i, split := range splits {
+		splitErrs, err := h.checkCategory(split.CategoryID, models.CategoryKindExpense, kept, "split_category_id", fmt.Sprintf("Split line %d category", i+1))
+		if err != nil {
+			return nil, err
+		}
+		errs = append(errs, splitErrs...)
+	}
+	return errs, nil
+}
+
+// ListCategories handles GET /categories
+func (h *Handler) ListCategories(w http.ResponseWriter, r *http.Request) {
+	data, err := h.getCategoryListData()
+	if err != nil {
 		log.Printf("Error querying categories: %v", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	data := struct {
-		Categories []models.Category
-	}{
-		Categories: categories,
-	}
-
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	if err := h.templates.ExecuteTemplate(w, "category-modal", data); err != nil {
 		log.Printf("Error executing template: %v", err)
@@ -41,42 +241,59 @@ func (h *Handler) CreateCategory(w http.ResponseWriter, r *http.Request) {
 
 	name := r.FormValue("name")
 	color := r.FormValue("color")
+	kind := r.FormValue("kind")
+	if kind == "" {
+		kind = models.CategoryKindExpense
+	}
 
 	// Validate input
 	validationErrors := validation.ValidateCategory(name, color)
+	validationErrors = append(validationErrors, validation.ValidateCategoryKind(kind)...)
+	parentID, parentErrors, err := h.parseCategoryParent(0, kind, r.FormValue("parent_id"))
+	if err != nil {
+		log.Printf("Error querying categories: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	validationErrors = append(validationErrors, parentErrors...)
 	if validationErrors.HasErrors() {
 		log.Printf("Validation errors: %v", validationErrors)
 		http.Error(w, validationErrors.Error(), http.StatusBadRequest)
 		return
 	}
 
-	// Check if category name already exists
+	// Check if category name already exists for this kind
 	var existingCategory models.Category
-	if err := h.db.Where("LOWER(name) = LOWER(?)", name).First(&existingCategory).Error; err == nil {
+	if err := h.db.Where("kind = ? AND LOWER(name) = LOWER(?)", kind, name).First(&existingCategory).Error; err == nil {
 		http.Error(w, "Category with this name already exists", http.StatusBadRequest)
 		return
 	}
 
 	// Create category
 	category := models.Category{
-		Name:  name,
-		Color: color,
+		Name:     name,
+		Color:    color,
+		Kind:     kind,
+		ParentID: parentID,
 	}
 
-	if err := h.db.Create(&category).Error; err != nil {
+	if err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&category).Error; err != nil {
+			return err
+		}
+		return audit.Record(tx, audit.EntityCategory, category.ID, audit.ActionCreate, "")
+	}); err != nil {
 		log.Printf("Error creating category: %v", err)
 		http.Error(w, "Failed to create category", http.StatusInternalServerError)
 		return
 	}
 
 	// Return updated category list
-	var categories []models.Category
-	h.db.Find(&categories)
-
-	data := struct {
-		Categories []models.Category
-	}{
-		Categories: categories,
+	data, err := h.getCategoryListData()
+	if err != nil {
+		log.Printf("Error querying categories: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
 	}
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
@@ -101,10 +318,19 @@ func (h *Handler) GetCategoryEditForm(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	listData, err := h.getCategoryListData()
+	if err != nil {
+		log.Printf("Error querying categories: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
 	data := struct {
 		Category models.Category
+		Tree     []CategoryNode
 	}{
 		Category: category,
+		Tree:     listData.Tree,
 	}
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
@@ -131,45 +357,113 @@ func (h *Handler) UpdateCategory(w http.ResponseWriter, r *http.Request) {
 	name := r.FormValue("name")
 	color := r.FormValue("color")
 
+	var category models.Category
+	if err := h.db.First(&category, id).Error; err != nil {
+		http.Error(w, "Category not found", http.StatusNotFound)
+		return
+	}
+
 	// Validate input
 	validationErrors := validation.ValidateCategory(name, color)
+	parentID, parentErrors, err := h.parseCategoryParent(category.ID, category.Kind, r.FormValue("parent_id"))
+	if err != nil {
+		log.Printf("Error querying categories: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	validationErrors = append(validationErrors, parentErrors...)
 	if validationErrors.HasErrors() {
 		log.Printf("Validation errors: %v", validationErrors)
 		http.Error(w, validationErrors.Error(), http.StatusBadRequest)
 		return
 	}
 
-	// Check if category name already exists (excluding current category)
+	// Check if category name already exists for this kind (excluding current category)
 	var existingCategory models.Category
-	if err := h.db.Where("LOWER(name) = LOWER(?) AND id != ?", name, id).First(&existingCategory).Error; err == nil {
+	if err := h.db.Where("kind = ? AND LOWER(name) = LOWER(?) AND id != ?", category.Kind, name, id).First(&existingCategory).Error; err == nil {
 		http.Error(w, "Category with this name already exists", http.StatusBadRequest)
 		return
 	}
 
 	// Update category
+	category.Name = name
+	category.Color = color
+	category.ParentID = parentID
+
+	if err := h.db.Transaction(func(tx *gorm.DB) error {
+		before, err := audit.Snapshot(tx, audit.EntityCategory, category.ID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Save(&category).Error; err != nil {
+			return err
+		}
+		return audit.Record(tx, audit.EntityCategory, category.ID, audit.ActionUpdate, before)
+	}); err != nil {
+		log.Printf("Error updating category: %v", err)
+		http.Error(w, "Failed to update category", http.StatusInternalServerError)
+		return
+	}
+
+	// Return updated category list
+	data, err := h.getCategoryListData()
+	if err != nil {
+		log.Printf("Error querying categories: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := h.templates.ExecuteTemplate(w, "category-list", data); err != nil {
+		log.Printf("Error executing template: %v", err)
+	}
+}
+
+// ArchiveCategory handles POST /categories/{id}/archive
+func (h *Handler) ArchiveCategory(w http.ResponseWriter, r *http.Request) {
+	h.setCategoryArchived(w, r, true)
+}
+
+// UnarchiveCategory handles POST /categories/{id}/unarchive
+func (h *Handler) UnarchiveCategory(w http.ResponseWriter, r *http.Request) {
+	h.setCategoryArchived(w, r, false)
+}
+
+// setCategoryArchived archives or unarchives a category and renders the list
+func (h *Handler) setCategoryArchived(w http.ResponseWriter, r *http.Request, archived bool) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
 	var category models.Category
 	if err := h.db.First(&category, id).Error; err != nil {
 		http.Error(w, "Category not found", http.StatusNotFound)
 		return
 	}
 
-	category.Name = name
-	category.Color = color
-
-	if err := h.db.Save(&category).Error; err != nil {
+	if err := h.db.Transaction(func(tx *gorm.DB) error {
+		before, err := audit.Snapshot(tx, audit.EntityCategory, category.ID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&category).Update("archived", archived).Error; err != nil {
+			return err
+		}
+		return audit.Record(tx, audit.EntityCategory, category.ID, audit.ActionUpdate, before)
+	}); err != nil {
 		log.Printf("Error updating category: %v", err)
 		http.Error(w, "Failed to update category", http.StatusInternalServerError)
 		return
 	}
 
-	// Return updated category list
-	var categories []models.Category
-	h.db.Find(&categories)
-
-	data := struct {
-		Categories []models.Category
-	}{
-		Categories: categories,
+	data, err := h.getCategoryListData()
+	if err != nil {
+		log.Printf("Error querying categories: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
 	}
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
@@ -178,7 +472,8 @@ func (h *Handler) UpdateCategory(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// DeleteCategory handles DELETE /categories/{id}
+// DeleteCategory handles DELETE /categories/{id}. With ?reassign_to=ID the
+// category's transactions move to that category first (a merge).
 func (h *Handler) DeleteCategory(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
@@ -187,21 +482,39 @@ func (h *Handler) DeleteCategory(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Delete category (expenses will have category_id set to NULL via ON DELETE SET NULL)
-	if err := h.db.Delete(&models.Category{}, id).Error; err != nil {
+	if reassignTo := r.FormValue("reassign_to"); reassignTo != "" {
+		intoID, err := strconv.ParseUint(reassignTo, 10, 32)
+		if err != nil {
+			h.writeCategoryDeleteErrors(w, uint(id), validation.ValidationErrors{{Field: "reassign_to", Message: "Invalid category"}})
+			return
+		}
+		h.mergeAndRender(w, uint(id), uint(intoID))
+		return
+	}
+
+	// Move category to the trash (expenses keep their category_id and show as
+	// uncategorized until it is restored; purging sets it to NULL)
+	if err := h.db.Transaction(func(tx *gorm.DB) error {
+		before, err := audit.Snapshot(tx, audit.EntityCategory, uint(id))
+		if err != nil || before == "" {
+			return err
+		}
+		if err := tx.Delete(&models.Category{}, id).Error; err != nil {
+			return err
+		}
+		return audit.Record(tx, audit.EntityCategory, uint(id), audit.ActionDelete, before)
+	}); err != nil {
 		log.Printf("Error deleting category: %v", err)
 		http.Error(w, "Failed to delete category", http.StatusInternalServerError)
 		return
 	}
 
 	// Return updated category list
-	var categories []models.Category
-	h.db.Find(&categories)
-
-	data := struct {
-		Categories []models.Category
-	}{
-		Categories: categories,
+	data, err := h.getCategoryListData()
+	if err != nil {
+		log.Printf("Error querying categories: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
 	}
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
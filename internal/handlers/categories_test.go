@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"context"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/g-linville/budgeting/internal/database/dbtest"
+	"github.com/g-linville/budgeting/internal/models"
+	"github.com/g-linville/budgeting/internal/validation"
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+)
+
+// errorMessages joins the messages of errs, without their fields
+func errorMessages(errs validation.ValidationErrors) string {
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+func TestCheckCategory(t *testing.T) {
+	dbtest.RunMigrated(t, func(t *testing.T, db *gorm.DB) {
+		dining := models.Category{Name: "Dining", Kind: models.CategoryKindExpense}
+		trashed := models.Category{Name: "Old dining", Kind: models.CategoryKindExpense}
+		archived := models.Category{Name: "Takeout", Kind: models.CategoryKindExpense, Archived: true}
+		salary := models.Category{Name: "Salary", Kind: models.CategoryKindIncome}
+		for _, c := range []*models.Category{&dining, &trashed, &archived, &salary} {
+			if err := db.Create(c).Error; err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := db.Delete(&trashed).Error; err != nil {
+			t.Fatal(err)
+		}
+		missing := salary.ID + 100
+		h := testHandler(t, db)
+
+		tests := []struct {
+			name string
+			id   *uint
+			kind string
+			kept []uint
+			want string
+		}{
+			{"none", nil, models.CategoryKindExpense, nil, ""},
+			{"expense", &dining.ID, models.CategoryKindExpense, nil, ""},
+			{"income", &salary.ID, models.CategoryKindIncome, nil, ""},
+			{"missing", &missing, models.CategoryKindExpense, nil, "Category not found"},
+			{"trashed", &trashed.ID, models.CategoryKindExpense, nil, "Category not found"},
+			{"income for expense", &salary.ID, models.CategoryKindExpense, nil, "Category must be an expense category"},
+			{"expense for income", &dining.ID, models.CategoryKindIncome, nil, "Category must be an income category"},
+			{"archived", &archived.ID, models.CategoryKindExpense, nil, "Category is archived"},
+			{"archived, kept on edit", &archived.ID, models.CategoryKindExpense, []uint{dining.ID, archived.ID}, ""},
+			{"archived, other kept", &archived.ID, models.CategoryKindExpense, []uint{dining.ID}, "Category is archived"},
+		}
+		for _, tt := range tests {
+			errs, err := h.checkCategory(tt.id, tt.kind, tt.kept, "category_id", "Category")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := errorMessages(errs); got != tt.want {
+				t.Errorf("%s: errors = %q, want %q", tt.name, got, tt.want)
+			}
+		}
+
+		// Split lines are reported by line
+		splits := []validation.Split{{CategoryID: &archived.ID, Amount: 500}, {CategoryID: &salary.ID, Amount: 500}}
+		errs, err := h.checkExpenseCategories(nil, splits, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := "Split line 1 category is archived; Split line 2 category must be an expense category"; errorMessages(errs) != want {
+			t.Errorf("split errors = %q, want %q", errorMessages(errs), want)
+		}
+	})
+}
+
+// An edit may keep a category archived since it was chosen, but not move a
+// transaction into another archived one
+func TestUpdateIncomeArchivedCategory(t *testing.T) {
+	dbtest.RunMigrated(t, func(t *testing.T, db *gorm.DB) {
+		salary := models.Category{Name: "Salary", Kind: models.CategoryKindIncome}
+		bonus := models.Category{Name: "Bonus", Kind: models.CategoryKindIncome}
+		for _, c := range []*models.Category{&salary, &bonus} {
+			if err := db.Create(c).Error; err != nil {
+				t.Fatal(err)
+			}
+		}
+		income := models.Income{Name: "Paycheck", Amount: 250000, IncomeDate: time.Now(), CategoryID: &salary.ID}
+		if err := db.Create(&income).Error; err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Model(&models.Category{}).Where("id IN ?", []uint{salary.ID, bonus.ID}).Update("archived", true).Error; err != nil {
+			t.Fatal(err)
+		}
+
+		h := testHandler(t, db)
+		h.templates = template.Must(template.New("").Parse(`{{ define "recent-transactions" }}{{ end }}{{ define "overview-stats-oob" }}{{ end }}`))
+		update := func(categoryID uint) *httptest.ResponseRecorder {
+			form := url.Values{
+				"name":        {"Paycheck"},
+				"amount":      {"2,600.00"},
+				"income_date": {time.Now().Format("2006-01-02")},
+				"category_id": {strconv.FormatUint(uint64(categoryID), 10)},
+			}
+			r := httptest.NewRequest(http.MethodPut, "/income/"+strconv.FormatUint(uint64(income.ID), 10), strings.NewReader(form.Encode()))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			routeCtx := chi.NewRouteContext()
+			routeCtx.URLParams.Add("id", strconv.FormatUint(uint64(income.ID), 10))
+			r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, routeCtx))
+			w := httptest.NewRecorder()
+			h.UpdateIncome(w, r)
+			return w
+		}
+
+		if w := update(bonus.ID); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "Category is archived") {
+			t.Errorf("moving to another archived category: %d %q, want 400 and the archived error", w.Code, w.Body.String())
+		}
+		if w := update(salary.ID); w.Code != http.StatusOK {
+			t.Errorf("keeping the archived category: %d %q, want 200", w.Code, w.Body.String())
+		}
+
+		var saved models.Income
+		if err := db.First(&saved, income.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		if saved.Amount != 260000 || saved.CategoryID == nil || *saved.CategoryID != salary.ID {
+			t.Errorf("saved income = %d in %v, want 260000 in %d", saved.Amount, saved.CategoryID, salary.ID)
+		}
+	})
+}
@@ -0,0 +1,289 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/g-linville/budgeting/internal/audit"
+	"github.com/g-linville/budgeting/internal/models"
+	"github.com/g-linville/budgeting/internal/validation"
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+)
+
+// errSameCategory is returned when merging a category into itself
+var errSameCategory = errors.New("cannot merge a category into itself")
+
+// errCategoryKind is returned when merging an expense category into an
+// income category or the other way around
+var errCategoryKind = errors.New("cannot merge categories of different kinds")
+
+// CategoryUsage counts what refers to a category
+type CategoryUsage struct {
+	Expenses      int64
+	Incomes       int64
+	SplitLines    int64
+	Recurring     int64
+	Subcategories int64
+	Templates     int64
+	Rules         int64
+}
+
+// Total is the number of records that refer to the category
+func (u CategoryUsage) Total() int64 {
+	return u.Expenses + u.Incomes + u.SplitLines + u.Recurring + u.Subcategories + u.Templates + u.Rules
+}
+
+// CategoryDeleteData holds the data for the delete dialog
+type CategoryDeleteData struct {
+	Category models.Category
+	Usage    CategoryUsage
+	Tree     []CategoryNode // Categories the records can move to
+}
+
+// getCategoryUsage counts the records that refer to a category, leaving
+// out those in the trash
+func getCategoryUsage(db *gorm.DB, id uint) (CategoryUsage, error) {
+	var u CategoryUsage
+	counts := []struct {
+		query *gorm.DB
+		dest  *int64
+	}{
+		{db.Model(&models.Expense{}).Where("category_id = ?", id), &u.Expenses},
+		{db.Model(&models.Income{}).Where("category_id = ?", id), &u.Incomes},
+		{db.Model(&models.ExpenseSplit{}).Joins("JOIN expenses e ON e.id = expense_splits.expense_id").
+			Where("e.deleted_at IS NULL AND expense_splits.category_id = ?", id), &u.SplitLines},
+		{db.Model(&models.RecurringExpense{}).Where("category_id = ?", id), &u.Recurring},
+		{db.Model(&models.Category{}).Where("parent_id = ?", id), &u.Subcategories},
+		{db.Model(&models.ExpenseTemplate{}).Where("category_id = ?", id), &u.Templates},
+		{db.Model(&models.CategoryRule{}).Where("category_id = ?", id), &u.Rules},
+	}
+	for _, c := range counts {
+		if err := c.query.Count(c.dest).Error; err != nil {
+			return CategoryUsage{}, err
+		}
+	}
+	return u, nil
+}
+
+// mergeCategory moves everything that refers to one category over to
+// another of the same kind and moves the emptied category to the trash.
+// Expenses and income (including trashed ones) and recurring expenses are
+// recorded in the audit log.
+// Subcategories move under the target, or up a level if the target is one
+// of them, so no cycle is created.
+func mergeCategory(tx *gorm.DB, fromID, intoID uint) error {
+	if fromID == intoID {
+		return errSameCategory
+	}
+
+	var from, into models.Category
+	if err := tx.First(&from, fromID).Error; err != nil {
+		return err
+	}
+	if err := tx.First(&into, intoID).Error; err != nil {
+		return err
+	}
+	if from.Kind != into.Kind {
+		return errCategoryKind
+	}
+
+	// Expenses, whether categorized directly or through a split line
+	var expenseIDs []uint
+	if err := tx.Raw(`
+		SELECT id FROM expenses WHERE category_id = ?
+		UNION
+		SELECT expense_id FROM expense_splits WHERE category_id = ?`,
+		from.ID, from.ID).Scan(&expenseIDs).Error; err != nil {
+		return err
+	}
+	for _, id := range expenseIDs {
+		before, err := audit.Snapshot(tx, audit.EntityExpense, id)
+		if err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&models.Expense{}).Where("id = ? AND category_id = ?", id, from.ID).
+			Update("category_id", into.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.ExpenseSplit{}).Where("expense_id = ? AND category_id = ?", id, from.ID).
+			Update("category_id", into.ID).Error; err != nil {
+			return err
+		}
+		if err := audit.Record(tx, audit.EntityExpense, id, audit.ActionUpdate, before); err != nil {
+			return err
+		}
+	}
+
+	var incomeIDs []uint
+	if err := tx.Unscoped().Model(&models.Income{}).Where("category_id = ?", from.ID).Pluck("id", &incomeIDs).Error; err != nil {
+		return err
+	}
+	for _, id := range incomeIDs {
+		before, err := audit.Snapshot(tx, audit.EntityIncome, id)
+		if err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&models.Income{}).Where("id = ?", id).Update("category_id", into.ID).Error; err != nil {
+			return err
+		}
+		if err := audit.Record(tx, audit.EntityIncome, id, audit.ActionUpdate, before); err != nil {
+			return err
+		}
+	}
+
+	var recurring []models.RecurringExpense
+	if err := tx.Where("category_id = ?", from.ID).Find(&recurring).Error; err != nil {
+		return err
+	}
+	for _, re := range recurring {
+		before, err := audit.Snapshot(tx, audit.EntityRecurringExpense, re.ID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&re).Update("category_id", into.ID).Error; err != nil {
+			return err
+		}
+		if err := audit.Record(tx, audit.EntityRecurringExpense, re.ID, audit.ActionUpdate, before); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Model(&models.ExpenseTemplate{}).Where("category_id = ?", from.ID).Update("category_id", into.ID).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&models.CategoryRule{}).Where("category_id = ?", from.ID).Update("category_id", into.ID).Error; err != nil {
+		return err
+	}
+
+	// Subcategories
+	var categories []models.Category
+	if err := tx.Select("id", "parent_id").Find(&categories).Error; err != nil {
+		return err
+	}
+	newParent := &into.ID
+	for _, node := range categoryTree(categories) {
+		if node.ID == into.ID && node.Under(from.ID) {
+			newParent = from.ParentID
+		}
+	}
+	if into.ParentID != nil && *into.ParentID == from.ID {
+		if err := tx.Model(&into).Update("parent_id", from.ParentID).Error; err != nil {
+			return err
+		}
+	}
+	if err := tx.Model(&models.Category{}).Where("parent_id = ? AND id != ?", from.ID, into.ID).
+		Update("parent_id", newParent).Error; err != nil {
+		return err
+	}
+
+	before, err := audit.Snapshot(tx, audit.EntityCategory, from.ID)
+	if err != nil {
+		return err
+	}
+	if err := tx.Delete(&from).Error; err != nil {
+		return err
+	}
+	return audit.Record(tx, audit.EntityCategory, from.ID, audit.ActionDelete, before)
+}
+
+// GetCategoryDeleteForm handles GET /categories/{id}/delete, asking where
+// the category's transactions should go before it is removed
+func (h *Handler) GetCategoryDeleteForm(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var category models.Category
+	if err := h.db.First(&category, id).Error; err != nil {
+		http.Error(w, "Category not found", http.StatusNotFound)
+		return
+	}
+
+	usage, err := getCategoryUsage(h.db, category.ID)
+	if err != nil {
+		log.Printf("Error counting category usage: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	listData, err := h.getCategoryListData()
+	if err != nil {
+		log.Printf("Error querying categories: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	data := CategoryDeleteData{Category: category, Usage: usage, Tree: listData.Tree}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := h.templates.ExecuteTemplate(w, "category-delete", data); err != nil {
+		log.Printf("Error executing template: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// MergeCategory handles POST /categories/{id}/merge, moving everything in
+// the category to the one given by the "into" form value
+func (h *Handler) MergeCategory(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	intoID, err := strconv.ParseUint(r.FormValue("into"), 10, 32)
+	if err != nil {
+		h.writeCategoryDeleteErrors(w, uint(id), validation.ValidationErrors{{Field: "into", Message: "Choose a category to merge into"}})
+		return
+	}
+
+	h.mergeAndRender(w, uint(id), uint(intoID))
+}
+
+// mergeAndRender merges one category into another and renders the
+// category list, or the errors in the delete dialog
+func (h *Handler) mergeAndRender(w http.ResponseWriter, fromID, intoID uint) {
+	if err := h.db.Transaction(func(tx *gorm.DB) error {
+		return mergeCategory(tx, fromID, intoID)
+	}); err != nil {
+		switch {
+		case errors.Is(err, errSameCategory):
+			h.writeCategoryDeleteErrors(w, fromID, validation.ValidationErrors{{Field: "into", Message: "Choose a different category to merge into"}})
+		case errors.Is(err, errCategoryKind):
+			h.writeCategoryDeleteErrors(w, fromID, validation.ValidationErrors{{Field: "into", Message: "Expense and income categories cannot be merged"}})
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			http.Error(w, "Category not found", http.StatusNotFound)
+		default:
+			log.Printf("Error merging categories: %v", err)
+			http.Error(w, "Failed to merge categories", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	data, err := h.getCategoryListData()
+	if err != nil {
+		log.Printf("Error querying categories: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := h.templates.ExecuteTemplate(w, "category-list", data); err != nil {
+		log.Printf("Error executing template: %v", err)
+	}
+}
+
+// writeCategoryDeleteErrors renders validation errors into the delete dialog
+func (h *Handler) writeCategoryDeleteErrors(w http.ResponseWriter, id uint, errs validation.ValidationErrors) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("HX-Retarget", "#category-delete-errors-"+strconv.FormatUint(uint64(id), 10))
+	w.Header().Set("HX-Reswap", "innerHTML")
+	w.WriteHeader(http.StatusBadRequest)
+	h.templates.ExecuteTemplate(w, "validation-errors", errs)
+}
@@ -3,35 +3,46 @@ package handlers
 import (
 	"log"
 	"net/http"
-	"sort"
 	"time"
 
+	"github.com/g-linville/budgeting/internal/currency"
 	"github.com/g-linville/budgeting/internal/models"
-	"github.com/g-linville/budgeting/internal/utils"
 )
 
 // DashboardData holds all data needed for the dashboard template
 type DashboardData struct {
-	Categories         []models.Category
+	Categories         []models.Category // Expense categories
+	IncomeCategories   []models.Category
 	RecentTransactions []Transaction
 	Overview           OverviewStats
 	CurrentMonth       int
 	CurrentYear        int
 	CurrentDay         int
+
+	// Quick entry for the expense form
+	Templates []models.ExpenseTemplate
+	Frequent  []FrequentExpense
+	Prefill   ExpensePrefill
 }
 
 // Transaction represents a combined view of expenses and income
 type Transaction struct {
-	ID         uint
-	Type       string  // "expense" or "income"
-	Name       string
-	Amount     string  // Pre-formatted "$12.34"
-	AmountRaw  int     // Raw cents value for calculations
-	Date       string  // "2026-01-14"
-	DateParsed time.Time
-	Category   *string // Category name (nil for income)
-	CategoryID *uint
-	Notes      string
+	ID           uint
+	Type         string // "expense" or "income"
+	Name         string
+	Amount       string // Pre-formatted in the base currency, e.g. "$12.34"
+	AmountRaw    int    // Raw minor units for calculations
+	Original     string // Amount as entered in another currency, e.g. "€11.50"; "" for the base currency
+	Date         string // "2026-01-14"
+	DateParsed   time.Time
+	Category     *string // Category name (nil for income)
+	CategoryID   *uint
+	Notes        string
+	Tags         []string
+	SplitCount   int  // Number of split lines (0 if not split)
+	Attachments  int  // Number of attached files
+	Refunded     int  // Minor units refunded or reimbursed so far
+	Reimbursable bool // Expense expected to be paid back
 }
 
 // OverviewStats holds summary statistics for the dashboard
@@ -48,9 +59,15 @@ func (h *Handler) Dashboard(w http.ResponseWriter, r *http.Request) {
 	currentMonth := int(now.Month())
 	currentYear := now.Year()
 
-	// Query all categories for dropdown
-	var categories []models.Category
-	if err := h.db.Find(&categories).Error; err != nil {
+	// Query categories for the dropdowns
+	categories, err := h.getCategories(models.CategoryKindExpense)
+	if err != nil {
+		log.Printf("Error querying categories: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	incomeCategories, err := h.getCategories(models.CategoryKindIncome)
+	if err != nil {
 		log.Printf("Error querying categories: %v", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
@@ -72,127 +89,122 @@ func (h *Handler) Dashboard(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	quickPicks, err := h.getQuickPicks()
+	if err != nil {
+		log.Printf("Error querying quick picks: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
 	data := DashboardData{
 		Categories:         categories,
+		IncomeCategories:   incomeCategories,
 		RecentTransactions: transactions,
 		Overview:           overview,
 		CurrentMonth:       currentMonth,
 		CurrentYear:        currentYear,
 		CurrentDay:         now.Day(),
+		Templates:          quickPicks.Templates,
+		Frequent:           quickPicks.Frequent,
 	}
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	if err := h.templates.ExecuteTemplate(w, "layout.html", data); err != nil {
+	if err := h.pages["dashboard"].ExecuteTemplate(w, "layout.html", data); err != nil {
 		log.Printf("Error executing template: %v", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 	}
 }
 
-// getRecentTransactionsData queries and combines recent expenses and income
-func (h *Handler) getRecentTransactionsData(limit int) ([]Transaction, error) {
-	// Query recent expenses
-	var expenses []models.Expense
-	if err := h.db.Preload("Category").
-		Order("expense_date DESC").
-		Limit(limit).
-		Find(&expenses).Error; err != nil {
-		return nil, err
+// getRefreshData gathers the recent transactions and current month overview
+// that are re-rendered after any change to transactions
+func (h *Handler) getRefreshData() (DashboardData, error) {
+	now := time.Now()
+	transactions, err := h.getRecentTransactionsData(20)
+	if err != nil {
+		return DashboardData{}, err
 	}
 
-	// Query recent income
-	var incomes []models.Income
-	if err := h.db.Order("income_date DESC").
-		Limit(limit).
-		Find(&incomes).Error; err != nil {
-		return nil, err
+	overview, err := h.calculateOverviewStats(int(now.Month()), now.Year())
+	if err != nil {
+		return DashboardData{}, err
 	}
 
-	// Convert to common Transaction type
-	var transactions []Transaction
-
-	for _, e := range expenses {
-		var categoryName *string
-		if e.Category != nil {
-			categoryName = &e.Category.Name
-		}
-
-		transactions = append(transactions, Transaction{
-			ID:         e.ID,
-			Type:       "expense",
-			Name:       e.Name,
-			Amount:     utils.CentsToUSD(e.Amount),
-			AmountRaw:  e.Amount,
-			Date:       e.ExpenseDate.Format("2006-01-02"),
-			DateParsed: e.ExpenseDate,
-			Category:   categoryName,
-			CategoryID: e.CategoryID,
-			Notes:      e.Notes,
-		})
-	}
+	return DashboardData{
+		RecentTransactions: transactions,
+		Overview:           overview,
+		CurrentMonth:       int(now.Month()),
+		CurrentYear:        now.Year(),
+	}, nil
+}
 
-	for _, i := range incomes {
-		transactions = append(transactions, Transaction{
-			ID:         i.ID,
-			Type:       "income",
-			Name:       i.Name,
-			Amount:     utils.CentsToUSD(i.Amount),
-			AmountRaw:  i.Amount,
-			Date:       i.IncomeDate.Format("2006-01-02"),
-			DateParsed: i.IncomeDate,
-			Category:   nil,
-			CategoryID: nil,
-			Notes:      i.Notes,
-		})
-	}
-
-	// Sort by date (newest first)
-	sort.Slice(transactions, func(i, j int) bool {
-		return transactions[i].DateParsed.After(transactions[j].DateParsed)
-	})
-
-	// Limit to requested number
-	if len(transactions) > limit {
-		transactions = transactions[:limit]
-	}
-
-	return transactions, nil
+// getRecentTransactionsData returns the most recent expenses and income,
+// merged and ordered by date in a single query
+func (h *Handler) getRecentTransactionsData(limit int) ([]Transaction, error) {
+	transactions, _, err := h.queryTransactions(TransactionFilter{Sort: "date", Dir: "desc"}, limit)
+	return transactions, err
 }
 
 // calculateOverviewStats calculates total income, expenses, and net savings for a given month
 func (h *Handler) calculateOverviewStats(month, year int) (OverviewStats, error) {
-	// Calculate date range for the month (using local timezone for local-first app)
+	// Calculate date range for the month (using local timezone for local-first app).
+	// Dates are compared as YYYY-MM-DD strings over a half-open range, which works
+	// for both SQLite (dates stored as text) and PostgreSQL (DATE columns).
 	startDate := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.Local)
-	endDate := startDate.AddDate(0, 1, 0).Add(-time.Second)
+	endDate := startDate.AddDate(0, 1, 0)
+	start, end := startDate.Format("2006-01-02"), endDate.Format("2006-01-02")
 
 	// Query expenses for the month
 	var expenses []models.Expense
-	if err := h.db.Where("expense_date BETWEEN ? AND ?", startDate, endDate).
+	if err := h.db.Where("expense_date >= ? AND expense_date < ?", start, end).
 		Find(&expenses).Error; err != nil {
 		return OverviewStats{}, err
 	}
 
 	// Query income for the month
 	var incomes []models.Income
-	if err := h.db.Where("income_date BETWEEN ? AND ?", startDate, endDate).
+	if err := h.db.Where("income_date >= ? AND income_date < ?", start, end).
 		Find(&incomes).Error; err != nil {
 		return OverviewStats{}, err
 	}
 
+	// Refunds and reimbursements received this month reduce spending
+	var refunded int
+	if err := h.db.Raw(`
+		SELECT COALESCE(SUM(r.amount), 0)
+		FROM refunds r
+		JOIN expenses e ON e.id = r.expense_id
+		WHERE e.deleted_at IS NULL AND r.refund_date >= ? AND r.refund_date < ?`,
+		start, end).Scan(&refunded).Error; err != nil {
+		return OverviewStats{}, err
+	}
+
 	// Calculate totals
-	var totalExpenses, totalIncome int
+	amounts := make([]int, 0, len(expenses)+1)
 	for _, e := range expenses {
-		totalExpenses += e.Amount
+		amounts = append(amounts, e.Amount)
 	}
+	totalExpenses, err := currency.Sum(h.base, append(amounts, -refunded)...)
+	if err != nil {
+		return OverviewStats{}, err
+	}
+	amounts = amounts[:0]
 	for _, i := range incomes {
-		totalIncome += i.Amount
+		amounts = append(amounts, i.Amount)
+	}
+	totalIncome, err := currency.Sum(h.base, amounts...)
+	if err != nil {
+		return OverviewStats{}, err
 	}
 
-	netSavings := totalIncome - totalExpenses
+	netSavings, err := totalIncome.Sub(totalExpenses)
+	if err != nil {
+		return OverviewStats{}, err
+	}
 
 	return OverviewStats{
-		TotalIncome:   utils.CentsToUSD(totalIncome),
-		TotalExpenses: utils.CentsToUSD(totalExpenses),
-		NetSavings:    utils.CentsToUSD(netSavings),
-		IsPositive:    netSavings >= 0,
+		TotalIncome:   h.locale.Format(totalIncome.Minor, h.base),
+		TotalExpenses: h.locale.Format(totalExpenses.Minor, h.base),
+		NetSavings:    h.locale.Format(netSavings.Minor, h.base),
+		IsPositive:    netSavings.Minor >= 0,
 	}, nil
 }
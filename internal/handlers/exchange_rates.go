@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/g-linville/budgeting/internal/currency"
+	"github.com/g-linville/budgeting/internal/exchange"
+	"github.com/g-linville/budgeting/internal/models"
+	"github.com/g-linville/budgeting/internal/validation"
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+)
+
+// maxRatesFileSize limits uploaded exchange rate CSV files
+const maxRatesFileSize = 5 << 20
+
+// ExchangeRatesData is the data for the exchange rates modal
+type ExchangeRatesData struct {
+	Base    currency.Currency
+	Rates   []models.ExchangeRate
+	Today   string
+	Message string // Outcome of the last change, e.g. how many were imported
+}
+
+// toBase converts an amount entered in c on date into the base currency. A
+// missing exchange rate is reported as a validation error.
+func (h *Handler) toBase(minor int, c currency.Currency, date time.Time) (int, validation.ValidationErrors, error) {
+	if minor == 0 {
+		return 0, nil, nil
+	}
+	amount, err := exchange.ToBase(h.db, minor, c, h.base, date)
+	if errors.Is(err, exchange.ErrNoRate) {
+		return 0, validation.ValidationErrors{{
+			Field:   "currency",
+			Message: fmt.Sprintf("No exchange rate for %s to %s has been entered yet", c.Code, h.base.Code),
+		}}, nil
+	}
+	return amount, nil, err
+}
+
+// belowRefundedMessage explains that converting an expense again would
+// leave less than its refunds
+func (h *Handler) belowRefundedMessage(e *exchange.BelowRefundedError) string {
+	return fmt.Sprintf("%q would be converted to %s, less than the %s already refunded",
+		e.Name, h.locale.Format(e.Amount.Minor, e.Amount.Currency), h.locale.Format(e.Refunded.Minor, e.Refunded.Currency))
+}
+
+// currencyCode returns the code stored with a transaction entered in c,
+// which is blank for the base currency
+func (h *Handler) currencyCode(c currency.Currency) string {
+	if c.Code == h.base.Code {
+		return ""
+	}
+	return c.Code
+}
+
+// ListExchangeRates handles GET /exchange-rates
+func (h *Handler) ListExchangeRates(w http.ResponseWriter, r *http.Request) {
+	h.renderExchangeRates(w, "exchange-rates-modal", http.StatusOK, "")
+}
+
+// CreateExchangeRate handles POST /exchange-rates
+func (h *Handler) CreateExchangeRate(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		return
+	}
+
+	rate, validationErrors := validation.ValidateExchangeRate(
+		r.FormValue("currency"), r.FormValue("rate_date"), r.FormValue("rate"), h.base)
+	if validationErrors.HasErrors() {
+		h.writeExchangeRateErrors(w, validationErrors)
+		return
+	}
+
+	h.saveRates(w, []currency.Rate{rate}, models.RateSourceManual)
+}
+
+// ImportExchangeRates handles POST /exchange-rates/import, reading a CSV file
+// of date,currency,rate lines
+func (h *Handler) ImportExchangeRates(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRatesFileSize)
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		h.writeExchangeRateErrors(w, validation.ValidationErrors{
+			{Field: "file", Message: "Choose a CSV file of up to 5 MB"},
+		})
+		return
+	}
+	defer file.Close()
+
+	rates, err := currency.ParseRatesCSV(file, h.base)
+	if err != nil {
+		h.writeExchangeRateErrors(w, validation.ValidationErrors{{Field: "file", Message: err.Error()}})
+		return
+	}
+	if len(rates) == 0 {
+		h.writeExchangeRateErrors(w, validation.ValidationErrors{{Field: "file", Message: "The file has no rates"}})
+		return
+	}
+
+	h.saveRates(w, rates, models.RateSourceCSV)
+}
+
+// DeleteExchangeRate handles DELETE /exchange-rates/{id}
+func (h *Handler) DeleteExchangeRate(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var changed int
+	if err := h.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&models.ExchangeRate{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		changed, err = exchange.Reconvert(tx, h.base)
+		return err
+	}); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Exchange rate not found", http.StatusNotFound)
+			return
+		}
+		if h.writeBelowRefunded(w, err) {
+			return
+		}
+		log.Printf("Error deleting exchange rate: %v", err)
+		http.Error(w, "Failed to delete exchange rate", http.StatusInternalServerError)
+		return
+	}
+
+	h.renderExchangeRates(w, "exchange-rate-list", http.StatusOK, reconvertMessage("Deleted the rate", changed))
+}
+
+// saveRates stores rates, recalculates the transactions they affect and
+// renders the rate list
+func (h *Handler) saveRates(w http.ResponseWriter, rates []currency.Rate, source string) {
+	var saved, changed int
+	if err := h.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if saved, err = exchange.SaveRates(tx, rates, source); err != nil {
+			return err
+		}
+		changed, err = exchange.Reconvert(tx, h.base)
+		return err
+	}); err != nil {
+		if h.writeBelowRefunded(w, err) {
+			return
+		}
+		log.Printf("Error saving exchange rates: %v", err)
+		http.Error(w, "Failed to save exchange rates", http.StatusInternalServerError)
+		return
+	}
+
+	message := fmt.Sprintf("Saved %d rate", saved)
+	if saved != 1 {
+		message += "s"
+	}
+	h.renderExchangeRates(w, "exchange-rate-list", http.StatusCreated, reconvertMessage(message, changed))
+}
+
+// writeBelowRefunded reports a rate change that would leave an expense
+// below its refunds as a validation error. It returns false for other
+// errors.
+func (h *Handler) writeBelowRefunded(w http.ResponseWriter, err error) bool {
+	var belowRefunded *exchange.BelowRefundedError
+	if !errors.As(err, &belowRefunded) {
+		return false
+	}
+	h.writeExchangeRateErrors(w, validation.ValidationErrors{{
+		Field:   "rate",
+		Message: h.belowRefundedMessage(belowRefunded),
+	}})
+	return true
+}
+
+// reconvertMessage adds how many transactions were recalculated to message
+func reconvertMessage(message string, changed int) string {
+	if changed == 0 {
+		return message
+	}
+	if changed == 1 {
+		return message + "; 1 transaction was recalculated"
+	}
+	return fmt.Sprintf("%s; %d transactions were recalculated", message, changed)
+}
+
+// renderExchangeRates renders the exchange rates modal or just its list
+func (h *Handler) renderExchangeRates(w http.ResponseWriter, name string, status int, message string) {
+	var rates []models.ExchangeRate
+	if err := h.db.Order("rate_date DESC, currency").Find(&rates).Error; err != nil {
+		log.Printf("Error querying exchange rates: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	data := ExchangeRatesData{
+		Base:    h.base,
+		Rates:   rates,
+		Today:   time.Now().Format("2006-01-02"),
+		Message: message,
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := h.templates.ExecuteTemplate(w, name, data); err != nil {
+		log.Printf("Error executing template: %v", err)
+	}
+}
+
+// writeExchangeRateErrors shows validation errors above the rate list
+func (h *Handler) writeExchangeRateErrors(w http.ResponseWriter, validationErrors validation.ValidationErrors) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("HX-Retarget", "#exchange-rate-errors")
+	w.Header().Set("HX-Reswap", "innerHTML")
+	w.WriteHeader(http.StatusBadRequest)
+	h.templates.ExecuteTemplate(w, "validation-errors", validationErrors)
+}
@@ -0,0 +1,281 @@
+package handlers
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/g-linville/budgeting/internal/models"
+	"github.com/g-linville/budgeting/internal/utils"
+	"github.com/g-linville/budgeting/internal/validation"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	// Expenses entered at least frequentMinCount times in the last
+	// frequentDays days are offered for quick entry
+	frequentDays     = 90
+	frequentMinCount = 2
+	frequentLimit    = 6
+)
+
+// ExpensePrefill holds values that fill in the quick-add expense form
+type ExpensePrefill struct {
+	Name       string
+	Amount     int // Minor units; 0 leaves the amount blank
+	CategoryID uint
+}
+
+// FrequentExpense is an expense entered often lately, offered for quick entry
+// with the values of its most recent occurrence
+type FrequentExpense struct {
+	ExpenseID uint // Most recent occurrence
+	Name      string
+	Amount    int // Minor units of the base currency
+	Count     int
+}
+
+// getExpenseTemplates returns the saved templates, by name
+func (h *Handler) getExpenseTemplates() ([]models.ExpenseTemplate, error) {
+	var list []models.ExpenseTemplate
+	err := h.db.Preload("Category").Order("name").Find(&list).Error
+	return list, err
+}
+
+// getFrequentExpenses ranks recent expenses by how often the same name was
+// entered, most frequent first. Names already saved as templates and split
+// expenses are left out.
+func (h *Handler) getFrequentExpenses(templates []models.ExpenseTemplate) ([]FrequentExpense, error) {
+	since := time.Now().AddDate(0, 0, -frequentDays).Format("2006-01-02")
+	var recent []models.Expense
+	if err := h.db.Select("id", "name", "amount").
+		Where("expense_date >= ?", since).
+		Where("NOT EXISTS (SELECT 1 FROM expense_splits s WHERE s.expense_id = expenses.id)").
+		Order("expense_date DESC, id DESC").
+		Find(&recent).Error; err != nil {
+		return nil, err
+	}
+
+	saved := make(map[string]bool, len(templates))
+	for _, t := range templates {
+		saved[utils.NormalizeName(t.Name)] = true
+	}
+
+	// The first expense seen for each name is the most recent
+	var frequent []FrequentExpense
+	index := make(map[string]int)
+	for _, e := range recent {
+		key := utils.NormalizeName(e.Name)
+		if saved[key] {
+			continue
+		}
+		if i, ok := index[key]; ok {
+			frequent[i].Count++
+			continue
+		}
+		index[key] = len(frequent)
+		frequent = append(frequent, FrequentExpense{ExpenseID: e.ID, Name: e.Name, Amount: e.Amount, Count: 1})
+	}
+
+	// Ties keep the most recently used first
+	sort.SliceStable(frequent, func(i, j int) bool { return frequent[i].Count > frequent[j].Count })
+	n := 0
+	for n < len(frequent) && n < frequentLimit && frequent[n].Count >= frequentMinCount {
+		n++
+	}
+	return frequent[:n], nil
+}
+
+// getQuickPicks loads the templates and frequent expenses shown above the
+// quick-add expense form
+func (h *Handler) getQuickPicks() (DashboardData, error) {
+	templates, err := h.getExpenseTemplates()
+	if err != nil {
+		return DashboardData{}, err
+	}
+	frequent, err := h.getFrequentExpenses(templates)
+	if err != nil {
+		return DashboardData{}, err
+	}
+	return DashboardData{Templates: templates, Frequent: frequent}, nil
+}
+
+// GetExpenseForm handles GET /partials/expense-form. The form is filled in
+// from a template (?template=ID) or a previous expense (?expense=ID).
+func (h *Handler) GetExpenseForm(w http.ResponseWriter, r *http.Request) {
+	var prefill ExpensePrefill
+	query := r.URL.Query()
+	if idStr := query.Get("template"); idStr != "" {
+		var tmpl models.ExpenseTemplate
+		if err := h.db.First(&tmpl, idStr).Error; err != nil {
+			http.Error(w, "Template not found", http.StatusNotFound)
+			return
+		}
+		prefill.Name = tmpl.Name
+		if tmpl.Amount != nil {
+			prefill.Amount = *tmpl.Amount
+		}
+		if tmpl.CategoryID != nil {
+			prefill.CategoryID = *tmpl.CategoryID
+		}
+	} else if idStr := query.Get("expense"); idStr != "" {
+		var expense models.Expense
+		if err := h.db.First(&expense, idStr).Error; err != nil {
+			http.Error(w, "Expense not found", http.StatusNotFound)
+			return
+		}
+		prefill = ExpensePrefill{Name: expense.Name, Amount: expense.Amount}
+		if expense.CategoryID != nil {
+			prefill.CategoryID = *expense.CategoryID
+		}
+	}
+
+	categories, err := h.getCategories(models.CategoryKindExpense)
+	if err != nil {
+		log.Printf("Error querying categories: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now()
+	data := DashboardData{
+		Categories:   categories,
+		CurrentMonth: int(now.Month()),
+		CurrentYear:  now.Year(),
+		CurrentDay:   now.Day(),
+		Prefill:      prefill,
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := h.templates.ExecuteTemplate(w, "expense-form", data); err != nil {
+		log.Printf("Error executing template: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// ListExpenseTemplates handles GET /expense-templates
+func (h *Handler) ListExpenseTemplates(w http.ResponseWriter, r *http.Request) {
+	templates, err := h.getExpenseTemplates()
+	if err != nil {
+		log.Printf("Error querying expense templates: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	categories, err := h.getCategories(models.CategoryKindExpense)
+	if err != nil {
+		log.Printf("Error querying categories: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	data := DashboardData{
+		Categories: categories,
+		Templates:  templates,
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := h.templates.ExecuteTemplate(w, "expense-template-modal", data); err != nil {
+		log.Printf("Error executing template: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// CreateExpenseTemplate handles POST /expense-templates
+func (h *Handler) CreateExpenseTemplate(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		return
+	}
+
+	name := strings.TrimSpace(r.FormValue("name"))
+	amount, validationErrors := validation.ValidateExpenseTemplate(name, r.FormValue("amount"), h.base, h.locale)
+
+	// Parse category ID (optional)
+	var categoryID *uint
+	if id, err := strconv.ParseUint(r.FormValue("category_id"), 10, 32); err == nil {
+		categoryIDUint := uint(id)
+		categoryID = &categoryIDUint
+	}
+	categoryErrors, err := h.checkCategory(categoryID, models.CategoryKindExpense, nil, "category_id", "Category")
+	if err != nil {
+		log.Printf("Error checking category: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	validationErrors = append(validationErrors, categoryErrors...)
+	if validationErrors.HasErrors() {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("HX-Retarget", "#expense-template-errors")
+		w.Header().Set("HX-Reswap", "innerHTML")
+		w.WriteHeader(http.StatusBadRequest)
+		h.templates.ExecuteTemplate(w, "validation-errors", validationErrors)
+		return
+	}
+
+	tmpl := models.ExpenseTemplate{
+		Name:       name,
+		Amount:     amount,
+		CategoryID: categoryID,
+	}
+	if err := h.db.Create(&tmpl).Error; err != nil {
+		log.Printf("Error creating expense template: %v", err)
+		http.Error(w, "Failed to save template", http.StatusInternalServerError)
+		return
+	}
+
+	h.renderExpenseTemplateList(w, http.StatusCreated)
+}
+
+// DeleteExpenseTemplate handles DELETE /expense-templates/{id}
+func (h *Handler) DeleteExpenseTemplate(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	result := h.db.Delete(&models.ExpenseTemplate{}, id)
+	if result.Error != nil {
+		log.Printf("Error deleting expense template: %v", result.Error)
+		http.Error(w, "Failed to delete template", http.StatusInternalServerError)
+		return
+	}
+	if result.RowsAffected == 0 {
+		http.Error(w, "Template not found", http.StatusNotFound)
+		return
+	}
+
+	h.renderExpenseTemplateList(w, http.StatusOK)
+}
+
+// renderExpenseTemplateList renders the template list along with the quick
+// picks above the expense form
+func (h *Handler) renderExpenseTemplateList(w http.ResponseWriter, status int) {
+	data, err := h.getQuickPicks()
+	if err != nil {
+		log.Printf("Error querying expense templates: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := h.templates.ExecuteTemplate(w, "expense-template-list", data); err != nil {
+		log.Printf("Error executing template: %v", err)
+		return
+	}
+
+	// Render OOB quick picks
+	oobBuf := new(bytes.Buffer)
+	if err := h.templates.ExecuteTemplate(oobBuf, "expense-quick-picks-oob", data); err != nil {
+		log.Printf("Error executing OOB template: %v", err)
+		return
+	}
+	w.Write(oobBuf.Bytes())
+}
@@ -2,14 +2,21 @@ package handlers
 
 import (
 	"bytes"
+	"fmt"
 	"log"
 	"net/http"
 	"strconv"
 	"time"
 
+	"github.com/g-linville/budgeting/internal/audit"
+	"github.com/g-linville/budgeting/internal/currency"
+	"github.com/g-linville/budgeting/internal/duplicates"
 	"github.com/g-linville/budgeting/internal/models"
+	"github.com/g-linville/budgeting/internal/payees"
+	"github.com/g-linville/budgeting/internal/rules"
 	"github.com/g-linville/budgeting/internal/validation"
 	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
 )
 
 // CreateExpense handles POST /expenses
@@ -26,16 +33,22 @@ func (h *Handler) CreateExpense(w http.ResponseWriter, r *http.Request) {
 	categoryIDStr := r.FormValue("category_id")
 
 	// Validate input
-	amountCents, date, validationErrors := validation.ValidateExpense(name, amountStr, dateStr)
-	if validationErrors.HasErrors() {
-		log.Printf("Validation errors: %v", validationErrors)
-		w.Header().Set("Content-Type", "text/html; charset=utf-8")
-		w.Header().Set("HX-Retarget", "#expense-form-errors")
-		w.Header().Set("HX-Reswap", "innerHTML")
-		w.WriteHeader(http.StatusBadRequest)
-		h.templates.ExecuteTemplate(w, "validation-errors", validationErrors)
+	entered, validationErrors := validation.ValidateCurrency(r.FormValue("currency"), h.base)
+	originalAmount, date, expenseErrors := validation.ValidateExpense(name, amountStr, dateStr, entered, h.locale)
+	validationErrors = append(validationErrors, expenseErrors...)
+	tags, tagErrors := validation.ValidateTags(r.FormValue("tags"))
+	validationErrors = append(validationErrors, tagErrors...)
+	splits, splitErrors := validation.ValidateSplits(originalAmount, r.Form["split_category_id"], r.Form["split_amount"], entered, h.locale)
+	validationErrors = append(validationErrors, splitErrors...)
+
+	// Amounts are stored in the base currency
+	amountCents, rateErrors, err := h.toBase(originalAmount, entered, date)
+	if err != nil {
+		log.Printf("Error converting amount: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
+	validationErrors = append(validationErrors, rateErrors...)
 
 	// Parse category ID (optional)
 	var categoryID *uint
@@ -47,16 +60,88 @@ func (h *Handler) CreateExpense(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// A split expense is categorized by its lines instead
+	if len(splits) > 0 {
+		categoryID = nil
+	}
+
+	categoryErrors, err := h.checkExpenseCategories(categoryID, splits, nil)
+	if err != nil {
+		log.Printf("Error checking categories: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	validationErrors = append(validationErrors, categoryErrors...)
+	if validationErrors.HasErrors() {
+		log.Printf("Validation errors: %v", validationErrors)
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("HX-Retarget", "#expense-form-errors")
+		w.Header().Set("HX-Reswap", "innerHTML")
+		w.WriteHeader(http.StatusBadRequest)
+		h.templates.ExecuteTemplate(w, "validation-errors", validationErrors)
+		return
+	}
+
+	// Warn about a probable duplicate until the user confirms it is not one
+	if r.FormValue("confirm_duplicate") == "" {
+		matches, err := duplicates.FindExpenses(h.db, duplicates.Candidate{Name: name, Amount: amountCents, Date: date})
+		if err != nil {
+			log.Printf("Error checking for duplicates: %v", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if len(matches) > 0 {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Header().Set("HX-Retarget", "#expense-form-errors")
+			w.Header().Set("HX-Reswap", "innerHTML")
+			if err := h.templates.ExecuteTemplate(w, "duplicate-warning", matches); err != nil {
+				log.Printf("Error executing template: %v", err)
+			}
+			return
+		}
+	}
+
 	// Create expense record
 	expense := models.Expense{
-		Name:        name,
-		Amount:      amountCents,
-		CategoryID:  categoryID,
-		ExpenseDate: date,
-		Notes:       notes,
-	}
+		Name:           name,
+		Amount:         amountCents,
+		Currency:       h.currencyCode(entered),
+		OriginalAmount: originalAmount,
+		CategoryID:     categoryID,
+		ExpenseDate:    date,
+		Notes:          notes,
+		Reimbursable:   r.FormValue("reimbursable") == "on",
+	}
+
+	if err := h.db.Transaction(func(tx *gorm.DB) error {
+		payeeID, err := payees.Match(tx, name)
+		if err != nil {
+			return err
+		}
+		expense.PayeeID = payeeID
 
-	if err := h.db.Create(&expense).Error; err != nil {
+		// Categorization rules fill in what the user left blank
+		engine, err := rules.Load(tx)
+		if err != nil {
+			return err
+		}
+		result := engine.Apply(rules.Input{Name: name, Amount: amountCents, Date: date, PayeeID: payeeID})
+		if expense.CategoryID == nil && len(splits) == 0 {
+			expense.CategoryID = result.CategoryID
+		}
+		tags = rules.MergeTags(tags, result.Tags)
+
+		if err := tx.Create(&expense).Error; err != nil {
+			return err
+		}
+		if err := setTags(tx, &expense, tags); err != nil {
+			return err
+		}
+		if err := setSplits(tx, expense.ID, splits, currency.New(expense.Amount, h.base)); err != nil {
+			return err
+		}
+		return audit.Record(tx, audit.EntityExpense, expense.ID, audit.ActionCreate, "")
+	}); err != nil {
 		log.Printf("Error creating expense: %v", err)
 		http.Error(w, "Failed to create expense", http.StatusInternalServerError)
 		return
@@ -114,21 +199,30 @@ func (h *Handler) GetExpenseEditForm(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var expense models.Expense
-	if err := h.db.Preload("Category").First(&expense, id).Error; err != nil {
+	if err := h.db.Preload("Category").
+		Preload("Tags", func(db *gorm.DB) *gorm.DB { return db.Order("tags.name") }).
+		Preload("Splits", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		First(&expense, id).Error; err != nil {
 		http.Error(w, "Expense not found", http.StatusNotFound)
 		return
 	}
 
 	// Get categories for dropdown
-	var categories []models.Category
-	h.db.Find(&categories)
+	categories, _ := h.getCategories(models.CategoryKindExpense)
+
+	var splitRows []SplitRowData
+	for _, split := range expense.Splits {
+		splitRows = append(splitRows, SplitRowData{Categories: categories, Split: split, Currency: expense.Currency})
+	}
 
 	data := struct {
 		Expense    models.Expense
 		Categories []models.Category
+		SplitRows  []SplitRowData
 	}{
 		Expense:    expense,
 		Categories: categories,
+		SplitRows:  splitRows,
 	}
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
@@ -159,12 +253,22 @@ func (h *Handler) UpdateExpense(w http.ResponseWriter, r *http.Request) {
 	categoryIDStr := r.FormValue("category_id")
 
 	// Validate input
-	amountCents, date, validationErrors := validation.ValidateExpense(name, amountStr, dateStr)
-	if validationErrors.HasErrors() {
-		log.Printf("Validation errors: %v", validationErrors)
-		http.Error(w, validationErrors.Error(), http.StatusBadRequest)
+	entered, validationErrors := validation.ValidateCurrency(r.FormValue("currency"), h.base)
+	originalAmount, date, expenseErrors := validation.ValidateExpense(name, amountStr, dateStr, entered, h.locale)
+	validationErrors = append(validationErrors, expenseErrors...)
+	tags, tagErrors := validation.ValidateTags(r.FormValue("tags"))
+	validationErrors = append(validationErrors, tagErrors...)
+	splits, splitErrors := validation.ValidateSplits(originalAmount, r.Form["split_category_id"], r.Form["split_amount"], entered, h.locale)
+	validationErrors = append(validationErrors, splitErrors...)
+
+	// Amounts are stored in the base currency
+	amountCents, rateErrors, err := h.toBase(originalAmount, entered, date)
+	if err != nil {
+		log.Printf("Error converting amount: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
+	validationErrors = append(validationErrors, rateErrors...)
 
 	// Parse category ID (optional)
 	var categoryID *uint
@@ -176,20 +280,81 @@ func (h *Handler) UpdateExpense(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// Update expense
+	// A split expense is categorized by its lines instead
+	if len(splits) > 0 {
+		categoryID = nil
+	}
+
 	var expense models.Expense
 	if err := h.db.First(&expense, id).Error; err != nil {
 		http.Error(w, "Expense not found", http.StatusNotFound)
 		return
 	}
 
+	// The expense may keep categories archived since they were chosen
+	var kept []uint
+	if err := h.db.Model(&models.ExpenseSplit{}).Where("expense_id = ? AND category_id IS NOT NULL", expense.ID).
+		Pluck("category_id", &kept).Error; err != nil {
+		log.Printf("Error loading splits: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if expense.CategoryID != nil {
+		kept = append(kept, *expense.CategoryID)
+	}
+	categoryErrors, err := h.checkExpenseCategories(categoryID, splits, kept)
+	if err != nil {
+		log.Printf("Error checking categories: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	validationErrors = append(validationErrors, categoryErrors...)
+	if validationErrors.HasErrors() {
+		log.Printf("Validation errors: %v", validationErrors)
+		http.Error(w, validationErrors.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Refunds can never exceed the expense they are recorded against
+	refunded, err := refundedAmount(h.db, expense.ID)
+	if err != nil {
+		log.Printf("Error summing refunds: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if amountCents < refunded {
+		http.Error(w, fmt.Sprintf("Amount cannot be less than the %s already refunded", h.locale.Format(refunded, h.base)), http.StatusBadRequest)
+		return
+	}
+
 	expense.Name = name
 	expense.Amount = amountCents
+	expense.Currency = h.currencyCode(entered)
+	expense.OriginalAmount = originalAmount
 	expense.CategoryID = categoryID
 	expense.ExpenseDate = date
 	expense.Notes = notes
+	expense.Reimbursable = r.FormValue("reimbursable") == "on"
 
-	if err := h.db.Save(&expense).Error; err != nil {
+	if err := h.db.Transaction(func(tx *gorm.DB) error {
+		before, err := audit.Snapshot(tx, audit.EntityExpense, expense.ID)
+		if err != nil {
+			return err
+		}
+		if expense.PayeeID, err = payees.Match(tx, name); err != nil {
+			return err
+		}
+		if err := tx.Save(&expense).Error; err != nil {
+			return err
+		}
+		if err := setTags(tx, &expense, tags); err != nil {
+			return err
+		}
+		if err := setSplits(tx, expense.ID, splits, currency.New(expense.Amount, h.base)); err != nil {
+			return err
+		}
+		return audit.Record(tx, audit.EntityExpense, expense.ID, audit.ActionUpdate, before)
+	}); err != nil {
 		log.Printf("Error updating expense: %v", err)
 		http.Error(w, "Failed to update expense", http.StatusInternalServerError)
 		return
@@ -244,7 +409,16 @@ func (h *Handler) DeleteExpense(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.db.Delete(&models.Expense{}, id).Error; err != nil {
+	if err := h.db.Transaction(func(tx *gorm.DB) error {
+		before, err := audit.Snapshot(tx, audit.EntityExpense, uint(id))
+		if err != nil || before == "" {
+			return err
+		}
+		if err := tx.Delete(&models.Expense{}, id).Error; err != nil {
+			return err
+		}
+		return audit.Record(tx, audit.EntityExpense, uint(id), audit.ActionDelete, before)
+	}); err != nil {
 		log.Printf("Error deleting expense: %v", err)
 		http.Error(w, "Failed to delete expense", http.StatusInternalServerError)
 		return
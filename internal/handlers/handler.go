@@ -3,19 +3,33 @@ package handlers
 import (
 	"html/template"
 
+	"github.com/g-linville/budgeting/internal/attachments"
+	"github.com/g-linville/budgeting/internal/backup"
+	"github.com/g-linville/budgeting/internal/currency"
+	"github.com/g-linville/budgeting/internal/locale"
 	"gorm.io/gorm"
 )
 
 // Handler holds dependencies for all HTTP handlers
 type Handler struct {
-	db        *gorm.DB
-	templates *template.Template
+	db          *gorm.DB
+	templates   *template.Template
+	pages       map[string]*template.Template // Full pages, keyed by name
+	backups     *backup.Manager               // nil when backups are disabled
+	attachments *attachments.Store
+	base        currency.Currency // Currency amounts are stored in
+	locale      locale.Locale     // How amounts are written and read
 }
 
 // New creates a new Handler with injected dependencies
-func New(db *gorm.DB, templates *template.Template) *Handler {
+func New(db *gorm.DB, templates *template.Template, pages map[string]*template.Template, backups *backup.Manager, attachmentStore *attachments.Store, base currency.Currency, loc locale.Locale) *Handler {
 	return &Handler{
-		db:        db,
-		templates: templates,
+		db:          db,
+		templates:   templates,
+		pages:       pages,
+		backups:     backups,
+		attachments: attachmentStore,
+		base:        base,
+		locale:      loc,
 	}
 }
@@ -1,12 +1,15 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/g-linville/budgeting/internal/database"
 )
 
-// HealthCheck verifies that the server and database are operational
+// HealthCheck verifies that the server and database are operational and
+// reports the state of database backups
 func (h *Handler) HealthCheck(w http.ResponseWriter, r *http.Request) {
 	// Check DB connectivity
 	if err := database.Ping(h.db); err != nil {
@@ -15,6 +18,36 @@ func (h *Handler) HealthCheck(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// A failed backup makes the server unhealthy so monitoring notices it
+	line, ok := h.backupStatusLine()
+	if !ok {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("Backup unhealthy\n"))
+		w.Write([]byte(line + "\n"))
+		return
+	}
+
 	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("OK"))
+	w.Write([]byte("OK\n"))
+	w.Write([]byte(line + "\n"))
+}
+
+// backupStatusLine summarizes the backup manager's status in one line and
+// reports false if the last backup failed
+func (h *Handler) backupStatusLine() (string, bool) {
+	if h.backups == nil {
+		return "backup: disabled", true
+	}
+
+	status := h.backups.Status()
+	switch {
+	case status.LastError != "":
+		return fmt.Sprintf("backup: FAILED at %s: %s (%d backups in %s)",
+			status.LastAttempt.Format(time.RFC3339), status.LastError, status.Count, status.Dir), false
+	case status.LastSuccess.IsZero():
+		return fmt.Sprintf("backup: none yet (%s)", status.Dir), true
+	default:
+		return fmt.Sprintf("backup: ok, last %s (%s, %d backups)",
+			status.LastSuccess.Format(time.RFC3339), status.LastFile, status.Count), true
+	}
 }
@@ -7,9 +7,11 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/g-linville/budgeting/internal/audit"
 	"github.com/g-linville/budgeting/internal/models"
 	"github.com/g-linville/budgeting/internal/validation"
 	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
 )
 
 // CreateIncome handles POST /incomes
@@ -24,8 +26,35 @@ func (h *Handler) CreateIncome(w http.ResponseWriter, r *http.Request) {
 	dateStr := r.FormValue("income_date")
 	notes := r.FormValue("notes")
 
+	// Parse category ID (optional)
+	var categoryID *uint
+	if id, err := strconv.ParseUint(r.FormValue("category_id"), 10, 32); err == nil {
+		categoryIDUint := uint(id)
+		categoryID = &categoryIDUint
+	}
+
 	// Validate input
-	amountCents, date, validationErrors := validation.ValidateIncome(name, amountStr, dateStr)
+	entered, validationErrors := validation.ValidateCurrency(r.FormValue("currency"), h.base)
+	originalAmount, date, incomeErrors := validation.ValidateIncome(name, amountStr, dateStr, entered, h.locale)
+	validationErrors = append(validationErrors, incomeErrors...)
+	tags, tagErrors := validation.ValidateTags(r.FormValue("tags"))
+	validationErrors = append(validationErrors, tagErrors...)
+
+	// Amounts are stored in the base currency
+	amountCents, rateErrors, err := h.toBase(originalAmount, entered, date)
+	if err != nil {
+		log.Printf("Error converting amount: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	validationErrors = append(validationErrors, rateErrors...)
+	categoryErrors, err := h.checkCategory(categoryID, models.CategoryKindIncome, nil, "category_id", "Category")
+	if err != nil {
+		log.Printf("Error checking category: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	validationErrors = append(validationErrors, categoryErrors...)
 	if validationErrors.HasErrors() {
 		log.Printf("Validation errors: %v", validationErrors)
 		w.Header().Set("Content-Type", "text/html; charset=utf-8")
@@ -38,13 +67,24 @@ func (h *Handler) CreateIncome(w http.ResponseWriter, r *http.Request) {
 
 	// Create income record
 	income := models.Income{
-		Name:       name,
-		Amount:     amountCents,
-		IncomeDate: date,
-		Notes:      notes,
+		Name:           name,
+		Amount:         amountCents,
+		Currency:       h.currencyCode(entered),
+		OriginalAmount: originalAmount,
+		IncomeDate:     date,
+		Notes:          notes,
+		CategoryID:     categoryID,
 	}
 
-	if err := h.db.Create(&income).Error; err != nil {
+	if err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&income).Error; err != nil {
+			return err
+		}
+		if err := setTags(tx, &income, tags); err != nil {
+			return err
+		}
+		return audit.Record(tx, audit.EntityIncome, income.ID, audit.ActionCreate, "")
+	}); err != nil {
 		log.Printf("Error creating income: %v", err)
 		http.Error(w, "Failed to create income", http.StatusInternalServerError)
 		return
@@ -102,15 +142,24 @@ func (h *Handler) GetIncomeEditForm(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var income models.Income
-	if err := h.db.First(&income, id).Error; err != nil {
+	if err := h.db.Preload("Tags", func(db *gorm.DB) *gorm.DB { return db.Order("tags.name") }).First(&income, id).Error; err != nil {
 		http.Error(w, "Income not found", http.StatusNotFound)
 		return
 	}
 
+	categories, err := h.getCategories(models.CategoryKindIncome)
+	if err != nil {
+		log.Printf("Error querying categories: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
 	data := struct {
-		Income models.Income
+		Income     models.Income
+		Categories []models.Category
 	}{
-		Income: income,
+		Income:     income,
+		Categories: categories,
 	}
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
@@ -139,27 +188,73 @@ func (h *Handler) UpdateIncome(w http.ResponseWriter, r *http.Request) {
 	dateStr := r.FormValue("income_date")
 	notes := r.FormValue("notes")
 
+	// Parse category ID (optional)
+	var categoryID *uint
+	if id, err := strconv.ParseUint(r.FormValue("category_id"), 10, 32); err == nil {
+		categoryIDUint := uint(id)
+		categoryID = &categoryIDUint
+	}
+
 	// Validate input
-	amountCents, date, validationErrors := validation.ValidateIncome(name, amountStr, dateStr)
-	if validationErrors.HasErrors() {
-		log.Printf("Validation errors: %v", validationErrors)
-		http.Error(w, validationErrors.Error(), http.StatusBadRequest)
+	entered, validationErrors := validation.ValidateCurrency(r.FormValue("currency"), h.base)
+	originalAmount, date, incomeErrors := validation.ValidateIncome(name, amountStr, dateStr, entered, h.locale)
+	validationErrors = append(validationErrors, incomeErrors...)
+	tags, tagErrors := validation.ValidateTags(r.FormValue("tags"))
+	validationErrors = append(validationErrors, tagErrors...)
+
+	// Amounts are stored in the base currency
+	amountCents, rateErrors, err := h.toBase(originalAmount, entered, date)
+	if err != nil {
+		log.Printf("Error converting amount: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
-
-	// Update income
+	validationErrors = append(validationErrors, rateErrors...)
 	var income models.Income
 	if err := h.db.First(&income, id).Error; err != nil {
 		http.Error(w, "Income not found", http.StatusNotFound)
 		return
 	}
 
+	// The income may keep a category archived since it was chosen
+	var kept []uint
+	if income.CategoryID != nil {
+		kept = append(kept, *income.CategoryID)
+	}
+	categoryErrors, err := h.checkCategory(categoryID, models.CategoryKindIncome, kept, "category_id", "Category")
+	if err != nil {
+		log.Printf("Error checking category: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	validationErrors = append(validationErrors, categoryErrors...)
+	if validationErrors.HasErrors() {
+		log.Printf("Validation errors: %v", validationErrors)
+		http.Error(w, validationErrors.Error(), http.StatusBadRequest)
+		return
+	}
+
 	income.Name = name
 	income.Amount = amountCents
+	income.Currency = h.currencyCode(entered)
+	income.OriginalAmount = originalAmount
 	income.IncomeDate = date
 	income.Notes = notes
-
-	if err := h.db.Save(&income).Error; err != nil {
+	income.CategoryID = categoryID
+
+	if err := h.db.Transaction(func(tx *gorm.DB) error {
+		before, err := audit.Snapshot(tx, audit.EntityIncome, income.ID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Save(&income).Error; err != nil {
+			return err
+		}
+		if err := setTags(tx, &income, tags); err != nil {
+			return err
+		}
+		return audit.Record(tx, audit.EntityIncome, income.ID, audit.ActionUpdate, before)
+	}); err != nil {
 		log.Printf("Error updating income: %v", err)
 		http.Error(w, "Failed to update income", http.StatusInternalServerError)
 		return
@@ -214,7 +309,16 @@ func (h *Handler) DeleteIncome(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.db.Delete(&models.Income{}, id).Error; err != nil {
+	if err := h.db.Transaction(func(tx *gorm.DB) error {
+		before, err := audit.Snapshot(tx, audit.EntityIncome, uint(id))
+		if err != nil || before == "" {
+			return err
+		}
+		if err := tx.Delete(&models.Income{}, id).Error; err != nil {
+			return err
+		}
+		return audit.Record(tx, audit.EntityIncome, uint(id), audit.ActionDelete, before)
+	}); err != nil {
 		log.Printf("Error deleting income: %v", err)
 		http.Error(w, "Failed to delete income", http.StatusInternalServerError)
 		return
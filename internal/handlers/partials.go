@@ -5,6 +5,8 @@ import (
 	"net/http"
 	"strconv"
 	"time"
+
+	"github.com/g-linville/budgeting/internal/validation"
 )
 
 // GetRecentTransactions handles GET /partials/recent-transactions
@@ -65,3 +67,33 @@ func (h *Handler) GetOverview(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 	}
 }
+
+// AmountPreviewData holds the computed value of an amount expression
+type AmountPreviewData struct {
+	Value string
+	Error string
+}
+
+// GetAmountPreview handles GET /partials/amount-preview?amount=...&currency=...,
+// showing what an arithmetic amount such as "84.20/2" works out to
+func (h *Handler) GetAmountPreview(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	c, validationErrors := validation.ValidateCurrency(r.URL.Query().Get("currency"), h.base)
+	if validationErrors.HasErrors() {
+		return
+	}
+
+	var data AmountPreviewData
+	value, err := validation.PreviewAmount(r.URL.Query().Get("amount"), c, h.locale)
+	if err != nil {
+		data.Error = err.Error()
+	} else if value == "" {
+		return
+	}
+	data.Value = value
+
+	if err := h.templates.ExecuteTemplate(w, "amount-preview", data); err != nil {
+		log.Printf("Error executing template: %v", err)
+	}
+}
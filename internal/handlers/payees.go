@@ -0,0 +1,313 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/g-linville/budgeting/internal/models"
+	"github.com/g-linville/budgeting/internal/payees"
+	"github.com/g-linville/budgeting/internal/validation"
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+)
+
+// PayeeItem is a payee with its aliases and number of linked expenses
+type PayeeItem struct {
+	models.Payee
+	Expenses int
+}
+
+// getPayees loads every payee for the payees modal, by name
+func (h *Handler) getPayees() ([]PayeeItem, error) {
+	var list []models.Payee
+	if err := h.db.Preload("Aliases", func(db *gorm.DB) *gorm.DB { return db.Order("pattern") }).
+		Order("name").Find(&list).Error; err != nil {
+		return nil, err
+	}
+
+	var counts []struct {
+		PayeeID uint
+		Count   int
+	}
+	if err := h.db.Model(&models.Expense{}).
+		Select("payee_id, COUNT(*) AS count").
+		Where("payee_id IS NOT NULL").
+		Group("payee_id").
+		Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+	byPayee := make(map[uint]int, len(counts))
+	for _, c := range counts {
+		byPayee[c.PayeeID] = c.Count
+	}
+
+	items := make([]PayeeItem, len(list))
+	for i, p := range list {
+		items[i] = PayeeItem{Payee: p, Expenses: byPayee[p.ID]}
+	}
+	return items, nil
+}
+
+// ListPayees handles GET /payees
+func (h *Handler) ListPayees(w http.ResponseWriter, r *http.Request) {
+	items, err := h.getPayees()
+	if err != nil {
+		log.Printf("Error querying payees: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	data := struct {
+		Payees []PayeeItem
+	}{
+		Payees: items,
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := h.templates.ExecuteTemplate(w, "payee-modal", data); err != nil {
+		log.Printf("Error executing template: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// CreatePayee handles POST /payees. Existing expenses matching the new payee
+// are linked to it.
+func (h *Handler) CreatePayee(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		return
+	}
+
+	name := strings.TrimSpace(r.FormValue("name"))
+	validationErrors := validation.ValidatePayee(name)
+	patterns, aliasErrors := validation.ValidatePayeeAliases(r.FormValue("aliases"))
+	validationErrors = append(validationErrors, aliasErrors...)
+	if validationErrors.HasErrors() {
+		h.writePayeeErrors(w, validationErrors)
+		return
+	}
+
+	var existing models.Payee
+	if err := h.db.Where("LOWER(name) = LOWER(?)", name).First(&existing).Error; err == nil {
+		h.writePayeeErrors(w, validation.ValidationErrors{{Field: "name", Message: "A payee with this name already exists"}})
+		return
+	}
+	if taken := h.takenPatterns(patterns); len(taken) > 0 {
+		h.writePayeeErrors(w, taken)
+		return
+	}
+
+	payee := models.Payee{Name: name}
+	for _, pattern := range patterns {
+		payee.Aliases = append(payee.Aliases, models.PayeeAlias{Pattern: pattern})
+	}
+
+	if err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&payee).Error; err != nil {
+			return err
+		}
+		_, err := payees.Apply(tx)
+		return err
+	}); err != nil {
+		log.Printf("Error creating payee: %v", err)
+		http.Error(w, "Failed to create payee", http.StatusInternalServerError)
+		return
+	}
+
+	h.renderPayeeList(w, http.StatusCreated)
+}
+
+// AddPayeeAlias handles POST /payees/{id}/aliases. Existing expenses
+// matching the new aliases are linked to the payee.
+func (h *Handler) AddPayeeAlias(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		return
+	}
+
+	var payee models.Payee
+	if err := h.db.First(&payee, id).Error; err != nil {
+		http.Error(w, "Payee not found", http.StatusNotFound)
+		return
+	}
+
+	patterns, validationErrors := validation.ValidatePayeeAliases(r.FormValue("aliases"))
+	if validationErrors.HasErrors() {
+		h.writePayeeErrors(w, validationErrors)
+		return
+	}
+	if taken := h.takenPatterns(patterns); len(taken) > 0 {
+		h.writePayeeErrors(w, taken)
+		return
+	}
+
+	if err := h.db.Transaction(func(tx *gorm.DB) error {
+		for _, pattern := range patterns {
+			if err := tx.Create(&models.PayeeAlias{PayeeID: payee.ID, Pattern: pattern}).Error; err != nil {
+				return err
+			}
+		}
+		_, err := payees.Apply(tx)
+		return err
+	}); err != nil {
+		log.Printf("Error adding payee alias: %v", err)
+		http.Error(w, "Failed to add alias", http.StatusInternalServerError)
+		return
+	}
+
+	h.renderPayeeList(w, http.StatusOK)
+}
+
+// DeletePayeeAlias handles DELETE /payee-aliases/{id}. The payee's expenses
+// are matched again, so ones only that alias matched are unlinked.
+func (h *Handler) DeletePayeeAlias(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var alias models.PayeeAlias
+	if err := h.db.First(&alias, id).Error; err != nil {
+		http.Error(w, "Alias not found", http.StatusNotFound)
+		return
+	}
+
+	if err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&alias).Error; err != nil {
+			return err
+		}
+		return payees.Relink(tx, alias.PayeeID)
+	}); err != nil {
+		log.Printf("Error deleting payee alias: %v", err)
+		http.Error(w, "Failed to delete alias", http.StatusInternalServerError)
+		return
+	}
+
+	h.renderPayeeList(w, http.StatusOK)
+}
+
+// MergePayee handles POST /payees/{id}/merge, folding the payee into the
+// one given by the "into" form value
+func (h *Handler) MergePayee(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	intoID, err := strconv.ParseUint(r.FormValue("into"), 10, 32)
+	if err != nil {
+		h.writePayeeErrors(w, validation.ValidationErrors{{Field: "into", Message: "Choose a payee to merge into"}})
+		return
+	}
+
+	if err := h.db.Transaction(func(tx *gorm.DB) error {
+		return payees.Merge(tx, uint(id), uint(intoID))
+	}); err != nil {
+		switch {
+		case errors.Is(err, payees.ErrSamePayee):
+			h.writePayeeErrors(w, validation.ValidationErrors{{Field: "into", Message: "Choose a different payee to merge into"}})
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			http.Error(w, "Payee not found", http.StatusNotFound)
+		default:
+			log.Printf("Error merging payees: %v", err)
+			http.Error(w, "Failed to merge payees", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	h.renderPayeeList(w, http.StatusOK)
+}
+
+// DeletePayee handles DELETE /payees/{id}. Its expenses are kept but
+// unlinked.
+func (h *Handler) DeletePayee(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var payee models.Payee
+	if err := h.db.First(&payee, id).Error; err != nil {
+		http.Error(w, "Payee not found", http.StatusNotFound)
+		return
+	}
+
+	if err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&models.Expense{}).Where("payee_id = ?", payee.ID).Update("payee_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&payee).Error
+	}); err != nil {
+		log.Printf("Error deleting payee: %v", err)
+		http.Error(w, "Failed to delete payee", http.StatusInternalServerError)
+		return
+	}
+
+	h.renderPayeeList(w, http.StatusOK)
+}
+
+// takenPatterns reports aliases that already belong to a payee
+func (h *Handler) takenPatterns(patterns []string) validation.ValidationErrors {
+	var errs validation.ValidationErrors
+	if len(patterns) == 0 {
+		return errs
+	}
+
+	var taken []models.PayeeAlias
+	h.db.Where("pattern IN ?", patterns).Find(&taken)
+	for _, alias := range taken {
+		errs = append(errs, validation.ValidationError{
+			Field:   "aliases",
+			Message: fmt.Sprintf("Alias %q is already used by another payee", alias.Pattern),
+		})
+	}
+	return errs
+}
+
+// renderPayeeList renders the payee list after a change
+func (h *Handler) renderPayeeList(w http.ResponseWriter, status int) {
+	items, err := h.getPayees()
+	if err != nil {
+		log.Printf("Error querying payees: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	data := struct {
+		Payees []PayeeItem
+	}{
+		Payees: items,
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := h.templates.ExecuteTemplate(w, "payee-list", data); err != nil {
+		log.Printf("Error executing template: %v", err)
+	}
+}
+
+// writePayeeErrors shows validation errors above the payee list
+func (h *Handler) writePayeeErrors(w http.ResponseWriter, validationErrors validation.ValidationErrors) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("HX-Retarget", "#payee-errors")
+	w.Header().Set("HX-Reswap", "innerHTML")
+	w.WriteHeader(http.StatusBadRequest)
+	h.templates.ExecuteTemplate(w, "validation-errors", validationErrors)
+}
@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/g-linville/budgeting/internal/audit"
+	"github.com/g-linville/budgeting/internal/models"
+	"github.com/g-linville/budgeting/internal/validation"
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+)
+
+// RefundsData holds the data for an expense's refunds modal
+type RefundsData struct {
+	Expense   models.Expense
+	Refunds   []models.Refund
+	Refunded  int    // Minor units refunded so far
+	Remaining int    // Minor units that can still be refunded
+	Today     string // Default date for new refunds
+}
+
+// refundedAmount sums the refunds recorded against an expense
+func refundedAmount(db *gorm.DB, expenseID uint) (int, error) {
+	var total int
+	err := db.Model(&models.Refund{}).
+		Where("expense_id = ?", expenseID).
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&total).Error
+	return total, err
+}
+
+// getRefundsData loads an expense and its refunds
+func (h *Handler) getRefundsData(expenseID uint64) (RefundsData, error) {
+	var expense models.Expense
+	if err := h.db.First(&expense, expenseID).Error; err != nil {
+		return RefundsData{}, err
+	}
+
+	var list []models.Refund
+	if err := h.db.Where("expense_id = ?", expense.ID).Order("refund_date, id").Find(&list).Error; err != nil {
+		return RefundsData{}, err
+	}
+
+	refunded := 0
+	for _, refund := range list {
+		refunded += refund.Amount
+	}
+
+	return RefundsData{
+		Expense:   expense,
+		Refunds:   list,
+		Refunded:  refunded,
+		Remaining: expense.Amount - refunded,
+		Today:     time.Now().Format("2006-01-02"),
+	}, nil
+}
+
+// ListRefunds handles GET /expenses/{id}/refunds
+func (h *Handler) ListRefunds(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	data, err := h.getRefundsData(id)
+	if err != nil {
+		http.Error(w, "Expense not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := h.templates.ExecuteTemplate(w, "refunds-modal", data); err != nil {
+		log.Printf("Error executing template: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// CreateRefund handles POST /expenses/{id}/refunds
+func (h *Handler) CreateRefund(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		return
+	}
+
+	var expense models.Expense
+	if err := h.db.First(&expense, id).Error; err != nil {
+		http.Error(w, "Expense not found", http.StatusNotFound)
+		return
+	}
+
+	refunded, err := refundedAmount(h.db, expense.ID)
+	if err != nil {
+		log.Printf("Error summing refunds: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	kind := r.FormValue("kind")
+	amountCents, date, validationErrors := validation.ValidateRefund(kind, r.FormValue("amount"), r.FormValue("refund_date"), expense.Amount-refunded, h.base, h.locale)
+	if validationErrors.HasErrors() {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("HX-Retarget", "#refund-errors")
+		w.Header().Set("HX-Reswap", "innerHTML")
+		w.WriteHeader(http.StatusBadRequest)
+		h.templates.ExecuteTemplate(w, "validation-errors", validationErrors)
+		return
+	}
+
+	refund := models.Refund{
+		ExpenseID:  expense.ID,
+		Kind:       kind,
+		Amount:     amountCents,
+		RefundDate: date,
+		Notes:      r.FormValue("notes"),
+	}
+
+	if err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&refund).Error; err != nil {
+			return err
+		}
+		return audit.Record(tx, audit.EntityRefund, refund.ID, audit.ActionCreate, "")
+	}); err != nil {
+		log.Printf("Error creating refund: %v", err)
+		http.Error(w, "Failed to save refund", http.StatusInternalServerError)
+		return
+	}
+
+	h.renderRefundList(w, id)
+}
+
+// DeleteRefund handles DELETE /refunds/{id}
+func (h *Handler) DeleteRefund(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var refund models.Refund
+	if err := h.db.First(&refund, id).Error; err != nil {
+		http.Error(w, "Refund not found", http.StatusNotFound)
+		return
+	}
+
+	if err := h.db.Transaction(func(tx *gorm.DB) error {
+		before, err := audit.Snapshot(tx, audit.EntityRefund, refund.ID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Delete(&refund).Error; err != nil {
+			return err
+		}
+		return audit.Record(tx, audit.EntityRefund, refund.ID, audit.ActionDelete, before)
+	}); err != nil {
+		log.Printf("Error deleting refund: %v", err)
+		http.Error(w, "Failed to delete refund", http.StatusInternalServerError)
+		return
+	}
+
+	h.renderRefundList(w, uint64(refund.ExpenseID))
+}
+
+// renderRefundList renders an expense's refund list along with the recent
+// transactions and overview, whose totals may have changed
+func (h *Handler) renderRefundList(w http.ResponseWriter, expenseID uint64) {
+	data, err := h.getRefundsData(expenseID)
+	if err != nil {
+		log.Printf("Error querying refunds: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	refreshData, err := h.getRefreshData()
+	if err != nil {
+		log.Printf("Error getting dashboard data: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := h.templates.ExecuteTemplate(w, "refund-list", data); err != nil {
+		log.Printf("Error executing template: %v", err)
+		return
+	}
+
+	// Render OOB recent transactions and overview stats
+	oobBuf := new(bytes.Buffer)
+	if err := h.templates.ExecuteTemplate(oobBuf, "recent-transactions-oob", refreshData); err != nil {
+		log.Printf("Error executing OOB template: %v", err)
+		return
+	}
+	if err := h.templates.ExecuteTemplate(oobBuf, "overview-stats-oob", refreshData); err != nil {
+		log.Printf("Error executing OOB template: %v", err)
+		return
+	}
+	w.Write(oobBuf.Bytes())
+}
@@ -0,0 +1,604 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/g-linville/budgeting/internal/currency"
+	"github.com/g-linville/budgeting/internal/database"
+	"github.com/g-linville/budgeting/internal/models"
+)
+
+// ReportsPageData holds all data needed for the reports page
+type ReportsPageData struct {
+	From          string // YYYY-MM-DD, inclusive
+	To            string // YYYY-MM-DD, inclusive
+	Spending      []CategoryTotal
+	TotalSpending currency.Money
+
+	// Income per income category, mirroring Spending
+	Income      []CategoryTotal
+	TotalIncome currency.Money
+
+	// Set when drilling down into a category's subcategories: its ID, the
+	// categories from the top level down to it, and whether it is an income
+	// category
+	Category    uint
+	Breadcrumb  []CategoryNode
+	IncomeDrill bool
+	TagTotals   []TagTotal
+	PayeeTotals []PayeeTotal
+
+	// Outstanding reimbursements are listed regardless of the period
+	Outstanding      []OutstandingReimbursement
+	TotalOutstanding currency.Money
+}
+
+// CategoryTotal is the spending in one category over a period
+type CategoryTotal struct {
+	CategoryID *uint
+	Category   *string // nil for uncategorized
+	Color      *string
+	Amount     currency.Money
+	Count      int // Number of expenses and split lines
+	Percent    float64
+
+	// Set by rollupCategoryTotals
+	HasChildren bool // Spending includes subcategories, which can be drilled into
+	Direct      bool // Spending in the drilled-into category itself
+}
+
+// TagTotal is the spending and income recorded under a tag over a period
+type TagTotal struct {
+	Tag      string
+	Expenses currency.Money
+	Income   currency.Money
+	Count    int // Number of tagged transactions
+}
+
+// PayeeTotal is the spending with one payee over a period
+type PayeeTotal struct {
+	PayeeID uint
+	Payee   string
+	Amount  currency.Money
+	Count   int // Number of expenses
+}
+
+// OutstandingReimbursement is a reimbursable expense that has not been fully
+// paid back
+type OutstandingReimbursement struct {
+	ID          uint
+	Name        string
+	Date        string
+	Amount      currency.Money
+	Received    currency.Money // Refunded or reimbursed so far
+	Outstanding currency.Money
+}
+
+// categoryAmount is an amount spent or received in a category as summed in
+// SQL, before it is added into a CategoryTotal
+type categoryAmount struct {
+	CategoryID *uint
+	Amount     int // Minor units of the base currency
+	Count      int
+}
+
+// parseReportPeriod reads the from/to query parameters, defaulting to the
+// current month
+func parseReportPeriod(query url.Values) (from, to time.Time) {
+	now := time.Now()
+	from = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local)
+	to = from.AddDate(0, 1, -1)
+
+	if parsed, err := time.ParseInLocation("2006-01-02", query.Get("from"), time.Local); err == nil {
+		from = parsed
+	}
+	if parsed, err := time.ParseInLocation("2006-01-02", query.Get("to"), time.Local); err == nil {
+		to = parsed
+	}
+	if to.Before(from) {
+		from, to = to, from
+	}
+	return from, to
+}
+
+// getCategoryTotals sums spending per category between start (inclusive)
+// and end (exclusive), without rolling subcategories up into their parents.
+// Split expenses contribute their lines to each line's category rather than
+// the expense as a whole. Refunds dated in the period are netted against the
+// category of the refunded expense, or shared across the lines of a split
+// expense by splitRefundShares.
+func (h *Handler) getCategoryTotals(start, end string) ([]CategoryTotal, error) {
+	var amounts []categoryAmount
+	err := h.db.Raw(`
+		SELECT x.category_id, SUM(x.amount) AS amount, SUM(x.n) AS count
+		FROM (
+			SELECT e.category_id, e.amount, 1 AS n
+			FROM expenses e
+			WHERE e.deleted_at IS NULL AND e.expense_date >= ? AND e.expense_date < ?
+				AND NOT EXISTS (SELECT 1 FROM expense_splits s WHERE s.expense_id = e.id)
+			UNION ALL
+			SELECT s.category_id, s.amount, 1 AS n
+			FROM expense_splits s
+			JOIN expenses e ON e.id = s.expense_id
+			WHERE e.deleted_at IS NULL AND e.expense_date >= ? AND e.expense_date < ?
+			UNION ALL
+			SELECT e.category_id, -r.amount, 0 AS n
+			FROM refunds r
+			JOIN expenses e ON e.id = r.expense_id
+			WHERE e.deleted_at IS NULL AND r.refund_date >= ? AND r.refund_date < ?
+				AND NOT EXISTS (SELECT 1 FROM expense_splits s WHERE s.expense_id = e.id)
+		) x
+		GROUP BY x.category_id`,
+		start, end, start, end, start, end).Scan(&amounts).Error
+	if err != nil {
+		return nil, err
+	}
+
+	shares, err := h.splitRefundShares(start, end)
+	if err != nil {
+		return nil, err
+	}
+	return h.categoryTotals(append(amounts, shares...))
+}
+
+// splitRefundShares shares each refund dated between start (inclusive) and
+// end (exclusive) on a split expense across its lines in proportion to their
+// amounts, as negative amounts that add up to the refund exactly
+func (h *Handler) splitRefundShares(start, end string) ([]categoryAmount, error) {
+	var lines []struct {
+		RefundID   uint
+		Refund     int
+		CategoryID *uint
+		Amount     int
+	}
+	err := h.db.Raw(`
+		SELECT r.id AS refund_id, r.amount AS refund, s.category_id, s.amount
+		FROM refunds r
+		JOIN expenses e ON e.id = r.expense_id
+		JOIN expense_splits s ON s.expense_id = e.id
+		WHERE e.deleted_at IS NULL AND r.refund_date >= ? AND r.refund_date < ?
+		ORDER BY r.id, s.id`,
+		start, end).Scan(&lines).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var shares []categoryAmount
+	for first := 0; first < len(lines); {
+		next := first
+		for next < len(lines) && lines[next].RefundID == lines[first].RefundID {
+			next++
+		}
+		refundLines := lines[first:next]
+		weights := make([]int, len(refundLines))
+		for i, line := range refundLines {
+			weights[i] = line.Amount
+		}
+		parts, err := currency.New(-refundLines[0].Refund, h.base).Allocate(weights)
+		if err != nil {
+			return nil, fmt.Errorf("refund %d: %w", refundLines[0].RefundID, err)
+		}
+		for i, part := range parts {
+			shares = append(shares, categoryAmount{CategoryID: refundLines[i].CategoryID, Amount: part.Minor})
+		}
+		first = next
+	}
+	return shares, nil
+}
+
+// getIncomeCategoryTotals sums income per category between start
+// (inclusive) and end (exclusive), without rolling subcategories up into
+// their parents
+func (h *Handler) getIncomeCategoryTotals(start, end string) ([]CategoryTotal, error) {
+	var amounts []categoryAmount
+	err := h.db.Raw(`
+		SELECT i.category_id, SUM(i.amount) AS amount, COUNT(*) AS count
+		FROM incomes i
+		WHERE i.deleted_at IS NULL AND i.income_date >= ? AND i.income_date < ?
+		GROUP BY i.category_id`,
+		start, end).Scan(&amounts).Error
+	if err != nil {
+		return nil, err
+	}
+	return h.categoryTotals(amounts)
+}
+
+// categoryTotals sums amounts per category in the base currency, largest
+// first. Amounts in a category that is in the trash count as uncategorized.
+func (h *Handler) categoryTotals(amounts []categoryAmount) ([]CategoryTotal, error) {
+	var categories []models.Category
+	if err := h.db.Select("id", "name", "color").Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]models.Category, len(categories))
+	for _, c := range categories {
+		byID[c.ID] = c
+	}
+
+	var totals []CategoryTotal
+	index := make(map[uint]int) // Row of each category; 0 is uncategorized
+	for _, a := range amounts {
+		var key uint
+		if a.CategoryID != nil {
+			if _, ok := byID[*a.CategoryID]; ok {
+				key = *a.CategoryID
+			}
+		}
+		i, ok := index[key]
+		if !ok {
+			total := CategoryTotal{Amount: currency.New(0, h.base)}
+			if key != 0 {
+				c := byID[key]
+				total.CategoryID, total.Category, total.Color = &c.ID, &c.Name, &c.Color
+			}
+			i = len(totals)
+			index[key] = i
+			totals = append(totals, total)
+		}
+		sum, err := totals[i].Amount.Add(currency.New(a.Amount, h.base))
+		if err != nil {
+			return nil, err
+		}
+		totals[i].Amount = sum
+		totals[i].Count += a.Count
+	}
+	sortCategoryTotals(totals)
+	return totals, nil
+}
+
+// sortCategoryTotals orders totals largest first, then by name, with
+// uncategorized last among equals
+func sortCategoryTotals(totals []CategoryTotal) {
+	sort.SliceStable(totals, func(i, j int) bool {
+		if totals[i].Amount.Minor != totals[j].Amount.Minor {
+			return totals[i].Amount.Minor > totals[j].Amount.Minor
+		}
+		if totals[i].Category == nil || totals[j].Category == nil {
+			return totals[j].Category == nil && totals[i].Category != nil
+		}
+		return *totals[i].Category < *totals[j].Category
+	})
+}
+
+// rollupCategoryTotals rolls per-category totals up the category tree. At the
+// top level (parentID 0) each top-level category includes all of its
+// descendants; otherwise the rows are the children of parentID plus the
+// spending recorded against parentID itself. Percentages are of the level's
+// total, which is returned with the rows in the base currency.
+func rollupCategoryTotals(totals []CategoryTotal, tree []CategoryNode, parentID uint, base currency.Currency) ([]CategoryTotal, currency.Money, error) {
+	nodes := make(map[uint]CategoryNode, len(tree))
+	for _, node := range tree {
+		nodes[node.ID] = node
+	}
+
+	var rows []CategoryTotal
+	index := make(map[uint]int)
+	uncategorized := -1
+	for _, t := range totals {
+		if t.CategoryID == nil {
+			if parentID != 0 {
+				continue
+			}
+			if uncategorized < 0 {
+				uncategorized = len(rows)
+				rows = append(rows, CategoryTotal{Amount: currency.New(0, base)})
+			}
+			sum, err := rows[uncategorized].Amount.Add(t.Amount)
+			if err != nil {
+				return nil, currency.Money{}, err
+			}
+			rows[uncategorized].Amount = sum
+			rows[uncategorized].Count += t.Count
+			continue
+		}
+
+		// Find which row at this level the category falls under
+		node, ok := nodes[*t.CategoryID]
+		if !ok {
+			continue
+		}
+		chain := append(append([]uint{}, node.ancestors...), node.ID)
+		var rowID uint
+		direct := false
+		if parentID == 0 {
+			rowID = chain[0]
+		} else {
+			for i, id := range chain {
+				if id != parentID {
+					continue
+				}
+				if i == len(chain)-1 {
+					rowID, direct = id, true
+				} else {
+					rowID = chain[i+1]
+				}
+				break
+			}
+			if rowID == 0 {
+				continue
+			}
+		}
+
+		i, ok := index[rowID]
+		if !ok {
+			row := nodes[rowID]
+			id, name, color := row.ID, row.Name, row.Color
+			i = len(rows)
+			index[rowID] = i
+			rows = append(rows, CategoryTotal{CategoryID: &id, Category: &name, Color: &color, Amount: currency.New(0, base), Direct: direct})
+		}
+		sum, err := rows[i].Amount.Add(t.Amount)
+		if err != nil {
+			return nil, currency.Money{}, err
+		}
+		rows[i].Amount = sum
+		rows[i].Count += t.Count
+		if node.ID != rowID {
+			rows[i].HasChildren = true
+		}
+	}
+
+	sortCategoryTotals(rows)
+
+	total := currency.New(0, base)
+	for _, row := range rows {
+		var err error
+		if total, err = total.Add(row.Amount); err != nil {
+			return nil, currency.Money{}, err
+		}
+	}
+	for i := range rows {
+		if total.IsPositive() {
+			rows[i].Percent = float64(rows[i].Amount.Minor) * 100 / float64(total.Minor)
+		}
+	}
+	return rows, total, nil
+}
+
+// getTagTotals sums tagged transactions per tag between start (inclusive)
+// and end (exclusive). A transaction with several tags counts toward each.
+// Refunds dated in the period reduce the expenses of their expense's tags.
+func (h *Handler) getTagTotals(start, end string) ([]TagTotal, error) {
+	var rows []struct {
+		Tag      string
+		Expenses int
+		Income   int
+		Count    int
+	}
+	err := h.db.Raw(`
+		SELECT tg.name AS tag, SUM(x.expenses) AS expenses, SUM(x.income) AS income, SUM(x.n) AS count
+		FROM (
+			SELECT et.tag_id, e.amount AS expenses, 0 AS income, 1 AS n
+			FROM expense_tags et
+			JOIN expenses e ON e.id = et.expense_id
+			WHERE e.deleted_at IS NULL AND e.expense_date >= ? AND e.expense_date < ?
+			UNION ALL
+			SELECT it.tag_id, 0 AS expenses, i.amount AS income, 1 AS n
+			FROM income_tags it
+			JOIN incomes i ON i.id = it.income_id
+			WHERE i.deleted_at IS NULL AND i.income_date >= ? AND i.income_date < ?
+			UNION ALL
+			SELECT et.tag_id, -r.amount AS expenses, 0 AS income, 0 AS n
+			FROM refunds r
+			JOIN expenses e ON e.id = r.expense_id
+			JOIN expense_tags et ON et.expense_id = e.id
+			WHERE e.deleted_at IS NULL AND r.refund_date >= ? AND r.refund_date < ?
+		) x
+		JOIN tags tg ON tg.id = x.tag_id
+		GROUP BY tg.name
+		ORDER BY SUM(x.expenses) DESC, tg.name`,
+		start, end, start, end, start, end).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	totals := make([]TagTotal, len(rows))
+	for i, row := range rows {
+		totals[i] = TagTotal{
+			Tag:      row.Tag,
+			Expenses: currency.New(row.Expenses, h.base),
+			Income:   currency.New(row.Income, h.base),
+			Count:    row.Count,
+		}
+	}
+	return totals, nil
+}
+
+// getPayeeTotals sums spending per payee between start (inclusive) and end
+// (exclusive), largest first, netting refunds as getCategoryTotals does.
+// Expenses not linked to a payee are left out.
+func (h *Handler) getPayeeTotals(start, end string) ([]PayeeTotal, error) {
+	var rows []struct {
+		PayeeID uint
+		Payee   string
+		Amount  int
+		Count   int
+	}
+	err := h.db.Raw(`
+		SELECT p.id AS payee_id, p.name AS payee, SUM(x.amount) AS amount, SUM(x.n) AS count
+		FROM (
+			SELECT e.payee_id, e.amount, 1 AS n
+			FROM expenses e
+			WHERE e.deleted_at IS NULL AND e.expense_date >= ? AND e.expense_date < ?
+			UNION ALL
+			SELECT e.payee_id, -r.amount, 0 AS n
+			FROM refunds r
+			JOIN expenses e ON e.id = r.expense_id
+			WHERE e.deleted_at IS NULL AND r.refund_date >= ? AND r.refund_date < ?
+		) x
+		JOIN payees p ON p.id = x.payee_id
+		GROUP BY p.id, p.name
+		ORDER BY SUM(x.amount) DESC, p.name`,
+		start, end, start, end).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	totals := make([]PayeeTotal, len(rows))
+	for i, row := range rows {
+		totals[i] = PayeeTotal{PayeeID: row.PayeeID, Payee: row.Payee, Amount: currency.New(row.Amount, h.base), Count: row.Count}
+	}
+	return totals, nil
+}
+
+// getOutstandingReimbursements lists reimbursable expenses that have not yet
+// been fully paid back, oldest first
+func (h *Handler) getOutstandingReimbursements() ([]OutstandingReimbursement, currency.Money, error) {
+	var rows []struct {
+		ID          uint
+		Name        string
+		Date        string
+		Amount      int
+		Received    int
+		Outstanding int
+	}
+	err := h.db.Raw(fmt.Sprintf(`
+		SELECT id, name, date, amount, received, amount - received AS outstanding
+		FROM (
+			SELECT e.id, e.name, %s AS date, e.amount,
+				(SELECT COALESCE(SUM(r.amount), 0) FROM refunds r WHERE r.expense_id = e.id) AS received
+			FROM expenses e
+			WHERE e.deleted_at IS NULL AND e.reimbursable
+		) x
+		WHERE amount > received
+		ORDER BY date, id`,
+		database.DateString(h.db, "e.expense_date"))).Scan(&rows).Error
+	if err != nil {
+		return nil, currency.Money{}, err
+	}
+
+	list := make([]OutstandingReimbursement, len(rows))
+	amounts := make([]int, len(rows))
+	for i, row := range rows {
+		list[i] = OutstandingReimbursement{
+			ID:          row.ID,
+			Name:        row.Name,
+			Date:        row.Date,
+			Amount:      currency.New(row.Amount, h.base),
+			Received:    currency.New(row.Received, h.base),
+			Outstanding: currency.New(row.Outstanding, h.base),
+		}
+		amounts[i] = row.Outstanding
+	}
+	total, err := currency.Sum(h.base, amounts...)
+	return list, total, err
+}
+
+// Reports handles GET /reports. Full page loads render the whole page; HTMX
+// requests (from changing the period) render only the reports.
+func (h *Handler) Reports(w http.ResponseWriter, r *http.Request) {
+	from, to := parseReportPeriod(r.URL.Query())
+	// Dates are compared as YYYY-MM-DD strings over a half-open range
+	start, end := from.Format("2006-01-02"), to.AddDate(0, 0, 1).Format("2006-01-02")
+
+	categoryTotals, err := h.getCategoryTotals(start, end)
+	if err != nil {
+		log.Printf("Error calculating category totals: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	incomeTotals, err := h.getIncomeCategoryTotals(start, end)
+	if err != nil {
+		log.Printf("Error calculating income totals: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	categoryData, err := h.getCategoryListData()
+	if err != nil {
+		log.Printf("Error querying categories: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	// Drill down into a category's subcategories with ?category=ID
+	var breadcrumb []CategoryNode
+	var drillID uint
+	incomeDrill := false
+	if id, err := strconv.ParseUint(r.URL.Query().Get("category"), 10, 32); err == nil {
+		for _, node := range categoryData.Tree {
+			if node.ID != uint(id) {
+				continue
+			}
+			drillID, incomeDrill = node.ID, node.IsIncome()
+			for _, ancestor := range categoryData.Tree {
+				if node.Under(ancestor.ID) {
+					breadcrumb = append(breadcrumb, ancestor)
+				}
+			}
+			break
+		}
+	}
+	spendingDrill, incomeDrillID := drillID, uint(0)
+	if incomeDrill {
+		spendingDrill, incomeDrillID = 0, drillID
+	}
+	spending, totalSpending, err := rollupCategoryTotals(categoryTotals, categoryData.Tree, spendingDrill, h.base)
+	if err != nil {
+		log.Printf("Error totaling spending: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	income, totalIncome, err := rollupCategoryTotals(incomeTotals, categoryData.Tree, incomeDrillID, h.base)
+	if err != nil {
+		log.Printf("Error totaling income: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	tagTotals, err := h.getTagTotals(start, end)
+	if err != nil {
+		log.Printf("Error calculating tag totals: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	payeeTotals, err := h.getPayeeTotals(start, end)
+	if err != nil {
+		log.Printf("Error calculating payee totals: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	outstanding, totalOutstanding, err := h.getOutstandingReimbursements()
+	if err != nil {
+		log.Printf("Error querying outstanding reimbursements: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	data := ReportsPageData{
+		From:             from.Format("2006-01-02"),
+		To:               to.Format("2006-01-02"),
+		Spending:         spending,
+		TotalSpending:    totalSpending,
+		Income:           income,
+		TotalIncome:      totalIncome,
+		Category:         drillID,
+		Breadcrumb:       breadcrumb,
+		IncomeDrill:      incomeDrill,
+		TagTotals:        tagTotals,
+		PayeeTotals:      payeeTotals,
+		Outstanding:      outstanding,
+		TotalOutstanding: totalOutstanding,
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if r.Header.Get("HX-Request") == "true" && r.Header.Get("HX-History-Restore-Request") != "true" {
+		if err := h.templates.ExecuteTemplate(w, "reports-results", data); err != nil {
+			log.Printf("Error executing template: %v", err)
+		}
+		return
+	}
+	if err := h.pages["reports"].ExecuteTemplate(w, "layout.html", data); err != nil {
+		log.Printf("Error executing template: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/g-linville/budgeting/internal/database/dbtest"
+	"github.com/g-linville/budgeting/internal/models"
+	"gorm.io/gorm"
+)
+
+// A refund on a split expense is shared across its lines without losing a
+// minor unit, so the category totals add up to the spending less the refund
+func TestCategoryTotalsShareSplitRefunds(t *testing.T) {
+	dbtest.RunMigrated(t, func(t *testing.T, db *gorm.DB) {
+		create := func(value interface{}) {
+			t.Helper()
+			if err := db.Create(value).Error; err != nil {
+				t.Fatal(err)
+			}
+		}
+		day := time.Date(2024, 3, 5, 0, 0, 0, 0, time.Local)
+
+		groceries := models.Category{Name: "Groceries", Kind: models.CategoryKindExpense}
+		household := models.Category{Name: "Household", Kind: models.CategoryKindExpense}
+		pharmacy := models.Category{Name: "Pharmacy", Kind: models.CategoryKindExpense}
+		trashed := models.Category{Name: "Old", Kind: models.CategoryKindExpense}
+		for _, c := range []*models.Category{&groceries, &household, &pharmacy, &trashed} {
+			create(c)
+		}
+
+		shop := models.Expense{Name: "Supermarket", Amount: 1000, ExpenseDate: day, Splits: []models.ExpenseSplit{
+			{CategoryID: &groceries.ID, Amount: 333},
+			{CategoryID: &household.ID, Amount: 333},
+			{CategoryID: &pharmacy.ID, Amount: 334},
+		}}
+		create(&shop)
+		create(&models.Expense{Name: "Lamp", Amount: 500, ExpenseDate: day, CategoryID: &trashed.ID})
+		create(&[]models.Refund{
+			{ExpenseID: shop.ID, Kind: models.RefundKindRefund, Amount: 100, RefundDate: day},
+			{ExpenseID: shop.ID, Kind: models.RefundKindRefund, Amount: 7, RefundDate: day},
+		})
+		if err := db.Delete(&trashed).Error; err != nil {
+			t.Fatal(err)
+		}
+
+		h := testHandler(t, db)
+		totals, err := h.getCategoryTotals("2024-03-01", "2024-04-01")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := make(map[string]int)
+		sum := 0
+		for _, total := range totals {
+			name := "Uncategorized"
+			if total.Category != nil {
+				name = *total.Category
+			}
+			got[name] = total.Amount.Minor
+			sum += total.Amount.Minor
+		}
+		if want := 1000 + 500 - 100 - 7; sum != want {
+			t.Errorf("totals add up to %d, want %d: %v", sum, want, got)
+		}
+		// 100 is shared 33, 33, 34 and 7 is shared 2, 2, 3
+		want := map[string]int{"Groceries": 298, "Household": 298, "Pharmacy": 297, "Uncategorized": 500}
+		for name, amount := range want {
+			if got[name] != amount {
+				t.Errorf("%s = %d, want %d", name, got[name], amount)
+			}
+		}
+		if len(got) != len(want) {
+			t.Errorf("totals = %v, want %v", got, want)
+		}
+	})
+}
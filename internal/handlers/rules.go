@@ -0,0 +1,338 @@
+package handlers
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/g-linville/budgeting/internal/audit"
+	"github.com/g-linville/budgeting/internal/models"
+	"github.com/g-linville/budgeting/internal/rules"
+	"github.com/g-linville/budgeting/internal/validation"
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+)
+
+// rulePreviewLimit caps how many changes a dry run lists
+const rulePreviewLimit = 200
+
+// RulesData holds the data for the rules modal
+type RulesData struct {
+	Rules      []models.CategoryRule
+	Categories []CategoryNode
+	Payees     []models.Payee
+	Weekdays   []string
+}
+
+// RuleChangeRow is a change from a dry run, prepared for display
+type RuleChangeRow struct {
+	rules.Change
+	From string // Current category name
+	To   string // New category name; "" when unchanged
+}
+
+// RulePreviewData holds the result of a dry run or of applying the rules
+type RulePreviewData struct {
+	Changes   []RuleChangeRow
+	Total     int  // Number of expenses that would change
+	Overwrite bool // Whether categorized expenses are recategorized
+	Applied   int  // Number of expenses changed, after applying
+	Done      bool
+}
+
+var weekdayNames = []string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"}
+
+// getRulesData loads the rules and the choices for the rule form
+func (h *Handler) getRulesData() (RulesData, error) {
+	var list []models.CategoryRule
+	// Trashed categories are loaded too, to show that the rule no longer sets them
+	if err := h.db.Preload("Category", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Preload("Payee").Order("priority, id").Find(&list).Error; err != nil {
+		return RulesData{}, err
+	}
+
+	categoryData, err := h.getCategoryListData()
+	if err != nil {
+		return RulesData{}, err
+	}
+
+	var payeeList []models.Payee
+	if err := h.db.Order("name").Find(&payeeList).Error; err != nil {
+		return RulesData{}, err
+	}
+
+	return RulesData{
+		Rules:      list,
+		Categories: categoryData.Tree,
+		Payees:     payeeList,
+		Weekdays:   weekdayNames,
+	}, nil
+}
+
+// ListRules handles GET /rules
+func (h *Handler) ListRules(w http.ResponseWriter, r *http.Request) {
+	data, err := h.getRulesData()
+	if err != nil {
+		log.Printf("Error querying rules: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := h.templates.ExecuteTemplate(w, "rules-modal", data); err != nil {
+		log.Printf("Error executing template: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// CreateRule handles POST /rules
+func (h *Handler) CreateRule(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		return
+	}
+
+	// Parse payee and category IDs (optional)
+	var payeeID, categoryID *uint
+	if id, err := strconv.ParseUint(r.FormValue("payee_id"), 10, 32); err == nil {
+		payeeIDUint := uint(id)
+		payeeID = &payeeIDUint
+	}
+	if id, err := strconv.ParseUint(r.FormValue("category_id"), 10, 32); err == nil {
+		categoryIDUint := uint(id)
+		categoryID = &categoryIDUint
+	}
+
+	input, validationErrors := validation.ValidateRule(
+		r.FormValue("name"), r.FormValue("priority"),
+		r.FormValue("name_contains"), r.FormValue("name_regex"),
+		r.FormValue("min_amount"), r.FormValue("max_amount"),
+		r.FormValue("weekday"), r.FormValue("tags"),
+		payeeID != nil, categoryID != nil, h.base, h.locale)
+	categoryErrors, err := h.checkCategory(categoryID, models.CategoryKindExpense, nil, "category_id", "Category")
+	if err != nil {
+		log.Printf("Error checking category: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	validationErrors = append(validationErrors, categoryErrors...)
+	if validationErrors.HasErrors() {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("HX-Retarget", "#rule-errors")
+		w.Header().Set("HX-Reswap", "innerHTML")
+		w.WriteHeader(http.StatusBadRequest)
+		h.templates.ExecuteTemplate(w, "validation-errors", validationErrors)
+		return
+	}
+
+	rule := models.CategoryRule{
+		Name:         input.Name,
+		Priority:     input.Priority,
+		Enabled:      true,
+		NameContains: input.NameContains,
+		NameRegex:    input.NameRegex,
+		MinAmount:    input.MinAmount,
+		MaxAmount:    input.MaxAmount,
+		PayeeID:      payeeID,
+		Weekday:      input.Weekday,
+		CategoryID:   categoryID,
+		Tags:         strings.Join(input.Tags, ","),
+	}
+	if err := h.db.Create(&rule).Error; err != nil {
+		log.Printf("Error creating rule: %v", err)
+		http.Error(w, "Failed to create rule", http.StatusInternalServerError)
+		return
+	}
+
+	h.renderRuleList(w, http.StatusCreated)
+}
+
+// ToggleRule handles POST /rules/{id}/toggle, enabling or disabling a rule
+func (h *Handler) ToggleRule(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var rule models.CategoryRule
+	if err := h.db.First(&rule, id).Error; err != nil {
+		http.Error(w, "Rule not found", http.StatusNotFound)
+		return
+	}
+
+	if err := h.db.Model(&rule).Update("enabled", !rule.Enabled).Error; err != nil {
+		log.Printf("Error updating rule: %v", err)
+		http.Error(w, "Failed to update rule", http.StatusInternalServerError)
+		return
+	}
+
+	h.renderRuleList(w, http.StatusOK)
+}
+
+// DeleteRule handles DELETE /rules/{id}
+func (h *Handler) DeleteRule(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.db.Delete(&models.CategoryRule{}, id).Error; err != nil {
+		log.Printf("Error deleting rule: %v", err)
+		http.Error(w, "Failed to delete rule", http.StatusInternalServerError)
+		return
+	}
+
+	h.renderRuleList(w, http.StatusOK)
+}
+
+// PreviewRules handles GET /rules/preview, a dry run of the rules over
+// existing expenses. With ?overwrite=on categorized expenses are included.
+func (h *Handler) PreviewRules(w http.ResponseWriter, r *http.Request) {
+	overwrite := r.URL.Query().Get("overwrite") == "on"
+	data, err := h.getRulePreviewData(overwrite)
+	if err != nil {
+		log.Printf("Error previewing rules: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := h.templates.ExecuteTemplate(w, "rule-preview", data); err != nil {
+		log.Printf("Error executing template: %v", err)
+	}
+}
+
+// ApplyRules handles POST /rules/apply, re-applying the rules to existing
+// expenses. Each changed expense is recorded in the audit log.
+func (h *Handler) ApplyRules(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		return
+	}
+	overwrite := r.FormValue("overwrite") == "on"
+
+	applied := 0
+	if err := h.db.Transaction(func(tx *gorm.DB) error {
+		engine, err := rules.Load(tx)
+		if err != nil {
+			return err
+		}
+		changes, err := rules.Preview(tx, engine, overwrite)
+		if err != nil {
+			return err
+		}
+
+		for _, change := range changes {
+			before, err := audit.Snapshot(tx, audit.EntityExpense, change.ExpenseID)
+			if err != nil {
+				return err
+			}
+			expense := &models.Expense{ID: change.ExpenseID}
+			if change.ToCategoryID != nil {
+				if err := tx.Model(expense).Update("category_id", *change.ToCategoryID).Error; err != nil {
+					return err
+				}
+			}
+			if len(change.AddTags) > 0 {
+				newTags, err := resolveTags(tx, change.AddTags)
+				if err != nil {
+					return err
+				}
+				if err := tx.Model(expense).Association("Tags").Append(newTags); err != nil {
+					return err
+				}
+			}
+			if err := audit.Record(tx, audit.EntityExpense, change.ExpenseID, audit.ActionUpdate, before); err != nil {
+				return err
+			}
+		}
+		applied = len(changes)
+		return nil
+	}); err != nil {
+		log.Printf("Error applying rules: %v", err)
+		http.Error(w, "Failed to apply rules", http.StatusInternalServerError)
+		return
+	}
+
+	refreshData, err := h.getRefreshData()
+	if err != nil {
+		log.Printf("Error getting dashboard data: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	data := RulePreviewData{Overwrite: overwrite, Applied: applied, Done: true}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := h.templates.ExecuteTemplate(w, "rule-preview", data); err != nil {
+		log.Printf("Error executing template: %v", err)
+		return
+	}
+
+	// Render OOB recent transactions
+	oobBuf := new(bytes.Buffer)
+	if err := h.templates.ExecuteTemplate(oobBuf, "recent-transactions-oob", refreshData); err != nil {
+		log.Printf("Error executing OOB template: %v", err)
+		return
+	}
+	w.Write(oobBuf.Bytes())
+}
+
+// getRulePreviewData runs the rules without saving anything
+func (h *Handler) getRulePreviewData(overwrite bool) (RulePreviewData, error) {
+	engine, err := rules.Load(h.db)
+	if err != nil {
+		return RulePreviewData{}, err
+	}
+	changes, err := rules.Preview(h.db, engine, overwrite)
+	if err != nil {
+		return RulePreviewData{}, err
+	}
+
+	// Trashed categories are named too, since expenses may still refer to them
+	var categories []models.Category
+	if err := h.db.Unscoped().Find(&categories).Error; err != nil {
+		return RulePreviewData{}, err
+	}
+	names := make(map[uint]string, len(categories))
+	for _, c := range categories {
+		names[c.ID] = c.Name
+	}
+
+	data := RulePreviewData{Total: len(changes), Overwrite: overwrite}
+	for i, change := range changes {
+		if i == rulePreviewLimit {
+			break
+		}
+		row := RuleChangeRow{Change: change, From: "Uncategorized"}
+		if change.FromCategoryID != nil {
+			row.From = names[*change.FromCategoryID]
+		}
+		if change.ToCategoryID != nil {
+			row.To = names[*change.ToCategoryID]
+		}
+		data.Changes = append(data.Changes, row)
+	}
+	return data, nil
+}
+
+// renderRuleList renders the rule list after a change
+func (h *Handler) renderRuleList(w http.ResponseWriter, status int) {
+	data, err := h.getRulesData()
+	if err != nil {
+		log.Printf("Error querying rules: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := h.templates.ExecuteTemplate(w, "rule-list", data); err != nil {
+		log.Printf("Error executing template: %v", err)
+	}
+}
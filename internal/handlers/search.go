@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/g-linville/budgeting/internal/search"
+)
+
+const searchResultLimit = 20
+
+// SearchTransactions handles GET /search
+func (h *Handler) SearchTransactions(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+
+	var results []search.Result
+	if query != "" {
+		var err error
+		results, err = search.Search(h.db, query, searchResultLimit)
+		if err != nil {
+			log.Printf("Error searching transactions: %v", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	data := struct {
+		Query   string
+		Results []search.Result
+	}{
+		Query:   query,
+		Results: results,
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := h.templates.ExecuteTemplate(w, "search-results", data); err != nil {
+		log.Printf("Error executing template: %v", err)
+	}
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/g-linville/budgeting/internal/currency"
+	"github.com/g-linville/budgeting/internal/models"
+	"github.com/g-linville/budgeting/internal/validation"
+	"gorm.io/gorm"
+)
+
+// SplitRowData holds the data for one split line in an expense form
+type SplitRowData struct {
+	Categories []models.Category
+	Split      models.ExpenseSplit
+	Currency   string // Currency of the expense; "" for the base currency
+}
+
+// setSplits replaces the split lines of an expense. The lines are entered in
+// the expense's currency; amount is the expense in the base currency, which
+// is shared between them in proportion.
+func setSplits(tx *gorm.DB, expenseID uint, splits []validation.Split, amount currency.Money) error {
+	if err := tx.Where("expense_id = ?", expenseID).Delete(&models.ExpenseSplit{}).Error; err != nil {
+		return err
+	}
+	if len(splits) == 0 {
+		return nil
+	}
+
+	weights := make([]int, len(splits))
+	for i, split := range splits {
+		weights[i] = split.Amount
+	}
+	amounts, err := amount.Allocate(weights)
+	if err != nil {
+		return err
+	}
+
+	lines := make([]models.ExpenseSplit, len(splits))
+	for i, split := range splits {
+		lines[i] = models.ExpenseSplit{
+			ExpenseID:      expenseID,
+			CategoryID:     split.CategoryID,
+			Amount:         amounts[i].Minor,
+			OriginalAmount: split.Amount,
+		}
+	}
+	return tx.Create(&lines).Error
+}
+
+// GetSplitRow handles GET /partials/split-row, returning an empty split line
+// to append to an expense form
+func (h *Handler) GetSplitRow(w http.ResponseWriter, r *http.Request) {
+	categories, err := h.getCategories(models.CategoryKindExpense)
+	if err != nil {
+		log.Printf("Error querying categories: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := h.templates.ExecuteTemplate(w, "expense-split-row", SplitRowData{Categories: categories}); err != nil {
+		log.Printf("Error executing template: %v", err)
+	}
+}
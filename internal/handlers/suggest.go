@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/g-linville/budgeting/internal/models"
+	"github.com/g-linville/budgeting/internal/suggest"
+)
+
+// suggestFolds is the number of folds used to measure suggestion accuracy
+const suggestFolds = 5
+
+// CategorySuggestion is a suggested category for the expense form
+type CategorySuggestion struct {
+	Category   models.Category
+	Confidence float64 // 0-100
+}
+
+// AccuracyRow is a category's line in the accuracy report
+type AccuracyRow struct {
+	suggest.CategoryAccuracy
+	Name string
+}
+
+// SuggestionAccuracyData holds the accuracy report
+type SuggestionAccuracyData struct {
+	Overall    suggest.CategoryAccuracy
+	Categories []AccuracyRow
+	Folds      int
+}
+
+// SuggestCategory handles GET /suggest/category?name=..., suggesting a
+// category for the expense form from past expenses with similar names
+func (h *Handler) SuggestCategory(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	name := r.URL.Query().Get("name")
+	if len(suggest.Tokenize(name)) == 0 {
+		return
+	}
+
+	examples, err := suggest.Load(h.db)
+	if err != nil {
+		log.Printf("Error loading suggestion history: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	s, ok := suggest.Train(examples).Predict(name)
+	if !ok || s.Confidence < suggest.MinConfidence {
+		return
+	}
+
+	var category models.Category
+	if err := h.db.First(&category, s.CategoryID).Error; err != nil || category.Archived {
+		// The category may have been moved to the trash or archived
+		return
+	}
+
+	data := CategorySuggestion{Category: category, Confidence: s.Confidence * 100}
+	if err := h.templates.ExecuteTemplate(w, "category-suggestion", data); err != nil {
+		log.Printf("Error executing template: %v", err)
+	}
+}
+
+// SuggestionAccuracy handles GET /suggest/accuracy, reporting how often
+// suggestions would have been right on past expenses
+func (h *Handler) SuggestionAccuracy(w http.ResponseWriter, r *http.Request) {
+	examples, err := suggest.Load(h.db)
+	if err != nil {
+		log.Printf("Error loading suggestion history: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	acc := suggest.Evaluate(examples, suggestFolds)
+
+	var categories []models.Category
+	if err := h.db.Unscoped().Find(&categories).Error; err != nil {
+		log.Printf("Error querying categories: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	names := make(map[uint]string, len(categories))
+	for _, c := range categories {
+		names[c.ID] = c.Name
+	}
+
+	data := SuggestionAccuracyData{Overall: acc.CategoryAccuracy, Folds: suggestFolds}
+	for _, c := range acc.Categories {
+		data.Categories = append(data.Categories, AccuracyRow{CategoryAccuracy: c, Name: names[c.CategoryID]})
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := h.templates.ExecuteTemplate(w, "suggestion-accuracy-modal", data); err != nil {
+		log.Printf("Error executing template: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
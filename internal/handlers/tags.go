@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"github.com/g-linville/budgeting/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// resolveTags returns the tags with the given (already normalized) names,
+// creating any that do not exist yet
+func resolveTags(tx *gorm.DB, names []string) ([]models.Tag, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	newTags := make([]models.Tag, len(names))
+	for i, name := range names {
+		newTags[i] = models.Tag{Name: name}
+	}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&newTags).Error; err != nil {
+		return nil, err
+	}
+
+	var tags []models.Tag
+	if err := tx.Where("name IN ?", names).Order("name").Find(&tags).Error; err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// setTags replaces the tags on an expense or income
+func setTags(tx *gorm.DB, record interface{}, names []string) error {
+	tags, err := resolveTags(tx, names)
+	if err != nil {
+		return err
+	}
+	return tx.Model(record).Association("Tags").Replace(tags)
+}
+
+// loadTransactionTags fills in the tag names of each transaction
+func (h *Handler) loadTransactionTags(transactions []Transaction) error {
+	type key struct {
+		Type string
+		ID   uint
+	}
+
+	ids := make(map[string][]uint)
+	for _, t := range transactions {
+		ids[t.Type] = append(ids[t.Type], t.ID)
+	}
+
+	tagNames := make(map[key][]string)
+	for txType, joinTable := range map[string]string{"expense": "expense_tags", "income": "income_tags"} {
+		if len(ids[txType]) == 0 {
+			continue
+		}
+
+		var rows []struct {
+			ID   uint
+			Name string
+		}
+		if err := h.db.Table(joinTable+" j").
+			Select("j."+txType+"_id AS id, tags.name").
+			Joins("JOIN tags ON tags.id = j.tag_id").
+			Where("j."+txType+"_id IN ?", ids[txType]).
+			Order("tags.name").
+			Scan(&rows).Error; err != nil {
+			return err
+		}
+		for _, row := range rows {
+			k := key{txType, row.ID}
+			tagNames[k] = append(tagNames[k], row.Name)
+		}
+	}
+
+	for i := range transactions {
+		transactions[i].Tags = tagNames[key{transactions[i].Type, transactions[i].ID}]
+	}
+	return nil
+}
@@ -0,0 +1,487 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/g-linville/budgeting/internal/currency"
+	"github.com/g-linville/budgeting/internal/database"
+	"github.com/g-linville/budgeting/internal/locale"
+	"github.com/g-linville/budgeting/internal/models"
+)
+
+const transactionsPageSize = 50
+
+// sortColumns maps the sort query parameter to the column of the unified query
+var sortColumns = map[string]string{
+	"date":   "t.date",
+	"amount": "t.amount",
+	"name":   "t.name",
+}
+
+// TransactionFilter holds the transactions page query parameters. The raw
+// strings round-trip into the filter form and shareable URLs.
+type TransactionFilter struct {
+	From     string // YYYY-MM-DD, inclusive
+	To       string // YYYY-MM-DD, inclusive
+	Type     string // "", "expense" or "income"
+	Category string // "", a category ID, or "none" for uncategorized
+	Tag      string // Tag name
+	Payee    string // Payee ID
+	Min      string // Amount in the base currency, written as in the locale
+	Max      string // Amount in the base currency, written as in the locale
+	Query    string // Text matched against name and notes
+	Sort     string // "date", "amount" or "name"
+	Dir      string // "asc" or "desc"
+	After    string // Keyset cursor returned with the previous page
+
+	minCents   int
+	maxCents   int
+	categoryID uint
+	payeeID    uint
+}
+
+// SortColumn is a sortable column header on the transactions page
+type SortColumn struct {
+	Label  string
+	URL    string
+	Active bool
+	Dir    string
+}
+
+// TransactionsPageData holds all data needed for the transactions page
+type TransactionsPageData struct {
+	Categories       []models.Category // Expense categories
+	IncomeCategories []models.Category
+	Tags             []models.Tag
+	Payees           []models.Payee
+	Filter           TransactionFilter
+	Columns          []SortColumn
+	Transactions     []Transaction
+	NextURL          string // Empty when there are no more pages
+}
+
+// transactionRow is a row of the unified expense+income query
+type transactionRow struct {
+	Type           string
+	ID             uint
+	Name           string
+	Amount         int
+	Date           string
+	CategoryID     *uint
+	CategoryName   *string
+	Notes          string
+	SplitCount     int
+	Attachments    int
+	Refunded       int
+	Reimbursable   bool
+	Currency       string
+	OriginalAmount int
+}
+
+// parseTransactionFilter reads filters from URL query parameters, ignoring
+// values that do not parse
+func parseTransactionFilter(query url.Values, base currency.Currency, loc locale.Locale) TransactionFilter {
+	f := TransactionFilter{
+		Type:     query.Get("type"),
+		Category: query.Get("category"),
+		Tag:      strings.TrimSpace(query.Get("tag")),
+		Payee:    query.Get("payee"),
+		Min:      strings.TrimSpace(query.Get("min")),
+		Max:      strings.TrimSpace(query.Get("max")),
+		Query:    strings.TrimSpace(query.Get("q")),
+		Sort:     query.Get("sort"),
+		Dir:      query.Get("dir"),
+		After:    query.Get("after"),
+	}
+
+	for _, date := range []struct {
+		param string
+		dest  *string
+	}{{"from", &f.From}, {"to", &f.To}} {
+		value := query.Get(date.param)
+		if _, err := time.Parse("2006-01-02", value); err == nil {
+			*date.dest = value
+		}
+	}
+
+	if f.Type != "expense" && f.Type != "income" {
+		f.Type = ""
+	}
+	if f.Category != "" && f.Category != "none" {
+		id, err := strconv.ParseUint(f.Category, 10, 32)
+		if err != nil {
+			f.Category = ""
+		} else {
+			f.categoryID = uint(id)
+		}
+	}
+	if f.Payee != "" {
+		id, err := strconv.ParseUint(f.Payee, 10, 32)
+		if err != nil {
+			f.Payee = ""
+		} else {
+			f.payeeID = uint(id)
+		}
+	}
+	if f.Min != "" {
+		if amount, err := loc.Parse(f.Min, base); err == nil && amount.IsPositive() {
+			f.minCents = amount.Minor
+		} else {
+			f.Min = ""
+		}
+	}
+	if f.Max != "" {
+		if amount, err := loc.Parse(f.Max, base); err == nil && amount.IsPositive() {
+			f.maxCents = amount.Minor
+		} else {
+			f.Max = ""
+		}
+	}
+	if _, ok := sortColumns[f.Sort]; !ok {
+		f.Sort = "date"
+	}
+	if f.Dir != "asc" {
+		f.Dir = "desc"
+	}
+
+	return f
+}
+
+// values encodes the filter as URL query parameters (without the cursor)
+func (f TransactionFilter) values() url.Values {
+	values := url.Values{}
+	set := func(key, value string) {
+		if value != "" {
+			values.Set(key, value)
+		}
+	}
+	set("from", f.From)
+	set("to", f.To)
+	set("type", f.Type)
+	set("category", f.Category)
+	set("tag", f.Tag)
+	set("payee", f.Payee)
+	set("min", f.Min)
+	set("max", f.Max)
+	set("q", f.Query)
+	if f.Sort != "date" || f.Dir != "desc" {
+		set("sort", f.Sort)
+		set("dir", f.Dir)
+	}
+	return values
+}
+
+// columns builds the header links, toggling direction on the active column
+func (f TransactionFilter) columns() []SortColumn {
+	var columns []SortColumn
+	for _, col := range []struct{ key, label string }{
+		{"date", "Date"}, {"name", "Name"}, {"amount", "Amount"},
+	} {
+		active := f.Sort == col.key
+		dir := "desc"
+		if col.key == "name" {
+			dir = "asc"
+		}
+		if active {
+			dir = "asc"
+			if f.Dir == "asc" {
+				dir = "desc"
+			}
+		}
+
+		sorted := f
+		sorted.Sort = col.key
+		sorted.Dir = dir
+		values := sorted.values()
+		values.Set("sort", col.key)
+		values.Set("dir", dir)
+
+		columns = append(columns, SortColumn{
+			Label:  col.label,
+			URL:    "/transactions?" + values.Encode(),
+			Active: active,
+			Dir:    f.Dir,
+		})
+	}
+	return columns
+}
+
+// errInvalidCursor is returned for a cursor that was not produced by
+// encodeCursor for the requested sort
+var errInvalidCursor = errors.New("invalid cursor")
+
+// encodeCursor packs the sort key of the last row on a page
+func encodeCursor(sortValue, txType string, id uint) string {
+	raw := fmt.Sprintf("%s\x00%s\x00%d", sortValue, txType, id)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeCursor unpacks a cursor produced by encodeCursor
+func decodeCursor(cursor string) (sortValue, txType string, id uint, ok bool) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", "", 0, false
+	}
+	parts := strings.Split(string(raw), "\x00")
+	if len(parts) != 3 {
+		return "", "", 0, false
+	}
+	parsedID, err := strconv.ParseUint(parts[2], 10, 32)
+	if err != nil {
+		return "", "", 0, false
+	}
+	return parts[0], parts[1], uint(parsedID), true
+}
+
+// queryTransactions runs a single query over expenses and income combined,
+// applying filters, sorting and keyset pagination. It returns the page and
+// the cursor for the next page ("" if this is the last page).
+func (h *Handler) queryTransactions(f TransactionFilter, limit int) ([]Transaction, string, error) {
+	union := fmt.Sprintf(`
+		SELECT 'expense' AS type, e.id, e.name, e.amount, %s AS date,
+			e.category_id, c.name AS category_name, e.notes,
+			(SELECT COUNT(*) FROM expense_splits s WHERE s.expense_id = e.id) AS split_count,
+			(SELECT COUNT(*) FROM attachments a WHERE a.expense_id = e.id) AS attachments,
+			(SELECT COALESCE(SUM(r.amount), 0) FROM refunds r WHERE r.expense_id = e.id) AS refunded,
+			e.reimbursable, e.currency, e.original_amount
+		FROM expenses e
+		LEFT JOIN categories c ON c.id = e.category_id AND c.deleted_at IS NULL
+		WHERE e.deleted_at IS NULL
+		UNION ALL
+		SELECT 'income' AS type, i.id, i.name, i.amount, %s AS date,
+			i.category_id, c.name AS category_name, i.notes, 0 AS split_count, 0 AS attachments,
+			0 AS refunded, false AS reimbursable, i.currency, i.original_amount
+		FROM incomes i
+		LEFT JOIN categories c ON c.id = i.category_id AND c.deleted_at IS NULL
+		WHERE i.deleted_at IS NULL`,
+		database.DateString(h.db, "e.expense_date"),
+		database.DateString(h.db, "i.income_date"))
+
+	var conditions []string
+	var args []interface{}
+	where := func(condition string, values ...interface{}) {
+		conditions = append(conditions, condition)
+		args = append(args, values...)
+	}
+
+	if f.From != "" {
+		where("t.date >= ?", f.From)
+	}
+	if f.To != "" {
+		where("t.date <= ?", f.To)
+	}
+	if f.Type != "" {
+		where("t.type = ?", f.Type)
+	}
+	if f.Category == "none" {
+		where("t.category_name IS NULL AND t.split_count = 0")
+	} else if f.categoryID != 0 {
+		where("(t.category_id = ? OR (t.type = 'expense' AND t.id IN (SELECT s.expense_id FROM expense_splits s WHERE s.category_id = ?)))",
+			f.categoryID, f.categoryID)
+	}
+	if f.Tag != "" {
+		where(`((t.type = 'expense' AND t.id IN (SELECT et.expense_id FROM expense_tags et JOIN tags tg ON tg.id = et.tag_id WHERE tg.name = ?))
+			OR (t.type = 'income' AND t.id IN (SELECT it.income_id FROM income_tags it JOIN tags tg ON tg.id = it.tag_id WHERE tg.name = ?)))`,
+			f.Tag, f.Tag)
+	}
+	if f.payeeID != 0 {
+		where("t.type = 'expense' AND t.id IN (SELECT e.id FROM expenses e WHERE e.payee_id = ?)", f.payeeID)
+	}
+	if f.minCents > 0 {
+		where("t.amount >= ?", f.minCents)
+	}
+	if f.maxCents > 0 {
+		where("t.amount <= ?", f.maxCents)
+	}
+	if f.Query != "" {
+		pattern := "%" + escapeLike(strings.ToLower(f.Query)) + "%"
+		where(`(LOWER(t.name) LIKE ? ESCAPE '\' OR LOWER(COALESCE(t.notes, '')) LIKE ? ESCAPE '\')`, pattern, pattern)
+	}
+
+	sortColumn := sortColumns[f.Sort]
+	comparison, direction := "<", "DESC"
+	if f.Dir == "asc" {
+		comparison, direction = ">", "ASC"
+	}
+
+	if f.After != "" {
+		sortValue, txType, id, ok := decodeCursor(f.After)
+		if !ok || (txType != "expense" && txType != "income") {
+			return nil, "", errInvalidCursor
+		}
+		var value interface{} = sortValue
+		switch f.Sort {
+		case "amount":
+			amount, err := strconv.Atoi(sortValue)
+			if err != nil {
+				return nil, "", errInvalidCursor
+			}
+			value = amount
+		case "date":
+			if _, err := time.Parse("2006-01-02", sortValue); err != nil {
+				return nil, "", errInvalidCursor
+			}
+		}
+		where(fmt.Sprintf("(%s, t.type, t.id) %s (?, ?, ?)", sortColumn, comparison), value, txType, id)
+	}
+
+	query := "SELECT * FROM (" + union + ") t"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += fmt.Sprintf(" ORDER BY %s %s, t.type %s, t.id %s LIMIT ?", sortColumn, direction, direction, direction)
+	args = append(args, limit+1)
+
+	var rows []transactionRow
+	if err := h.db.Raw(query, args...).Scan(&rows).Error; err != nil {
+		return nil, "", err
+	}
+
+	nextCursor := ""
+	if len(rows) > limit {
+		rows = rows[:limit]
+		last := rows[len(rows)-1]
+		sortValue := last.Date
+		switch f.Sort {
+		case "amount":
+			sortValue = strconv.Itoa(last.Amount)
+		case "name":
+			sortValue = last.Name
+		}
+		nextCursor = encodeCursor(sortValue, last.Type, last.ID)
+	}
+
+	transactions := make([]Transaction, 0, len(rows))
+	for _, row := range rows {
+		dateParsed, _ := time.ParseInLocation("2006-01-02", row.Date, time.Local)
+		original := ""
+		if row.Currency != "" && row.Currency != h.base.Code {
+			original = h.locale.Format(row.OriginalAmount, currency.Of(row.Currency, h.base))
+		}
+		transactions = append(transactions, Transaction{
+			ID:           row.ID,
+			Type:         row.Type,
+			Name:         row.Name,
+			Amount:       h.locale.Format(row.Amount, h.base),
+			Original:     original,
+			AmountRaw:    row.Amount,
+			Date:         row.Date,
+			DateParsed:   dateParsed,
+			Category:     row.CategoryName,
+			CategoryID:   row.CategoryID,
+			Notes:        row.Notes,
+			SplitCount:   row.SplitCount,
+			Attachments:  row.Attachments,
+			Refunded:     row.Refunded,
+			Reimbursable: row.Reimbursable,
+		})
+	}
+
+	if err := h.loadTransactionTags(transactions); err != nil {
+		return nil, "", err
+	}
+
+	return transactions, nextCursor, nil
+}
+
+// escapeLike escapes LIKE wildcards so user input matches literally
+func escapeLike(s string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return replacer.Replace(s)
+}
+
+// getTransactionsPageData runs the filtered query and loads the form options
+// for the transactions page
+func (h *Handler) getTransactionsPageData(filter TransactionFilter) (TransactionsPageData, error) {
+	transactions, nextCursor, err := h.queryTransactions(filter, transactionsPageSize)
+	if err != nil {
+		return TransactionsPageData{}, err
+	}
+
+	categories, err := h.getCategories(models.CategoryKindExpense)
+	if err != nil {
+		return TransactionsPageData{}, err
+	}
+	incomeCategories, err := h.getCategories(models.CategoryKindIncome)
+	if err != nil {
+		return TransactionsPageData{}, err
+	}
+
+	var tags []models.Tag
+	if err := h.db.Order("name").Find(&tags).Error; err != nil {
+		return TransactionsPageData{}, err
+	}
+
+	var payeeList []models.Payee
+	if err := h.db.Order("name").Find(&payeeList).Error; err != nil {
+		return TransactionsPageData{}, err
+	}
+
+	data := TransactionsPageData{
+		Categories:       categories,
+		IncomeCategories: incomeCategories,
+		Tags:             tags,
+		Payees:           payeeList,
+		Filter:           filter,
+		Columns:          filter.columns(),
+		Transactions:     transactions,
+	}
+	if nextCursor != "" {
+		values := filter.values()
+		values.Set("sort", filter.Sort)
+		values.Set("dir", filter.Dir)
+		values.Set("after", nextCursor)
+		data.NextURL = "/transactions?" + values.Encode()
+	}
+	return data, nil
+}
+
+// ListTransactions handles GET /transactions. Full page loads render the
+// whole page; HTMX requests render only the results (or the next page of rows).
+func (h *Handler) ListTransactions(w http.ResponseWriter, r *http.Request) {
+	filter := parseTransactionFilter(r.URL.Query(), h.base, h.locale)
+
+	data, err := h.getTransactionsPageData(filter)
+	if errors.Is(err, errInvalidCursor) {
+		// Silently starting over would repeat rows already on the page
+		http.Error(w, "Invalid cursor", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("Error querying transactions: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	isHTMX := r.Header.Get("HX-Request") == "true" && r.Header.Get("HX-History-Restore-Request") != "true"
+	switch {
+	case !isHTMX:
+		if err := h.pages["transactions"].ExecuteTemplate(w, "layout.html", data); err != nil {
+			log.Printf("Error executing template: %v", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+	case filter.After != "":
+		if err := h.templates.ExecuteTemplate(w, "transactions-rows", data); err != nil {
+			log.Printf("Error executing template: %v", err)
+		}
+	default:
+		if err := h.templates.ExecuteTemplate(w, "transactions-results", data); err != nil {
+			log.Printf("Error executing template: %v", err)
+			return
+		}
+		// Keep the filter form's hidden sort inputs in sync (OOB)
+		if err := h.templates.ExecuteTemplate(w, "transaction-sort-inputs-oob", data); err != nil {
+			log.Printf("Error executing OOB template: %v", err)
+		}
+	}
+}
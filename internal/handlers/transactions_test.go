@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/g-linville/budgeting/internal/currency"
+	"github.com/g-linville/budgeting/internal/database/dbtest"
+	"github.com/g-linville/budgeting/internal/locale"
+	"github.com/g-linville/budgeting/internal/models"
+	"gorm.io/gorm"
+)
+
+// transactionFixture holds the IDs of the rows seeded for the transaction
+// query tests
+type transactionFixture struct {
+	dining, travel, salary uint // Category IDs
+}
+
+func seedTransactions(t *testing.T, db *gorm.DB) transactionFixture {
+	t.Helper()
+	day := func(date string) time.Time {
+		d, err := time.ParseInLocation("2006-01-02", date, time.Local)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	create := func(value interface{}) {
+		t.Helper()
+		if err := db.Create(value).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dining := models.Category{Name: "Dining", Kind: models.CategoryKindExpense}
+	travel := models.Category{Name: "Travel", Kind: models.CategoryKindExpense}
+	salary := models.Category{Name: "Salary", Kind: models.CategoryKindIncome}
+	create(&dining)
+	create(&travel)
+	create(&salary)
+
+	lunchTag := models.Tag{Name: "lunch"}
+	create(&lunchTag)
+
+	expenses := []models.Expense{
+		{Name: "Lunch", Amount: 1250, ExpenseDate: day("2024-03-05"), CategoryID: &dining.ID, Tags: []models.Tag{lunchTag}},
+		{Name: "Flight", Amount: 45000, ExpenseDate: day("2024-03-05"), CategoryID: &travel.ID, Notes: "Booked at 50% off"},
+		{Name: "Dinner_party", Amount: 8000, ExpenseDate: day("2024-03-10"), Splits: []models.ExpenseSplit{
+			{CategoryID: &dining.ID, Amount: 5000},
+			{CategoryID: &travel.ID, Amount: 3000},
+		}},
+		{Name: "Taxi", Amount: 2500, ExpenseDate: day("2024-02-28"), Notes: "About 50 miles", Reimbursable: true},
+		{Name: "Trashed", Amount: 100, ExpenseDate: day("2024-03-06")},
+	}
+	create(&expenses)
+	if err := db.Delete(&expenses[4]).Error; err != nil {
+		t.Fatal(err)
+	}
+	create(&models.Refund{ExpenseID: expenses[3].ID, Kind: models.RefundKindReimbursement, Amount: 500, RefundDate: day("2024-03-01")})
+
+	create(&[]models.Income{
+		{Name: "Paycheck", Amount: 500000, IncomeDate: day("2024-03-05"), CategoryID: &salary.ID},
+		{Name: "Refund check", Amount: 1250, IncomeDate: day("2024-03-10")},
+	})
+
+	return transactionFixture{dining: dining.ID, travel: travel.ID, salary: salary.ID}
+}
+
+func testHandler(t *testing.T, db *gorm.DB) *Handler {
+	t.Helper()
+	usd, _ := currency.Lookup("USD")
+	loc, _ := locale.Lookup("en-US")
+	return &Handler{db: db, base: usd, locale: loc}
+}
+
+func transactionNames(transactions []Transaction) []string {
+	names := make([]string, len(transactions))
+	for i, tx := range transactions {
+		names[i] = tx.Name
+	}
+	return names
+}
+
+func TestQueryTransactionsOrder(t *testing.T) {
+	dbtest.RunMigrated(t, func(t *testing.T, db *gorm.DB) {
+		seedTransactions(t, db)
+		h := testHandler(t, db)
+
+		tests := []struct {
+			query string
+			want  []string
+		}{
+			// Ties on the sort column are broken by type, then ID
+			{"", []string{"Refund check", "Dinner_party", "Paycheck", "Flight", "Lunch", "Taxi"}},
+			{"dir=asc", []string{"Taxi", "Lunch", "Flight", "Paycheck", "Dinner_party", "Refund check"}},
+			{"sort=amount", []string{"Paycheck", "Flight", "Dinner_party", "Taxi", "Refund check", "Lunch"}},
+			{"sort=name&dir=asc", []string{"Dinner_party", "Flight", "Lunch", "Paycheck", "Refund check", "Taxi"}},
+		}
+		for _, tt := range tests {
+			query, _ := url.ParseQuery(tt.query)
+			transactions, next, err := h.queryTransactions(parseTransactionFilter(query, h.base, h.locale), 100)
+			if err != nil {
+				t.Fatalf("%q: %v", tt.query, err)
+			}
+			if got := transactionNames(transactions); !slices.Equal(got, tt.want) {
+				t.Errorf("%q: got %q, want %q", tt.query, got, tt.want)
+			}
+			if next != "" {
+				t.Errorf("%q: next cursor %q on the last page", tt.query, next)
+			}
+		}
+	})
+}
+
+// Paging through with the cursor must visit every row of the unpaged query
+// exactly once, in the same order, for every sort
+func TestQueryTransactionsPagination(t *testing.T) {
+	dbtest.RunMigrated(t, func(t *testing.T, db *gorm.DB) {
+		seedTransactions(t, db)
+		h := testHandler(t, db)
+
+		for _, sort := range []string{"date", "amount", "name"} {
+			for _, dir := range []string{"asc", "desc"} {
+				query := url.Values{"sort": {sort}, "dir": {dir}}
+				filter := parseTransactionFilter(query, h.base, h.locale)
+
+				all, _, err := h.queryTransactions(filter, 100)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				var paged []string
+				for page := 0; ; page++ {
+					if page > len(all) {
+						t.Fatalf("%s %s: pagination does not end", sort, dir)
+					}
+					transactions, next, err := h.queryTransactions(filter, 2)
+					if err != nil {
+						t.Fatalf("%s %s page %d: %v", sort, dir, page, err)
+					}
+					paged = append(paged, transactionNames(transactions)...)
+					if next == "" {
+						break
+					}
+					filter.After = next
+				}
+
+				if want := transactionNames(all); !slices.Equal(paged, want) {
+					t.Errorf("%s %s: pages = %q, want %q", sort, dir, paged, want)
+				}
+			}
+		}
+	})
+}
+
+func TestQueryTransactionsFilters(t *testing.T) {
+	dbtest.RunMigrated(t, func(t *testing.T, db *gorm.DB) {
+		ids := seedTransactions(t, db)
+		h := testHandler(t, db)
+
+		tests := []struct {
+			query string
+			want  []string // Names in the default order, newest first
+		}{
+			{"type=income", []string{"Refund check", "Paycheck"}},
+			{"type=expense&from=2024-03-01", []string{"Dinner_party", "Flight", "Lunch"}},
+			{"from=2024-03-05&to=2024-03-05", []string{"Paycheck", "Flight", "Lunch"}},
+			{"to=2024-02-29", []string{"Taxi"}},
+			{fmt.Sprintf("category=%d", ids.dining), []string{"Dinner_party", "Lunch"}},
+			{fmt.Sprintf("category=%d", ids.salary), []string{"Paycheck"}},
+			{"category=none", []string{"Refund check", "Taxi"}},
+			{"tag=lunch", []string{"Lunch"}},
+			{"min=20&max=100", []string{"Dinner_party", "Taxi"}},
+			{"min=1000", []string{"Paycheck"}},
+			{"q=booked", []string{"Flight"}},
+			{"q=REFUND", []string{"Refund check"}},
+			{"q=50%25", []string{"Flight"}},
+			{"q=r_p", []string{"Dinner_party"}},
+			{"q=_", []string{"Dinner_party"}},
+		}
+		for _, tt := range tests {
+			query, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			transactions, _, err := h.queryTransactions(parseTransactionFilter(query, h.base, h.locale), 100)
+			if err != nil {
+				t.Fatalf("%q: %v", tt.query, err)
+			}
+			if got := transactionNames(transactions); !slices.Equal(got, tt.want) {
+				t.Errorf("%q: got %q, want %q", tt.query, got, tt.want)
+			}
+		}
+	})
+}
+
+func TestQueryTransactionsColumns(t *testing.T) {
+	dbtest.RunMigrated(t, func(t *testing.T, db *gorm.DB) {
+		ids := seedTransactions(t, db)
+		h := testHandler(t, db)
+
+		transactions, _, err := h.queryTransactions(parseTransactionFilter(url.Values{}, h.base, h.locale), 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		byName := make(map[string]Transaction)
+		for _, tx := range transactions {
+			byName[tx.Name] = tx
+		}
+
+		lunch := byName["Lunch"]
+		if lunch.Type != "expense" || lunch.Date != "2024-03-05" || lunch.AmountRaw != 1250 || lunch.Amount != "$12.50" ||
+			lunch.Category == nil || *lunch.Category != "Dining" || lunch.CategoryID == nil || *lunch.CategoryID != ids.dining ||
+			!slices.Equal(lunch.Tags, []string{"lunch"}) {
+			t.Errorf("Lunch = %+v", lunch)
+		}
+		if party := byName["Dinner_party"]; party.SplitCount != 2 || party.Category != nil {
+			t.Errorf("Dinner_party = %+v, want 2 splits and no category", party)
+		}
+		if taxi := byName["Taxi"]; taxi.Refunded != 500 || !taxi.Reimbursable {
+			t.Errorf("Taxi = %+v, want 500 refunded and reimbursable", taxi)
+		}
+		paycheck := byName["Paycheck"]
+		if paycheck.Type != "income" || paycheck.Category == nil || *paycheck.Category != "Salary" ||
+			paycheck.Refunded != 0 || paycheck.Reimbursable || paycheck.SplitCount != 0 {
+			t.Errorf("Paycheck = %+v", paycheck)
+		}
+	})
+}
+
+func TestQueryTransactionsInvalidCursor(t *testing.T) {
+	dbtest.RunMigrated(t, func(t *testing.T, db *gorm.DB) {
+		h := testHandler(t, db)
+
+		tests := []struct {
+			sort   string
+			cursor string
+		}{
+			{"date", "not a cursor"},
+			{"date", encodeCursor("yesterday", "expense", 1)},
+			{"date", encodeCursor("2024-03-05", "transfer", 1)},
+			{"amount", encodeCursor("12.50", "expense", 1)},
+		}
+		for _, tt := range tests {
+			filter := parseTransactionFilter(url.Values{"sort": {tt.sort}, "after": {tt.cursor}}, h.base, h.locale)
+			if _, _, err := h.queryTransactions(filter, 10); !errors.Is(err, errInvalidCursor) {
+				t.Errorf("%s cursor %q: error = %v, want %v", tt.sort, tt.cursor, err, errInvalidCursor)
+			}
+		}
+	})
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/g-linville/budgeting/internal/audit"
+	"github.com/g-linville/budgeting/internal/models"
+	"github.com/g-linville/budgeting/internal/trash"
+	"github.com/g-linville/budgeting/internal/validation"
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+)
+
+// ListTrash handles GET /trash
+func (h *Handler) ListTrash(w http.ResponseWriter, r *http.Request) {
+	items, err := trash.List(h.db)
+	if err != nil {
+		log.Printf("Error querying trash: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	data := struct {
+		Items []trash.Item
+	}{
+		Items: items,
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := h.templates.ExecuteTemplate(w, "trash-modal", data); err != nil {
+		log.Printf("Error executing template: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// RestoreTrashItem handles POST /trash/{type}/{id}/restore
+func (h *Handler) RestoreTrashItem(w http.ResponseWriter, r *http.Request) {
+	h.handleTrashAction(w, r, trash.Restore)
+}
+
+// PurgeTrashItem handles DELETE /trash/{type}/{id}. Attachment files of a
+// purged expense are removed once nothing else refers to them.
+func (h *Handler) PurgeTrashItem(w http.ResponseWriter, r *http.Request) {
+	h.handleTrashAction(w, r, func(db *gorm.DB, entityType string, id uint) error {
+		var hashes []string
+		if entityType == audit.EntityExpense {
+			if err := db.Model(&models.Attachment{}).Where("expense_id = ?", id).Pluck("hash", &hashes).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := trash.Purge(db, entityType, id); err != nil {
+			return err
+		}
+
+		for _, hash := range hashes {
+			if err := h.attachments.RemoveIfUnreferenced(db, hash); err != nil {
+				log.Printf("Error removing attachment file %s: %v", hash, err)
+			}
+		}
+		return nil
+	})
+}
+
+// handleTrashAction applies a restore or purge and re-renders the trash list
+// along with the dashboard sections it may affect
+func (h *Handler) handleTrashAction(w http.ResponseWriter, r *http.Request, action func(db *gorm.DB, entityType string, id uint) error) {
+	entityType := chi.URLParam(r, "type")
+	if !trash.IsTrashable(entityType) {
+		http.Error(w, "Invalid type", http.StatusBadRequest)
+		return
+	}
+
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := action(h.db, entityType, uint(id)); err != nil {
+		if errors.Is(err, trash.ErrNotTrashed) || errors.Is(err, trash.ErrNameTaken) {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Header().Set("HX-Retarget", "#trash-errors")
+			w.Header().Set("HX-Reswap", "innerHTML")
+			w.WriteHeader(http.StatusConflict)
+			h.templates.ExecuteTemplate(w, "validation-errors", validation.ValidationErrors{
+				{Field: entityType, Message: err.Error()},
+			})
+			return
+		}
+		log.Printf("Error updating trash: %v", err)
+		http.Error(w, "Failed to update trash", http.StatusInternalServerError)
+		return
+	}
+
+	items, err := trash.List(h.db)
+	if err != nil {
+		log.Printf("Error querying trash: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	data, err := h.getRefreshData()
+	if err != nil {
+		log.Printf("Error getting dashboard data: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	// Render trash list
+	trashData := struct {
+		Items []trash.Item
+	}{
+		Items: items,
+	}
+	if err := h.templates.ExecuteTemplate(w, "trash-list", trashData); err != nil {
+		log.Printf("Error executing template: %v", err)
+		return
+	}
+
+	// Render OOB recent transactions and overview stats
+	oobBuf := new(bytes.Buffer)
+	if err := h.templates.ExecuteTemplate(oobBuf, "recent-transactions-oob", data); err != nil {
+		log.Printf("Error executing OOB template: %v", err)
+		return
+	}
+	if err := h.templates.ExecuteTemplate(oobBuf, "overview-stats-oob", data); err != nil {
+		log.Printf("Error executing OOB template: %v", err)
+		return
+	}
+	w.Write(oobBuf.Bytes())
+}
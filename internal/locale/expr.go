@@ -0,0 +1,305 @@
+package locale
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/g-linville/budgeting/internal/currency"
+)
+
+// ErrExpression is returned for an arithmetic expression that cannot be
+// evaluated
+var ErrExpression = errors.New("invalid expression")
+
+// maxExpressionLength bounds the input so a pasted blob cannot make the
+// evaluator do unbounded work
+const maxExpressionLength = 200
+
+// maxExpressionDepth bounds parenthesis nesting
+const maxExpressionDepth = 20
+
+// IsExpression reports whether s contains arithmetic beyond a plain amount,
+// such as "84.20/2" or "12.50+15%". Anything with parentheses counts, since
+// in an amount field they group rather than mark a negative amount.
+func (l Locale) IsExpression(s string, c currency.Currency) bool {
+	if strings.ContainsAny(s, "()") {
+		return true
+	}
+	if _, err := l.Parse(s, c); err == nil {
+		return false
+	}
+	return strings.ContainsAny(s, "+-*/×÷%")
+}
+
+// ParseExpression reads an amount that may be written as an arithmetic
+// expression using + - * / parentheses and percentages. A plain amount is
+// read exactly as by Parse. An expression is evaluated with exact decimal
+// math and only the result is rounded, half away from zero, to the
+// currency's minor unit. Each number in it is held to the currency's decimal
+// places as in Parse, except a percentage.
+//
+// A percentage added to or subtracted from an amount is relative to it, so
+// "40+15%" is 46 and "40-10%" is 36. Anywhere else it is a plain fraction:
+// "15%*40" is 6. Parentheses always group, so "(12.50)" is 12.50 here even
+// though Parse reads it as the accounting negative -12.50.
+func (l Locale) ParseExpression(s string, c currency.Currency) (currency.Money, error) {
+	if !l.IsExpression(s, c) {
+		return l.Parse(s, c)
+	}
+
+	value, err := l.Evaluate(s, c)
+	if err != nil {
+		return currency.Money{}, err
+	}
+	return currency.FromMajor(value, c)
+}
+
+// Evaluate evaluates an arithmetic expression of amounts written in this
+// locale, returning the exact result in major units
+func (l Locale) Evaluate(s string, c currency.Currency) (*big.Rat, error) {
+	if utf8.RuneCountInString(s) > maxExpressionLength {
+		return nil, fmt.Errorf("%w: longer than %d characters", ErrExpression, maxExpressionLength)
+	}
+	tokens, err := l.tokenize(s, c)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("amount cannot be empty")
+	}
+
+	p := &exprParser{tokens: tokens, currency: c}
+	value, err := p.sum()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("%w: unexpected %q", ErrExpression, p.tokens[p.pos].text)
+	}
+	return value, nil
+}
+
+// exprToken is a number or a single operator character
+type exprToken struct {
+	text     string
+	value    *big.Rat // nil for operators
+	decimals int      // Significant decimal places of a number
+}
+
+// tokenize splits s into numbers and operators, skipping white space and
+// the currency's symbol or code
+func (l Locale) tokenize(s string, c currency.Currency) ([]exprToken, error) {
+	s = normalizeSpaces(s)
+	var tokens []exprToken
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		rest := s[i:]
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+		case c.Symbol != "" && strings.HasPrefix(rest, c.Symbol):
+			i += len(c.Symbol)
+		case len(rest) >= len(c.Code) && strings.EqualFold(rest[:len(c.Code)], c.Code):
+			i += len(c.Code)
+		case strings.ContainsRune("+-*/()%", r):
+			tokens = append(tokens, exprToken{text: string(r)})
+			i += size
+		case r == '×':
+			tokens = append(tokens, exprToken{text: "*"})
+			i += size
+		case r == '÷':
+			tokens = append(tokens, exprToken{text: "/"})
+			i += size
+		case l.isNumberRune(r):
+			end := i
+			for end < len(s) {
+				r, size := utf8.DecodeRuneInString(s[end:])
+				if !l.isNumberRune(r) {
+					break
+				}
+				end += size
+			}
+			value, decimals, err := l.parseRat(s[i:end])
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, exprToken{text: s[i:end], value: value, decimals: decimals})
+			i = end
+		case unicode.IsLetter(r):
+			word := strings.FieldsFunc(rest, func(r rune) bool { return !unicode.IsLetter(r) })[0]
+			// Words such as "tip" have no value; a tip is added as a percentage
+			return nil, fmt.Errorf("%w: %q is not a number; add a tip as a percentage, e.g. +15%%", ErrExpression, word)
+		default:
+			return nil, fmt.Errorf("%w: unexpected %q", ErrExpression, string(r))
+		}
+	}
+	return tokens, nil
+}
+
+// isNumberRune reports whether r can be part of a number in this locale.
+// Space group separators are left out, so numbers in expressions are
+// written without them.
+func (l Locale) isNumberRune(r rune) bool {
+	if r >= '0' && r <= '9' {
+		return true
+	}
+	sep := string(r)
+	if r == '’' {
+		sep = "'"
+	}
+	group := strings.ReplaceAll(l.Group, "’", "'")
+	return sep == l.Decimal || (sep == group && !unicode.IsSpace(r))
+}
+
+// parseRat reads an unsigned number written in this locale, along with its
+// number of decimal places, not counting trailing zeros
+func (l Locale) parseRat(text string) (*big.Rat, int, error) {
+	whole, fraction, err := l.splitNumber(text)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w in %q", err, text)
+	}
+	value, ok := new(big.Rat).SetString(whole + "." + fraction + "0")
+	if !ok {
+		return nil, 0, fmt.Errorf("invalid amount format: %q", text)
+	}
+	return value, len(strings.TrimRight(fraction, "0")), nil
+}
+
+// exprParser is a recursive descent parser over:
+//
+//	sum     = product { ("+" | "-") product }
+//	product = unary { ("*" | "/") unary }
+//	unary   = ("+" | "-") unary | primary [ "%" ]
+//	primary = number | "(" sum ")"
+type exprParser struct {
+	tokens   []exprToken
+	pos      int
+	depth    int
+	currency currency.Currency
+}
+
+// peek returns the next operator, or "" at the end or before a number
+func (p *exprParser) peek() string {
+	if p.pos >= len(p.tokens) || p.tokens[p.pos].value != nil {
+		return ""
+	}
+	return p.tokens[p.pos].text
+}
+
+// sum parses additions and subtractions
+func (p *exprParser) sum() (*big.Rat, error) {
+	left, _, err := p.product()
+	if err != nil {
+		return nil, err
+	}
+	for op := p.peek(); op == "+" || op == "-"; op = p.peek() {
+		p.pos++
+		right, percent, err := p.product()
+		if err != nil {
+			return nil, err
+		}
+		if percent {
+			// 40 + 15% adds 15% of 40
+			right.Mul(right, left)
+		}
+		if op == "+" {
+			left.Add(left, right)
+		} else {
+			left.Sub(left, right)
+		}
+	}
+	return left, nil
+}
+
+// product parses multiplications and divisions. It also reports whether the
+// whole product was a single percentage.
+func (p *exprParser) product() (*big.Rat, bool, error) {
+	left, percent, err := p.unary()
+	if err != nil {
+		return nil, false, err
+	}
+	for op := p.peek(); op == "*" || op == "/"; op = p.peek() {
+		p.pos++
+		right, _, err := p.unary()
+		if err != nil {
+			return nil, false, err
+		}
+		if op == "*" {
+			left.Mul(left, right)
+		} else {
+			if right.Sign() == 0 {
+				return nil, false, fmt.Errorf("%w: division by zero", ErrExpression)
+			}
+			left.Quo(left, right)
+		}
+		percent = false
+	}
+	return left, percent, nil
+}
+
+// unary parses a signed value with an optional trailing percent sign
+func (p *exprParser) unary() (*big.Rat, bool, error) {
+	switch p.peek() {
+	case "+":
+		p.pos++
+		return p.unary()
+	case "-":
+		p.pos++
+		value, percent, err := p.unary()
+		if err != nil {
+			return nil, false, err
+		}
+		return value.Neg(value), percent, nil
+	}
+
+	start := p.pos
+	value, err := p.primary()
+	if err != nil {
+		return nil, false, err
+	}
+	if p.peek() == "%" {
+		p.pos++
+		return value.Quo(value, big.NewRat(100, 1)), true, nil
+	}
+	// A number is held to the currency's precision as in Parse, so a typo
+	// such as "10.005" is not silently rounded; a percentage is exempt
+	if token := p.tokens[start]; token.value != nil && token.decimals > p.currency.Exponent {
+		return nil, false, fmt.Errorf("%w: %s has %d", currency.ErrPrecision, p.currency.Code, p.currency.Exponent)
+	}
+	return value, false, nil
+}
+
+// primary parses a number or a parenthesized sum
+func (p *exprParser) primary() (*big.Rat, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("%w: ends too early", ErrExpression)
+	}
+	token := p.tokens[p.pos]
+	if token.value != nil {
+		p.pos++
+		return new(big.Rat).Set(token.value), nil
+	}
+	if token.text != "(" {
+		return nil, fmt.Errorf("%w: unexpected %q", ErrExpression, token.text)
+	}
+
+	p.depth++
+	if p.depth > maxExpressionDepth {
+		return nil, fmt.Errorf("%w: too many parentheses", ErrExpression)
+	}
+	p.pos++
+	value, err := p.sum()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek() != ")" {
+		return nil, fmt.Errorf("%w: missing closing parenthesis", ErrExpression)
+	}
+	p.pos++
+	p.depth--
+	return value, nil
+}
@@ -0,0 +1,108 @@
+package locale
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/g-linville/budgeting/internal/currency"
+)
+
+func TestParseExpression(t *testing.T) {
+	tests := []struct {
+		locale  string
+		code    string
+		input   string
+		want    int
+		wantErr error
+	}{
+		{"en-US", "USD", "12.50", 1250, nil},
+		{"en-US", "USD", "84.20/2", 4210, nil},
+		{"en-US", "USD", "12.50+15%", 1438, nil},
+		{"en-US", "USD", "40-10%", 3600, nil},
+		{"en-US", "USD", "15%*40", 600, nil},
+		{"en-US", "USD", "$10 / 3", 333, nil},
+		{"en-US", "USD", "20/3", 667, nil},
+		{"en-US", "USD", "2 × 3.25 ÷ 2", 325, nil},
+		{"en-US", "USD", "(12.50+7.50)*2", 4000, nil},
+		{"en-US", "USD", "-(5+5)", -1000, nil},
+		{"en-US", "JPY", "1000/3", 333, nil},
+		{"de-DE", "EUR", "12,50+7,50", 2000, nil},
+		{"de-DE", "EUR", "1.000/4", 25000, nil},
+		// Parentheses group in an expression; a plain amount in
+		// parentheses is not the accounting negative
+		{"en-US", "USD", "(12.50)", 1250, nil},
+		{"en-US", "USD", "($12.50)", 1250, nil},
+		{"en-US", "USD", "-12.50", -1250, nil},
+		{"en-US", "USD", "12.50+tip", 0, ErrExpression},
+		{"en-US", "USD", "5/0", 0, ErrExpression},
+		{"en-US", "USD", "(5+5", 0, ErrExpression},
+		{"en-US", "USD", "5+5)", 0, ErrExpression},
+		{"en-US", "USD", "5+", 0, ErrExpression},
+		{"en-US", "USD", "5 # 2+1", 0, ErrExpression},
+		{"en-US", "USD", strings.Repeat("(", 21) + "1" + strings.Repeat(")", 21), 0, ErrExpression},
+		{"en-US", "USD", strings.Repeat("1+", 101) + "1", 0, ErrExpression},
+		{"en-US", "USD", "92233720368547758*2", 0, currency.ErrOverflow},
+		// Each number is held to the currency's precision, but not a percentage
+		{"en-US", "USD", "10.005+1", 0, currency.ErrPrecision},
+		{"en-US", "USD", "(1.001)*3", 0, currency.ErrPrecision},
+		{"en-US", "USD", "10.500+1", 1150, nil},
+		{"en-US", "USD", "100+7.125%", 10713, nil},
+		{"en-US", "JPY", "1000/2.5", 0, currency.ErrPrecision},
+		{"de-DE", "EUR", "10,005+1", 0, currency.ErrPrecision},
+	}
+	for _, tt := range tests {
+		t.Run(tt.locale+" "+tt.input, func(t *testing.T) {
+			l, c := mustLocale(t, tt.locale), mustCurrency(t, tt.code)
+			got, err := l.ParseExpression(tt.input, c)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ParseExpression(%q) error = %v, want %v", tt.input, err, tt.wantErr)
+			}
+			if err == nil && got.Minor != tt.want {
+				t.Errorf("ParseExpression(%q) = %d, want %d", tt.input, got.Minor, tt.want)
+			}
+		})
+	}
+}
+
+// An over-precise number in an expression gets the same message as a plain
+// amount
+func TestParseExpressionPrecisionMessage(t *testing.T) {
+	l, c := mustLocale(t, "en-US"), mustCurrency(t, "USD")
+	_, exprErr := l.ParseExpression("10.005+1", c)
+	_, plainErr := l.ParseExpression("10.005", c)
+	if exprErr == nil || plainErr == nil || exprErr.Error() != plainErr.Error() {
+		t.Errorf("expression error = %v, plain error = %v, want the same", exprErr, plainErr)
+	}
+}
+
+// The error for a word such as "tip" says how to write one instead
+func TestParseExpressionTip(t *testing.T) {
+	_, err := mustLocale(t, "en-US").ParseExpression("40+tip", mustCurrency(t, "USD"))
+	if err == nil || !strings.Contains(err.Error(), `"tip"`) || !strings.Contains(err.Error(), "+15%") {
+		t.Errorf("error = %v, want it to suggest a percentage", err)
+	}
+}
+
+func TestIsExpression(t *testing.T) {
+	l, usd := mustLocale(t, "en-US"), mustCurrency(t, "USD")
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{"12.50", false},
+		{"-12.50", false},
+		{"$1,234.56", false},
+		{"12.50-", false},
+		{"(12.50)", true},
+		{"84.20/2", true},
+		{"12.50+15%", true},
+		{"2×3", true},
+		{"abc", false},
+	}
+	for _, tt := range tests {
+		if got := l.IsExpression(tt.input, usd); got != tt.want {
+			t.Errorf("IsExpression(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
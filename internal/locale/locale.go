@@ -0,0 +1,298 @@
+// Package locale writes and reads amounts of money the way a locale does:
+// separators, digit grouping, symbol placement and negative style, plus
+// arithmetic typed into amount fields.
+package locale
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/g-linville/budgeting/internal/currency"
+)
+
+// NegativeStyle is how a locale writes negative amounts
+type NegativeStyle int
+
+const (
+	NegativeMinus  NegativeStyle = iota // -$12.34
+	NegativeParens                      // ($12.34), as in accounting
+)
+
+// Locale describes how amounts are written: the separators, where the
+// currency symbol goes, and how negative amounts are shown
+type Locale struct {
+	Tag         string // BCP 47 tag, e.g. "de-DE"
+	Decimal     string // Decimal separator
+	Group       string // Thousands separator
+	SymbolAfter bool   // "12,34 €" rather than "€12,34"
+	SymbolSpace bool   // Space between the symbol and the number
+	Negative    NegativeStyle
+}
+
+var (
+	errInvalidNumber = errors.New("invalid amount format")
+	errGrouping      = errors.New("invalid digit grouping")
+)
+
+// Non-breaking spaces keep a formatted amount on one line
+const (
+	nbsp       = "\u00a0"
+	narrowNbsp = "\u202f"
+)
+
+// locales are the supported locales, keyed by tag
+var locales = map[string]Locale{
+	"de-CH": {"de-CH", ".", "'", false, true, NegativeMinus},
+	"de-DE": {"de-DE", ",", ".", true, true, NegativeMinus},
+	"en-GB": {"en-GB", ".", ",", false, false, NegativeMinus},
+	"en-US": {"en-US", ".", ",", false, false, NegativeMinus},
+	"es-ES": {"es-ES", ",", ".", true, true, NegativeMinus},
+	"fr-CA": {"fr-CA", ",", nbsp, true, true, NegativeMinus},
+	"fr-FR": {"fr-FR", ",", narrowNbsp, true, true, NegativeMinus},
+	"it-IT": {"it-IT", ",", ".", true, true, NegativeMinus},
+	"ja-JP": {"ja-JP", ".", ",", false, false, NegativeMinus},
+	"nl-NL": {"nl-NL", ",", ".", false, true, NegativeMinus},
+	"pl-PL": {"pl-PL", ",", nbsp, true, true, NegativeMinus},
+	"pt-BR": {"pt-BR", ",", ".", false, true, NegativeMinus},
+	"sv-SE": {"sv-SE", ",", nbsp, true, true, NegativeMinus},
+}
+
+// Default is used when none is configured
+var Default = locales["en-US"]
+
+// Lookup returns the locale with the given tag, ignoring case and
+// accepting "_" for "-"
+func Lookup(tag string) (Locale, bool) {
+	tag = strings.ReplaceAll(strings.TrimSpace(tag), "_", "-")
+	for key, l := range locales {
+		if strings.EqualFold(key, tag) {
+			return l, true
+		}
+	}
+	return Locale{}, false
+}
+
+// Tags returns the tags of every supported locale, sorted
+func Tags() []string {
+	tags := make([]string, 0, len(locales))
+	for tag := range locales {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// ParseNegativeStyle reads "minus" or "parentheses"
+func ParseNegativeStyle(s string) (NegativeStyle, bool) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "minus":
+		return NegativeMinus, true
+	case "parentheses", "parens":
+		return NegativeParens, true
+	}
+	return 0, false
+}
+
+// Format formats an amount in minor units of c
+// Examples (en-US): 1234 EUR -> "€12.34", 120000 JPY -> "¥120,000",
+// -5 USD -> "-$0.05", 1234 BHD -> "BHD 1.234"
+// Examples (de-DE): 123456 EUR -> "1.234,56 €", -5 USD -> "-0,05 $"
+func (l Locale) Format(minor int, c currency.Currency) string {
+	negative := minor < 0
+	if negative {
+		minor = -minor
+	}
+
+	number := currency.Decimal(minor, c)
+	whole, fraction := number, ""
+	if i := strings.IndexByte(number, '.'); i >= 0 {
+		whole, fraction = number[:i], l.Decimal+number[i+1:]
+	}
+
+	var grouped strings.Builder
+	for i, r := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			grouped.WriteString(l.Group)
+		}
+		grouped.WriteRune(r)
+	}
+
+	symbol, space := c.Symbol, ""
+	if l.SymbolSpace {
+		space = nbsp
+	}
+	if symbol == "" {
+		symbol, space = c.Code, nbsp
+	}
+
+	body := symbol + space + grouped.String() + fraction
+	if l.SymbolAfter {
+		body = grouped.String() + fraction + space + symbol
+	}
+
+	switch {
+	case !negative:
+		return body
+	case l.Negative == NegativeParens:
+		return "(" + body + ")"
+	default:
+		return "-" + body
+	}
+}
+
+// Plain writes an amount in minor units of c with the locale's decimal
+// separator but no grouping or symbol, as filled into form fields
+// Examples: 123456 EUR -> "1234.56" (en-US), "1234,56" (de-DE)
+func (l Locale) Plain(minor int, c currency.Currency) string {
+	return strings.Replace(currency.Decimal(minor, c), ".", l.Decimal, 1)
+}
+
+// Parse parses an amount in c written in this locale. The symbol or code of
+// c may come before or after the number, and a negative amount may be
+// written with a leading or trailing minus or in parentheses. Parsing is
+// exact: more decimal places than c has is currency.ErrPrecision, and digit
+// grouping is checked, so "1,23,456" is rejected in en-US. A lone separator
+// that cannot be a thousands separator (not followed by exactly three digits)
+// is read as the decimal separator, so "12.5" is 12,50 in de-DE and "12,34"
+// is 12.34 in en-US, while "12,345" is twelve thousand.
+// Examples (de-DE): "1.234,56 €" EUR -> 123456, "-12,5" EUR -> -1250,
+// "1,5" JPY -> currency.ErrPrecision
+func (l Locale) Parse(s string, c currency.Currency) (currency.Money, error) {
+	text, negative, err := l.stripSign(s, c)
+	if err != nil {
+		return currency.Money{}, err
+	}
+	if text == "" {
+		return currency.Money{}, fmt.Errorf("amount cannot be empty")
+	}
+
+	whole, fraction, err := l.splitNumber(text)
+	if err != nil {
+		return currency.Money{}, fmt.Errorf("%w in %q", err, s)
+	}
+
+	fraction = strings.TrimRight(fraction, "0")
+	if len(fraction) > c.Exponent {
+		return currency.Money{}, fmt.Errorf("%w: %s has %d", currency.ErrPrecision, c.Code, c.Exponent)
+	}
+	minor, ok := new(big.Int).SetString(whole+fraction+strings.Repeat("0", c.Exponent-len(fraction)), 10)
+	if !ok || !minor.IsInt64() || minor.Int64() > math.MaxInt {
+		return currency.Money{}, currency.ErrOverflow
+	}
+	if negative {
+		minor.Neg(minor)
+	}
+	return currency.New(int(minor.Int64()), c), nil
+}
+
+// splitNumber splits a number written in this locale, without sign or
+// symbol, into its whole and fractional digits
+func (l Locale) splitNumber(text string) (whole, fraction string, err error) {
+	decimal, group := l.Decimal, normalizeSpaces(l.Group)
+	if group == "\u2019" {
+		group = "'"
+	}
+	text = strings.ReplaceAll(text, "\u2019", "'")
+
+	whole, fraction, hasFraction := strings.Cut(text, decimal)
+	if strings.Contains(fraction, decimal) {
+		return "", "", errInvalidNumber
+	}
+	if !hasFraction && strings.Count(whole, group) == 1 {
+		if _, after, _ := strings.Cut(whole, group); len(after) != 3 {
+			whole, fraction, hasFraction = strings.Cut(whole, group)
+		}
+	}
+
+	if strings.Contains(whole, group) {
+		parts := strings.Split(whole, group)
+		for i, part := range parts {
+			if (i == 0 && (len(part) == 0 || len(part) > 3)) || (i > 0 && len(part) != 3) {
+				return "", "", errGrouping
+			}
+		}
+		whole = strings.Join(parts, "")
+	}
+	if whole == "" && hasFraction {
+		whole = "0"
+	}
+	if !allDigits(whole) || !allDigits(fraction) || whole+fraction == "" {
+		return "", "", errInvalidNumber
+	}
+	return whole, fraction, nil
+}
+
+// stripSign removes the currency symbol or code, spaces, and any sign from
+// an amount, reporting whether it was negative
+func (l Locale) stripSign(s string, c currency.Currency) (string, bool, error) {
+	text := strings.TrimSpace(normalizeSpaces(s))
+	negative := false
+	setNegative := func() error {
+		if negative {
+			return fmt.Errorf("invalid amount format: %q", s)
+		}
+		negative = true
+		return nil
+	}
+
+	if strings.HasPrefix(text, "(") && strings.HasSuffix(text, ")") {
+		if err := setNegative(); err != nil {
+			return "", false, err
+		}
+		text = text[1 : len(text)-1]
+	}
+
+	affixes := []string{c.Code}
+	if c.Symbol != "" {
+		affixes = append(affixes, c.Symbol)
+	}
+	for changed := true; changed; {
+		changed = false
+		text = strings.TrimSpace(text)
+		for _, minus := range []string{"-", "\u2212"} {
+			if strings.HasPrefix(text, minus) {
+				text, changed = strings.TrimPrefix(text, minus), true
+			} else if strings.HasSuffix(text, minus) {
+				text, changed = strings.TrimSuffix(text, minus), true
+			} else {
+				continue
+			}
+			if err := setNegative(); err != nil {
+				return "", false, err
+			}
+		}
+		for _, affix := range affixes {
+			if len(text) >= len(affix) && strings.EqualFold(text[:len(affix)], affix) {
+				text, changed = text[len(affix):], true
+			} else if len(text) >= len(affix) && strings.EqualFold(text[len(text)-len(affix):], affix) {
+				text, changed = text[:len(text)-len(affix)], true
+			}
+		}
+	}
+	return text, negative, nil
+}
+
+// normalizeSpaces replaces non-breaking and thin spaces with plain spaces
+func normalizeSpaces(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || r == '\u00a0' || r == '\u202f' {
+			return ' '
+		}
+		return r
+	}, s)
+}
+
+// allDigits reports whether s consists only of ASCII digits
+func allDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,155 @@
+package locale
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/g-linville/budgeting/internal/currency"
+)
+
+func mustCurrency(t *testing.T, code string) currency.Currency {
+	t.Helper()
+	c, ok := currency.Lookup(code)
+	if !ok {
+		t.Fatalf("unknown currency %s", code)
+	}
+	return c
+}
+
+func mustLocale(t *testing.T, tag string) Locale {
+	t.Helper()
+	l, ok := Lookup(tag)
+	if !ok {
+		t.Fatalf("unknown locale %s", tag)
+	}
+	return l
+}
+
+func TestParse(t *testing.T) {
+	// errInvalid stands for any error that is not one of the sentinels
+	errInvalid := errors.New("invalid")
+
+	tests := []struct {
+		locale  string
+		code    string
+		input   string
+		want    int
+		wantErr error
+	}{
+		{"en-US", "USD", "12.34", 1234, nil},
+		{"en-US", "USD", "12", 1200, nil},
+		{"en-US", "USD", ".5", 50, nil},
+		{"en-US", "USD", "12.50", 1250, nil},
+		{"en-US", "USD", "12.3400", 1234, nil},
+		{"en-US", "USD", "$1,234.56", 123456, nil},
+		{"en-US", "USD", "1,234", 123400, nil},
+		{"en-US", "USD", "1,234,567.89", 123456789, nil},
+		{"en-US", "USD", "12,5", 1250, nil},
+		{"en-US", "USD", "USD 5", 500, nil},
+		{"en-US", "USD", "5 usd", 500, nil},
+		{"en-US", "USD", "-5", -500, nil},
+		{"en-US", "USD", "5-", -500, nil},
+		{"en-US", "USD", "-$5.00", -500, nil},
+		{"en-US", "USD", "($5.00)", -500, nil},
+		{"en-US", "USD", "\u22125", -500, nil},
+		{"en-US", "USD", "12.345", 0, currency.ErrPrecision},
+		{"en-US", "USD", "0.001", 0, currency.ErrPrecision},
+		{"en-US", "USD", "92233720368547758.08", 0, currency.ErrOverflow},
+		{"en-US", "USD", "99999999999999999999", 0, currency.ErrOverflow},
+		{"en-US", "USD", "1e3", 0, errInvalid},
+		{"en-US", "USD", "1E3", 0, errInvalid},
+		{"en-US", "USD", "0x10", 0, errInvalid},
+		{"en-US", "USD", "", 0, errInvalid},
+		{"en-US", "USD", "$", 0, errInvalid},
+		{"en-US", "USD", "--5", 0, errInvalid},
+		{"en-US", "USD", "(-5)", 0, errInvalid},
+		{"en-US", "USD", "1,23,456", 0, errInvalid},
+		{"en-US", "USD", "12,34", 1234, nil},
+		{"en-US", "USD", "12,345", 1234500, nil},
+		{"en-US", "USD", "1234,567.00", 0, errInvalid},
+		{"en-US", "USD", "12.50.1", 0, errInvalid},
+		{"en-US", "USD", "€5", 0, errInvalid},
+		{"en-US", "JPY", "¥1,200", 1200, nil},
+		{"en-US", "JPY", "1.5", 0, currency.ErrPrecision},
+		{"en-US", "BHD", "1.234", 1234, nil},
+		{"de-DE", "EUR", "1.234,56 €", 123456, nil},
+		{"de-DE", "EUR", "1.234", 123400, nil},
+		{"de-DE", "EUR", "12.5", 1250, nil},
+		{"de-DE", "EUR", "-12,5", -1250, nil},
+		{"de-DE", "EUR", "1,234.56", 0, errInvalid},
+		{"de-DE", "JPY", "1,5", 0, currency.ErrPrecision},
+		{"de-CH", "CHF", "1'234.50", 123450, nil},
+		{"de-CH", "CHF", "1\u2019234.50", 123450, nil},
+		{"fr-FR", "EUR", "1 234,56 €", 123456, nil},
+		{"fr-FR", "EUR", "1\u202f234,56\u00a0€", 123456, nil},
+		{"sv-SE", "SEK", "1\u00a0234,56 SEK", 123456, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.locale+" "+tt.input, func(t *testing.T) {
+			l, c := mustLocale(t, tt.locale), mustCurrency(t, tt.code)
+			got, err := l.Parse(tt.input, c)
+			switch {
+			case tt.wantErr == errInvalid:
+				if err == nil || errors.Is(err, currency.ErrPrecision) || errors.Is(err, currency.ErrOverflow) {
+					t.Fatalf("Parse(%q) error = %v, want a format error", tt.input, err)
+				}
+			case !errors.Is(err, tt.wantErr):
+				t.Fatalf("Parse(%q) error = %v, want %v", tt.input, err, tt.wantErr)
+			case err == nil && (got.Minor != tt.want || got.Currency != c):
+				t.Errorf("Parse(%q) = %d %s, want %d %s", tt.input, got.Minor, got.Currency.Code, tt.want, c.Code)
+			}
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	parens := mustLocale(t, "en-US")
+	parens.Negative = NegativeParens
+
+	tests := []struct {
+		locale Locale
+		code   string
+		minor  int
+		want   string
+	}{
+		{mustLocale(t, "en-US"), "USD", 123456, "$1,234.56"},
+		{mustLocale(t, "en-US"), "USD", -5, "-$0.05"},
+		{mustLocale(t, "en-US"), "EUR", 1234, "€12.34"},
+		{mustLocale(t, "en-US"), "JPY", 120000, "¥120,000"},
+		{mustLocale(t, "en-US"), "BHD", 1234, "BHD\u00a01.234"},
+		{mustLocale(t, "de-DE"), "EUR", 123456, "1.234,56\u00a0€"},
+		{mustLocale(t, "de-DE"), "USD", -5, "-0,05\u00a0$"},
+		{mustLocale(t, "fr-FR"), "EUR", 123456, "1\u202f234,56\u00a0€"},
+		{mustLocale(t, "de-CH"), "CHF", 123450, "CHF\u00a01'234.50"},
+		{parens, "USD", -1234, "($12.34)"},
+	}
+	for _, tt := range tests {
+		if got := tt.locale.Format(tt.minor, mustCurrency(t, tt.code)); got != tt.want {
+			t.Errorf("%s Format(%d %s) = %q, want %q", tt.locale.Tag, tt.minor, tt.code, got, tt.want)
+		}
+	}
+}
+
+// Every locale must read back what it writes, for every currency
+func TestFormatParseRoundTrip(t *testing.T) {
+	amounts := []int{0, 5, -5, 1234, 123456789, -123456789}
+	for _, tag := range Tags() {
+		for _, negative := range []NegativeStyle{NegativeMinus, NegativeParens} {
+			l := mustLocale(t, tag)
+			l.Negative = negative
+			for _, c := range currency.All() {
+				for _, minor := range amounts {
+					formatted := l.Format(minor, c)
+					got, err := l.Parse(formatted, c)
+					if err != nil || got.Minor != minor {
+						t.Errorf("%s: Parse(Format(%d %s) = %q) = %d, %v", tag, minor, c.Code, formatted, got.Minor, err)
+					}
+					plain := l.Plain(minor, c)
+					if got, err := l.Parse(plain, c); err != nil || got.Minor != minor {
+						t.Errorf("%s: Parse(Plain(%d %s) = %q) = %d, %v", tag, minor, c.Code, plain, got.Minor, err)
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// Attachment is a receipt or document attached to an expense. The file is
+// kept in the attachment store under its content hash.
+type Attachment struct {
+	ID          uint      `gorm:"primaryKey"`
+	ExpenseID   uint      `gorm:"index;not null"`
+	Hash        string    `gorm:"index;not null"` // Hex SHA-256 of the contents
+	FileName    string    `gorm:"not null"`       // Original name, for downloads
+	ContentType string    `gorm:"not null"`
+	Size        int64     `gorm:"not null"` // Bytes
+	CreatedAt   time.Time `gorm:"autoCreateTime"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+type AuditEntry struct {
+	ID         uint       `gorm:"primaryKey"`
+	EntityType string     `gorm:"not null;index:idx_audit_log_entity"` // 'expense', 'income', 'category', ...
+	EntityID   uint       `gorm:"not null;index:idx_audit_log_entity"`
+	Action     string     `gorm:"not null"`  // 'create', 'update', 'delete', 'undo'
+	Before     string     `gorm:"type:text"` // JSON snapshot, empty for creates
+	After      string     `gorm:"type:text"` // JSON snapshot, empty when the record no longer exists
+	UndoneAt   *time.Time // Set once this change has been undone
+	CreatedAt  time.Time  `gorm:"autoCreateTime;index"`
+}
+
+func (AuditEntry) TableName() string {
+	return "audit_log"
+}
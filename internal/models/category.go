@@ -1,14 +1,35 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Category kinds. Expense categories apply to expenses, income categories to
+// income.
+const (
+	CategoryKindExpense = "expense"
+	CategoryKindIncome  = "income"
+)
 
 type Category struct {
-	ID        uint      `gorm:"primaryKey"`
-	Name      string    `gorm:"uniqueIndex;not null"`
-	Color     string    `gorm:"size:7"`
-	CreatedAt time.Time `gorm:"autoCreateTime"`
+	ID        uint           `gorm:"primaryKey"`
+	Name      string         `gorm:"not null"` // Unique per kind, ignoring case
+	Color     string         `gorm:"size:7"`
+	ParentID  *uint          `gorm:"index"`                    // nil for a top-level category
+	Kind      string         `gorm:"not null;default:expense"` // CategoryKindExpense or CategoryKindIncome
+	Archived  bool           `gorm:"not null;default:false"`   // Hidden from entry forms, kept in reports
+	CreatedAt time.Time      `gorm:"autoCreateTime"`
+	DeletedAt gorm.DeletedAt `gorm:"index"` // Set when moved to the trash
 
 	// Relationships
 	Expenses          []Expense          `gorm:"foreignKey:CategoryID"`
 	RecurringExpenses []RecurringExpense `gorm:"foreignKey:CategoryID"`
+	Incomes           []Income           `gorm:"foreignKey:CategoryID"`
+}
+
+// IsIncome reports whether the category is for income
+func (c Category) IsIncome() bool {
+	return c.Kind == CategoryKindIncome
 }
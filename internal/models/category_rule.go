@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// CategoryRule categorizes and tags expenses automatically. Conditions left
+// nil or empty match anything; at least one is always set.
+type CategoryRule struct {
+	ID       uint   `gorm:"primaryKey"`
+	Name     string `gorm:"not null"`
+	Priority int    `gorm:"not null;default:0;index"` // Lower runs first
+	Enabled  bool   `gorm:"not null;default:true"`
+
+	// Conditions
+	NameContains string
+	NameRegex    string
+	MinAmount    *int  // Minor units, inclusive
+	MaxAmount    *int  // Minor units, inclusive
+	PayeeID      *uint // Nullable FK
+	Weekday      *int  // time.Weekday of the expense date
+
+	// Actions
+	CategoryID *uint  // Nullable FK; nil leaves the category alone
+	Tags       string // Comma-separated tag names to add
+
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+
+	// Relationships
+	Payee    *Payee    `gorm:"foreignKey:PayeeID;constraint:OnDelete:CASCADE"`
+	Category *Category `gorm:"foreignKey:CategoryID;constraint:OnDelete:SET NULL"`
+}
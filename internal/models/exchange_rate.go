@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Exchange rate sources
+const (
+	RateSourceManual = "manual"
+	RateSourceCSV    = "csv"
+)
+
+// ExchangeRate is the value of one unit of a currency in the base currency
+// as of a date. Transactions use the latest rate on or before their date.
+type ExchangeRate struct {
+	ID        uint      `gorm:"primaryKey"`
+	Currency  string    `gorm:"size:3;not null;uniqueIndex:idx_exchange_rates_currency_date"` // ISO 4217 code
+	RateDate  time.Time `gorm:"type:date;not null;uniqueIndex:idx_exchange_rates_currency_date"`
+	Rate      string    `gorm:"not null"`                // Exact decimal, e.g. "1.0845"
+	Source    string    `gorm:"not null;default:manual"` // RateSourceManual or RateSourceCSV
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
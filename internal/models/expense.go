@@ -1,18 +1,32 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type Expense struct {
-	ID          uint      `gorm:"primaryKey"`
-	Name        string    `gorm:"not null"`
-	Amount      int       `gorm:"not null"` // Stored as cents
-	CategoryID  *uint     `gorm:"index"`    // Nullable FK
-	ExpenseDate time.Time `gorm:"type:date;index;not null"`
-	Notes       string    `gorm:"type:text"`
-	RecurringID *uint     `gorm:"index"`
-	CreatedAt   time.Time `gorm:"autoCreateTime"`
+	ID             uint           `gorm:"primaryKey"`
+	Name           string         `gorm:"not null"`
+	Amount         int            `gorm:"not null"`                   // In minor units of the base currency
+	Currency       string         `gorm:"size:3;not null;default:''"` // ISO 4217 code entered in; "" for the base currency
+	OriginalAmount int            `gorm:"not null;default:0"`         // In minor units of Currency
+	CategoryID     *uint          `gorm:"index"`                      // Nullable FK
+	ExpenseDate    time.Time      `gorm:"type:date;index;not null"`
+	Notes          string         `gorm:"type:text"`
+	RecurringID    *uint          `gorm:"index"`
+	PayeeID        *uint          `gorm:"index"`                  // Set from the payee aliases matching Name
+	Reimbursable   bool           `gorm:"not null;default:false"` // Expected to be paid back
+	CreatedAt      time.Time      `gorm:"autoCreateTime"`
+	DeletedAt      gorm.DeletedAt `gorm:"index"` // Set when moved to the trash
 
 	// Relationships
+	Tags             []Tag             `gorm:"many2many:expense_tags;constraint:OnDelete:CASCADE"`
+	Splits           []ExpenseSplit    `gorm:"foreignKey:ExpenseID;constraint:OnDelete:CASCADE"` // Empty unless split across categories
+	Attachments      []Attachment      `gorm:"foreignKey:ExpenseID;constraint:OnDelete:CASCADE"`
+	Refunds          []Refund          `gorm:"foreignKey:ExpenseID;constraint:OnDelete:CASCADE"`
 	Category         *Category         `gorm:"foreignKey:CategoryID;constraint:OnDelete:SET NULL"`
+	Payee            *Payee            `gorm:"foreignKey:PayeeID;constraint:OnDelete:SET NULL"`
 	RecurringExpense *RecurringExpense `gorm:"foreignKey:RecurringID;constraint:OnDelete:SET NULL"`
 }
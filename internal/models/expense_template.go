@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// ExpenseTemplate is a saved favorite that fills in the quick-add expense form
+type ExpenseTemplate struct {
+	ID         uint      `gorm:"primaryKey"`
+	Name       string    `gorm:"not null"`
+	Amount     *int      // Minor units; nil to leave the amount blank
+	CategoryID *uint     // Nullable FK
+	CreatedAt  time.Time `gorm:"autoCreateTime"`
+
+	// Relationships
+	Category *Category `gorm:"foreignKey:CategoryID;constraint:OnDelete:SET NULL"`
+}
@@ -1,16 +1,26 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type Income struct {
-	ID          uint      `gorm:"primaryKey"`
-	Name        string    `gorm:"not null"`
-	Amount      int       `gorm:"not null"` // Stored as cents
-	IncomeDate  time.Time `gorm:"type:date;index;not null"`
-	Notes       string    `gorm:"type:text"`
-	RecurringID *uint     `gorm:"index"`
-	CreatedAt   time.Time `gorm:"autoCreateTime"`
+	ID             uint           `gorm:"primaryKey"`
+	Name           string         `gorm:"not null"`
+	Amount         int            `gorm:"not null"`                   // In minor units of the base currency
+	Currency       string         `gorm:"size:3;not null;default:''"` // ISO 4217 code entered in; "" for the base currency
+	OriginalAmount int            `gorm:"not null;default:0"`         // In minor units of Currency
+	IncomeDate     time.Time      `gorm:"type:date;index;not null"`
+	Notes          string         `gorm:"type:text"`
+	CategoryID     *uint          `gorm:"index"` // Nullable FK to an income category
+	RecurringID    *uint          `gorm:"index"`
+	CreatedAt      time.Time      `gorm:"autoCreateTime"`
+	DeletedAt      gorm.DeletedAt `gorm:"index"` // Set when moved to the trash
 
 	// Relationships
+	Category        *Category        `gorm:"foreignKey:CategoryID;constraint:OnDelete:SET NULL"`
+	Tags            []Tag            `gorm:"many2many:income_tags;constraint:OnDelete:CASCADE"`
 	RecurringIncome *RecurringIncome `gorm:"foreignKey:RecurringID;constraint:OnDelete:SET NULL"`
 }
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// Payee is a merchant or other party that expenses are paid to
+type Payee struct {
+	ID        uint      `gorm:"primaryKey"`
+	Name      string    `gorm:"uniqueIndex;not null"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+
+	// Relationships
+	Aliases []PayeeAlias `gorm:"foreignKey:PayeeID;constraint:OnDelete:CASCADE"`
+}
+
+// PayeeAlias is a pattern that links matching expense names to a payee
+type PayeeAlias struct {
+	ID        uint      `gorm:"primaryKey"`
+	PayeeID   uint      `gorm:"index;not null"`
+	Pattern   string    `gorm:"uniqueIndex;not null"` // Normalized; "*" matches anything
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
@@ -3,9 +3,9 @@ package models
 import "time"
 
 type RecurringExpense struct {
-	ID         uint       `gorm:"primaryKey"`
-	Name       string     `gorm:"not null"`
-	Amount     int        `gorm:"not null"` // Stored as cents
+	ID         uint   `gorm:"primaryKey"`
+	Name       string `gorm:"not null"`
+	Amount     int    `gorm:"not null"` // In minor units of the base currency
 	CategoryID *uint
 	Cadence    string     `gorm:"not null"` // 'monthly', 'semi-annual', 'annual'
 	StartDate  time.Time  `gorm:"type:date;not null"`
@@ -22,7 +22,7 @@ type RecurringExpense struct {
 type RecurringIncome struct {
 	ID        uint       `gorm:"primaryKey"`
 	Name      string     `gorm:"not null"`
-	Amount    int        `gorm:"not null"` // Stored as cents
+	Amount    int        `gorm:"not null"` // In minor units of the base currency
 	Cadence   string     `gorm:"not null"` // 'monthly', 'semi-annual', 'annual'
 	StartDate time.Time  `gorm:"type:date;not null"`
 	NextDate  time.Time  `gorm:"type:date;index;not null"`
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// Refund kinds
+const (
+	RefundKindRefund        = "refund"        // Returned by the merchant
+	RefundKindReimbursement = "reimbursement" // Paid back by someone else, e.g. an employer
+)
+
+// Refund is money returned for an expense. It reduces the expense's spending
+// as of the refund date rather than counting as income.
+type Refund struct {
+	ID         uint      `gorm:"primaryKey"`
+	ExpenseID  uint      `gorm:"index;not null"`
+	Kind       string    `gorm:"not null"` // RefundKindRefund or RefundKindReimbursement
+	Amount     int       `gorm:"not null"` // In minor units of the base currency
+	RefundDate time.Time `gorm:"type:date;index;not null"`
+	Notes      string    `gorm:"type:text"`
+	CreatedAt  time.Time `gorm:"autoCreateTime"`
+}
@@ -0,0 +1,13 @@
+package models
+
+// ExpenseSplit is one line of an expense split across several categories
+type ExpenseSplit struct {
+	ID             uint  `gorm:"primaryKey"`
+	ExpenseID      uint  `gorm:"index;not null"`
+	CategoryID     *uint `gorm:"index"`              // Nullable FK
+	Amount         int   `gorm:"not null"`           // In minor units of the base currency
+	OriginalAmount int   `gorm:"not null;default:0"` // In minor units of the expense's currency
+
+	// Relationships
+	Category *Category `gorm:"foreignKey:CategoryID;constraint:OnDelete:SET NULL"`
+}
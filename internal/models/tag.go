@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+// Tag is a free-form label on expenses and incomes
+type Tag struct {
+	ID        uint      `gorm:"primaryKey"`
+	Name      string    `gorm:"uniqueIndex;not null"` // Normalized, e.g. "vacation-2026"
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
@@ -0,0 +1,264 @@
+// Package payees links free-text expense names to payees. Each payee matches
+// its own name plus any aliases; an alias is a normalized name in which "*"
+// matches any run of characters, e.g. "amzn mktp*".
+package payees
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/g-linville/budgeting/internal/audit"
+	"github.com/g-linville/budgeting/internal/models"
+	"github.com/g-linville/budgeting/internal/utils"
+	"gorm.io/gorm"
+)
+
+// ErrSamePayee is returned when merging a payee into itself
+var ErrSamePayee = errors.New("cannot merge a payee into itself")
+
+// NormalizePattern normalizes each part of an alias between wildcards the
+// same way as expense names, so "AMZN Mktp*" becomes "amzn mktp*"
+func NormalizePattern(pattern string) string {
+	parts := strings.Split(pattern, "*")
+	for i, part := range parts {
+		parts[i] = utils.NormalizeName(part)
+	}
+	return strings.Join(parts, "*")
+}
+
+// matchPattern reports whether a normalized name matches a normalized pattern
+func matchPattern(pattern, name string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == name
+	}
+
+	// The first part anchors the start and the last the end; the ones in
+	// between must appear in order
+	if !strings.HasPrefix(name, parts[0]) {
+		return false
+	}
+	name = name[len(parts[0]):]
+	last := parts[len(parts)-1]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(name, part)
+		if i < 0 {
+			return false
+		}
+		name = name[i+len(part):]
+	}
+	return strings.HasSuffix(name, last)
+}
+
+// rule is one pattern that links names to a payee
+type rule struct {
+	payeeID uint
+	pattern string
+}
+
+// Matcher finds the payee for an expense name
+type Matcher struct {
+	rules []rule
+}
+
+// Load reads every payee and alias. Exact names are tried before wildcard
+// patterns, and longer patterns before shorter ones, so the most specific
+// rule wins.
+func Load(db *gorm.DB) (*Matcher, error) {
+	var payees []models.Payee
+	if err := db.Preload("Aliases").Find(&payees).Error; err != nil {
+		return nil, err
+	}
+
+	m := &Matcher{}
+	for _, p := range payees {
+		m.rules = append(m.rules, rule{payeeID: p.ID, pattern: utils.NormalizeName(p.Name)})
+		for _, a := range p.Aliases {
+			m.rules = append(m.rules, rule{payeeID: a.PayeeID, pattern: a.Pattern})
+		}
+	}
+	sort.SliceStable(m.rules, func(i, j int) bool {
+		wi, wj := strings.Contains(m.rules[i].pattern, "*"), strings.Contains(m.rules[j].pattern, "*")
+		if wi != wj {
+			return !wi
+		}
+		return len(m.rules[i].pattern) > len(m.rules[j].pattern)
+	})
+	return m, nil
+}
+
+// Match returns the ID of the payee for an expense name, or nil if none
+// matches
+func (m *Matcher) Match(name string) *uint {
+	normalized := utils.NormalizeName(name)
+	for _, r := range m.rules {
+		if matchPattern(r.pattern, normalized) {
+			id := r.payeeID
+			return &id
+		}
+	}
+	return nil
+}
+
+// Match loads the payees and matches a single expense name
+func Match(db *gorm.DB, name string) (*uint, error) {
+	m, err := Load(db)
+	if err != nil {
+		return nil, err
+	}
+	return m.Match(name), nil
+}
+
+// Apply links every expense without a payee (including those in the trash)
+// to the payee matching its name, returning how many were linked. It is run
+// after payees or aliases are added. Each link is recorded in the audit log
+// so earlier changes to the expense can still be undone.
+func Apply(tx *gorm.DB) (int, error) {
+	return apply(tx, nil)
+}
+
+// apply is Apply for expenses that may have just been unlinked by Relink.
+// before holds their snapshots from prior to unlinking, so each expense gets
+// a single audit entry covering both steps, or none if it ends up linked to
+// the same payee again.
+func apply(tx *gorm.DB, before map[uint]string) (int, error) {
+	m, err := Load(tx)
+	if err != nil {
+		return 0, err
+	}
+
+	var unlinked []models.Expense
+	if err := tx.Unscoped().Select("id", "name").Where("payee_id IS NULL").Find(&unlinked).Error; err != nil {
+		return 0, err
+	}
+
+	linked := 0
+	for _, e := range unlinked {
+		snapshot, unlinkedNow := before[e.ID]
+		payeeID := m.Match(e.Name)
+		if payeeID == nil {
+			if unlinkedNow {
+				if err := audit.Record(tx, audit.EntityExpense, e.ID, audit.ActionUpdate, snapshot); err != nil {
+					return 0, err
+				}
+			}
+			continue
+		}
+
+		if !unlinkedNow {
+			if snapshot, err = audit.Snapshot(tx, audit.EntityExpense, e.ID); err != nil {
+				return 0, err
+			}
+		}
+		if err := tx.Unscoped().Model(&models.Expense{}).Where("id = ?", e.ID).Update("payee_id", *payeeID).Error; err != nil {
+			return 0, err
+		}
+		if err := recordChange(tx, e.ID, snapshot); err != nil {
+			return 0, err
+		}
+		linked++
+	}
+	return linked, nil
+}
+
+// recordChange records an audit update for an expense unless it is back
+// where it was before
+func recordChange(tx *gorm.DB, expenseID uint, before string) error {
+	current, err := audit.Snapshot(tx, audit.EntityExpense, expenseID)
+	if err != nil {
+		return err
+	}
+	if audit.Matches(current, before) {
+		return nil
+	}
+	return audit.Record(tx, audit.EntityExpense, expenseID, audit.ActionUpdate, before)
+}
+
+// snapshotPayeeExpenses takes audit snapshots of every expense linked to a
+// payee, including those in the trash
+func snapshotPayeeExpenses(tx *gorm.DB, payeeID uint) (map[uint]string, error) {
+	var ids []uint
+	if err := tx.Unscoped().Model(&models.Expense{}).Where("payee_id = ?", payeeID).Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+	before := make(map[uint]string, len(ids))
+	for _, id := range ids {
+		snapshot, err := audit.Snapshot(tx, audit.EntityExpense, id)
+		if err != nil {
+			return nil, err
+		}
+		before[id] = snapshot
+	}
+	return before, nil
+}
+
+// Relink unlinks a payee's expenses and matches them again, for when one of
+// its aliases is removed
+func Relink(tx *gorm.DB, payeeID uint) error {
+	before, err := snapshotPayeeExpenses(tx, payeeID)
+	if err != nil {
+		return err
+	}
+	if err := tx.Unscoped().Model(&models.Expense{}).Where("payee_id = ?", payeeID).Update("payee_id", nil).Error; err != nil {
+		return err
+	}
+	_, err = apply(tx, before)
+	return err
+}
+
+// Merge folds one payee into another: its expenses and aliases move over,
+// its name becomes an alias of the target, and it is deleted
+func Merge(tx *gorm.DB, fromID, intoID uint) error {
+	if fromID == intoID {
+		return ErrSamePayee
+	}
+
+	var from, into models.Payee
+	if err := tx.First(&from, fromID).Error; err != nil {
+		return err
+	}
+	if err := tx.First(&into, intoID).Error; err != nil {
+		return err
+	}
+
+	before, err := snapshotPayeeExpenses(tx, from.ID)
+	if err != nil {
+		return err
+	}
+	if err := tx.Unscoped().Model(&models.Expense{}).Where("payee_id = ?", from.ID).Update("payee_id", into.ID).Error; err != nil {
+		return err
+	}
+	ids := make([]uint, 0, len(before))
+	for id := range before {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for _, id := range ids {
+		if err := audit.Record(tx, audit.EntityExpense, id, audit.ActionUpdate, before[id]); err != nil {
+			return err
+		}
+	}
+	if err := tx.Model(&models.PayeeAlias{}).Where("payee_id = ?", from.ID).Update("payee_id", into.ID).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&models.CategoryRule{}).Where("payee_id = ?", from.ID).Update("payee_id", into.ID).Error; err != nil {
+		return err
+	}
+
+	// Keep matching the old name, unless the target already does
+	pattern := utils.NormalizeName(from.Name)
+	if pattern != utils.NormalizeName(into.Name) {
+		var count int64
+		if err := tx.Model(&models.PayeeAlias{}).Where("pattern = ?", pattern).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			if err := tx.Create(&models.PayeeAlias{PayeeID: into.ID, Pattern: pattern}).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Delete(&from).Error
+}
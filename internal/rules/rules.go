@@ -0,0 +1,215 @@
+// Package rules applies user-defined categorization rules to expenses. Rules
+// run in ascending priority (then creation order). The first matching rule
+// that has a category decides the category; every matching rule adds its
+// tags.
+package rules
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/g-linville/budgeting/internal/models"
+	"gorm.io/gorm"
+)
+
+// Input is what rules are matched against
+type Input struct {
+	Name    string
+	Amount  int // Minor units of the base currency
+	Date    time.Time
+	PayeeID *uint
+}
+
+// Result is what the matching rules assign
+type Result struct {
+	CategoryID *uint
+	Tags       []string
+	RuleIDs    []uint // Every rule that matched
+}
+
+// rule is a CategoryRule with its regular expression compiled
+type rule struct {
+	models.CategoryRule
+	regex *regexp.Regexp
+}
+
+// Engine holds the enabled rules in the order they run
+type Engine struct {
+	rules []rule
+}
+
+// CompileRegex compiles a rule's name pattern, which matches case-insensitively
+func CompileRegex(pattern string) (*regexp.Regexp, error) {
+	return regexp.Compile("(?i)" + pattern)
+}
+
+// Load reads the enabled rules. A rule whose category is in the trash,
+// archived or not an expense category keeps its tags but sets no category,
+// leaving the choice to the next matching rule.
+func Load(db *gorm.DB) (*Engine, error) {
+	var list []models.CategoryRule
+	if err := db.Where("enabled = ?", true).Order("priority, id").Find(&list).Error; err != nil {
+		return nil, err
+	}
+
+	var usable []uint
+	if err := db.Model(&models.Category{}).
+		Where("archived = ? AND kind = ?", false, models.CategoryKindExpense).
+		Pluck("id", &usable).Error; err != nil {
+		return nil, err
+	}
+	assignable := make(map[uint]bool, len(usable))
+	for _, id := range usable {
+		assignable[id] = true
+	}
+
+	e := &Engine{}
+	for _, r := range list {
+		if r.CategoryID != nil && !assignable[*r.CategoryID] {
+			r.CategoryID = nil
+		}
+		compiled := rule{CategoryRule: r}
+		if r.NameRegex != "" {
+			regex, err := CompileRegex(r.NameRegex)
+			if err != nil {
+				return nil, fmt.Errorf("rule %d: %w", r.ID, err)
+			}
+			compiled.regex = regex
+		}
+		e.rules = append(e.rules, compiled)
+	}
+	return e, nil
+}
+
+// matches reports whether every condition set on the rule holds for in
+func (r rule) matches(in Input) bool {
+	if r.NameContains != "" && !strings.Contains(strings.ToLower(in.Name), strings.ToLower(r.NameContains)) {
+		return false
+	}
+	if r.regex != nil && !r.regex.MatchString(in.Name) {
+		return false
+	}
+	if r.MinAmount != nil && in.Amount < *r.MinAmount {
+		return false
+	}
+	if r.MaxAmount != nil && in.Amount > *r.MaxAmount {
+		return false
+	}
+	if r.PayeeID != nil && (in.PayeeID == nil || *in.PayeeID != *r.PayeeID) {
+		return false
+	}
+	if r.Weekday != nil && int(in.Date.Weekday()) != *r.Weekday {
+		return false
+	}
+	return true
+}
+
+// Apply runs the rules against in
+func (e *Engine) Apply(in Input) Result {
+	var result Result
+	seen := make(map[string]bool)
+	for _, r := range e.rules {
+		if !r.matches(in) {
+			continue
+		}
+		result.RuleIDs = append(result.RuleIDs, r.ID)
+		if result.CategoryID == nil && r.CategoryID != nil {
+			id := *r.CategoryID
+			result.CategoryID = &id
+		}
+		for _, tag := range SplitTags(r.Tags) {
+			if !seen[tag] {
+				seen[tag] = true
+				result.Tags = append(result.Tags, tag)
+			}
+		}
+	}
+	return result
+}
+
+// SplitTags parses a rule's stored comma-separated tags
+func SplitTags(tags string) []string {
+	var list []string
+	for _, tag := range strings.Split(tags, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			list = append(list, tag)
+		}
+	}
+	return list
+}
+
+// MergeTags appends the tags a rule assigned to those the user entered,
+// skipping any already present
+func MergeTags(tags, added []string) []string {
+	has := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		has[tag] = true
+	}
+	for _, tag := range added {
+		if !has[tag] {
+			has[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// Change is how the rules would change an existing expense
+type Change struct {
+	ExpenseID      uint
+	Name           string
+	Date           time.Time
+	Amount         int   // Minor units of the base currency
+	FromCategoryID *uint // Current category
+	ToCategoryID   *uint // nil when the category is unchanged
+	AddTags        []string
+}
+
+// Preview runs the rules over existing expenses (excluding the trash and
+// split expenses) and returns those that would change, newest first. Unless
+// overwrite is set, only uncategorized expenses get a category.
+func Preview(db *gorm.DB, e *Engine, overwrite bool) ([]Change, error) {
+	var expenses []models.Expense
+	if err := db.Preload("Tags").
+		Where("NOT EXISTS (SELECT 1 FROM expense_splits s WHERE s.expense_id = expenses.id)").
+		Order("expense_date DESC, id DESC").
+		Find(&expenses).Error; err != nil {
+		return nil, err
+	}
+
+	var changes []Change
+	for _, expense := range expenses {
+		result := e.Apply(Input{Name: expense.Name, Amount: expense.Amount, Date: expense.ExpenseDate, PayeeID: expense.PayeeID})
+		if len(result.RuleIDs) == 0 {
+			continue
+		}
+
+		change := Change{
+			ExpenseID:      expense.ID,
+			Name:           expense.Name,
+			Date:           expense.ExpenseDate,
+			Amount:         expense.Amount,
+			FromCategoryID: expense.CategoryID,
+		}
+		if result.CategoryID != nil && (overwrite || expense.CategoryID == nil) &&
+			(expense.CategoryID == nil || *expense.CategoryID != *result.CategoryID) {
+			change.ToCategoryID = result.CategoryID
+		}
+		has := make(map[string]bool, len(expense.Tags))
+		for _, tag := range expense.Tags {
+			has[tag.Name] = true
+		}
+		for _, tag := range result.Tags {
+			if !has[tag] {
+				change.AddTags = append(change.AddTags, tag)
+			}
+		}
+
+		if change.ToCategoryID != nil || len(change.AddTags) > 0 {
+			changes = append(changes, change)
+		}
+	}
+	return changes, nil
+}
@@ -0,0 +1,73 @@
+package rules
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/g-linville/budgeting/internal/database/dbtest"
+	"github.com/g-linville/budgeting/internal/models"
+	"gorm.io/gorm"
+)
+
+// A rule must never assign a category that cannot be chosen by hand: one in
+// the trash, an archived one, or an income category
+func TestLoadSkipsUnassignableCategories(t *testing.T) {
+	dbtest.RunMigrated(t, func(t *testing.T, db *gorm.DB) {
+		create := func(value interface{}) {
+			t.Helper()
+			if err := db.Create(value).Error; err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		coffee := models.Category{Name: "Coffee", Kind: models.CategoryKindExpense}
+		trashed := models.Category{Name: "Old coffee", Kind: models.CategoryKindExpense}
+		archived := models.Category{Name: "Cafes", Kind: models.CategoryKindExpense, Archived: true}
+		salary := models.Category{Name: "Salary", Kind: models.CategoryKindIncome}
+		for _, c := range []*models.Category{&coffee, &trashed, &archived, &salary} {
+			create(c)
+		}
+		if err := db.Delete(&trashed).Error; err != nil {
+			t.Fatal(err)
+		}
+
+		// Each unassignable rule runs before the one that should decide
+		create(&[]models.CategoryRule{
+			{Name: "Trashed", Priority: 1, Enabled: true, NameContains: "latte", CategoryID: &trashed.ID, Tags: "caffeine"},
+			{Name: "Archived", Priority: 2, Enabled: true, NameContains: "latte", CategoryID: &archived.ID},
+			{Name: "Income", Priority: 3, Enabled: true, NameContains: "latte", CategoryID: &salary.ID},
+			{Name: "Coffee", Priority: 4, Enabled: true, NameContains: "latte", CategoryID: &coffee.ID},
+			{Name: "Mocha", Priority: 5, Enabled: true, NameContains: "mocha", CategoryID: &trashed.ID, Tags: "chocolate"},
+		})
+
+		engine, err := Load(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		latte := engine.Apply(Input{Name: "Oat latte", Amount: 450, Date: time.Now()})
+		if latte.CategoryID == nil || *latte.CategoryID != coffee.ID {
+			t.Errorf("latte category = %v, want %d", latte.CategoryID, coffee.ID)
+		}
+		if !slices.Equal(latte.Tags, []string{"caffeine"}) || len(latte.RuleIDs) != 4 {
+			t.Errorf("latte = %+v, want the trashed rule's tag and 4 matching rules", latte)
+		}
+
+		mocha := engine.Apply(Input{Name: "Mocha", Amount: 500, Date: time.Now()})
+		if mocha.CategoryID != nil || !slices.Equal(mocha.Tags, []string{"chocolate"}) {
+			t.Errorf("mocha = %+v, want no category and the tag", mocha)
+		}
+
+		// Re-applying with overwrite must not move an expense out of a live
+		// category into the trashed one
+		create(&models.Expense{Name: "Mocha", Amount: 500, ExpenseDate: time.Now(), CategoryID: &coffee.ID})
+		changes, err := Preview(db, engine, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(changes) != 1 || changes[0].ToCategoryID != nil || !slices.Equal(changes[0].AddTags, []string{"chocolate"}) {
+			t.Errorf("changes = %+v, want only the tag added", changes)
+		}
+	})
+}
@@ -0,0 +1,302 @@
+package search
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/g-linville/budgeting/internal/database"
+	"gorm.io/gorm"
+)
+
+// Markers wrapped around matched terms by the database. Control characters
+// never occur in user text, so they survive HTML escaping and can then be
+// swapped for <mark> tags safely.
+const (
+	markStart = "\x02"
+	markEnd   = "\x03"
+)
+
+// Result is a transaction matching a search
+type Result struct {
+	Type     string // "expense" or "income"
+	ID       uint
+	Name     template.HTML // Name with matched terms highlighted
+	Snippet  template.HTML // Excerpt of the notes around the matches; empty if the notes did not match
+	Amount   int           // Minor units of the base currency
+	Date     string        // YYYY-MM-DD
+	Category *string
+}
+
+// resultRow is a row of the search query before highlighting
+type resultRow struct {
+	Type         string
+	ID           uint
+	Name         string
+	Snippet      string
+	Amount       int
+	Date         string
+	CategoryName *string
+}
+
+// Search finds expenses and incomes whose name or notes contain every word
+// of text, matching word prefixes, best matches first. Matches in the name
+// rank above matches in the notes. On SQLite built without FTS5, words match
+// anywhere in the text rather than at word starts.
+func Search(db *gorm.DB, text string, limit int) ([]Result, error) {
+	terms := searchTerms(text)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	var rows []resultRow
+	var err error
+	switch {
+	case database.IsPostgres(db):
+		rows, err = searchPostgres(db, terms, limit)
+	case database.HasFTS5(db):
+		rows, err = searchSQLite(db, terms, limit)
+	default:
+		rows, err = searchLike(db, terms, limit)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]Result, 0, len(rows))
+	for _, row := range rows {
+		result := Result{
+			Type:     row.Type,
+			ID:       row.ID,
+			Name:     highlight(row.Name),
+			Amount:   row.Amount,
+			Date:     row.Date,
+			Category: row.CategoryName,
+		}
+		if strings.Contains(row.Snippet, markStart) {
+			result.Snippet = highlight(row.Snippet)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// searchTerms splits text into lowercase words of letters and digits
+func searchTerms(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// searchSQLite queries the FTS5 indexes. Each term becomes a quoted prefix
+// query, so punctuation in the input can never be read as FTS5 syntax.
+func searchSQLite(db *gorm.DB, terms []string, limit int) ([]resultRow, error) {
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = `"` + term + `"*`
+	}
+	match := strings.Join(quoted, " ")
+
+	query := `
+		SELECT * FROM (
+			SELECT 'expense' AS type, e.id,
+				highlight(expenses_fts, 0, @start, @end) AS name,
+				snippet(expenses_fts, 1, @start, @end, '…', 12) AS snippet,
+				e.amount, substr(e.expense_date, 1, 10) AS date, c.name AS category_name,
+				bm25(expenses_fts, 10.0, 1.0) AS rank
+			FROM expenses_fts
+			JOIN expenses e ON e.id = expenses_fts.rowid
+			LEFT JOIN categories c ON c.id = e.category_id AND c.deleted_at IS NULL
+			WHERE expenses_fts MATCH @match AND e.deleted_at IS NULL
+			UNION ALL
+			SELECT 'income' AS type, i.id,
+				highlight(incomes_fts, 0, @start, @end) AS name,
+				snippet(incomes_fts, 1, @start, @end, '…', 12) AS snippet,
+				i.amount, substr(i.income_date, 1, 10) AS date, NULL AS category_name,
+				bm25(incomes_fts, 10.0, 1.0) AS rank
+			FROM incomes_fts
+			JOIN incomes i ON i.id = incomes_fts.rowid
+			WHERE incomes_fts MATCH @match AND i.deleted_at IS NULL
+		)
+		ORDER BY rank, date DESC
+		LIMIT @limit`
+
+	var rows []resultRow
+	err := db.Raw(query, map[string]interface{}{
+		"start": markStart,
+		"end":   markEnd,
+		"match": match,
+		"limit": limit,
+	}).Scan(&rows).Error
+	return rows, err
+}
+
+// searchPostgres uses the built-in text search. The document expression must
+// match the GIN indexes created by migration 0005.
+func searchPostgres(db *gorm.DB, terms []string, limit int) ([]resultRow, error) {
+	prefixed := make([]string, len(terms))
+	for i, term := range terms {
+		prefixed[i] = term + ":*"
+	}
+
+	query := `
+		SELECT * FROM (
+			SELECT 'expense' AS type, e.id,
+				ts_headline('simple', e.name, q, @nameOpts) AS name,
+				ts_headline('simple', COALESCE(e.notes, ''), q, @notesOpts) AS snippet,
+				e.amount, to_char(e.expense_date, 'YYYY-MM-DD') AS date, c.name AS category_name,
+				ts_rank(setweight(to_tsvector('simple', e.name), 'A') ||
+					setweight(to_tsvector('simple', COALESCE(e.notes, '')), 'D'), q) AS rank
+			FROM expenses e
+			CROSS JOIN to_tsquery('simple', @query) q
+			LEFT JOIN categories c ON c.id = e.category_id AND c.deleted_at IS NULL
+			WHERE to_tsvector('simple', e.name || ' ' || COALESCE(e.notes, '')) @@ q
+				AND e.deleted_at IS NULL
+			UNION ALL
+			SELECT 'income' AS type, i.id,
+				ts_headline('simple', i.name, q, @nameOpts) AS name,
+				ts_headline('simple', COALESCE(i.notes, ''), q, @notesOpts) AS snippet,
+				i.amount, to_char(i.income_date, 'YYYY-MM-DD') AS date, NULL AS category_name,
+				ts_rank(setweight(to_tsvector('simple', i.name), 'A') ||
+					setweight(to_tsvector('simple', COALESCE(i.notes, '')), 'D'), q) AS rank
+			FROM incomes i
+			CROSS JOIN to_tsquery('simple', @query) q
+			WHERE to_tsvector('simple', i.name || ' ' || COALESCE(i.notes, '')) @@ q
+				AND i.deleted_at IS NULL
+		) results
+		ORDER BY rank DESC, date DESC
+		LIMIT @limit`
+
+	selectors := "StartSel=" + markStart + ", StopSel=" + markEnd
+	var rows []resultRow
+	err := db.Raw(query, map[string]interface{}{
+		"nameOpts":  selectors + ", HighlightAll=true",
+		"notesOpts": selectors + ", MaxWords=12, MinWords=4",
+		"query":     strings.Join(prefixed, " & "),
+		"limit":     limit,
+	}).Scan(&rows).Error
+	return rows, err
+}
+
+// searchLike matches each term anywhere in the name or notes, for SQLite
+// built without FTS5. Name matches rank first; the markers the full-text
+// queries add in SQL are added by markTerms instead.
+func searchLike(db *gorm.DB, terms []string, limit int) ([]resultRow, error) {
+	args := map[string]interface{}{"limit": limit}
+	for i, term := range terms {
+		// Terms are letters and digits only, so they hold no LIKE wildcards
+		args[fmt.Sprintf("term%d", i)] = "%" + term + "%"
+	}
+	// where requires every term in the name or notes of alias; rank counts
+	// the terms found in the name
+	where := func(alias string) string {
+		conditions := make([]string, len(terms))
+		for i := range terms {
+			conditions[i] = fmt.Sprintf(`(LOWER(%[1]s.name) LIKE @term%[2]d OR LOWER(COALESCE(%[1]s.notes, '')) LIKE @term%[2]d)`, alias, i)
+		}
+		return strings.Join(conditions, " AND ")
+	}
+	rank := func(alias string) string {
+		matches := make([]string, len(terms))
+		for i := range terms {
+			matches[i] = fmt.Sprintf(`(LOWER(%s.name) LIKE @term%d)`, alias, i)
+		}
+		return strings.Join(matches, " + ")
+	}
+
+	query := `
+		SELECT * FROM (
+			SELECT 'expense' AS type, e.id, e.name, COALESCE(e.notes, '') AS snippet,
+				e.amount, substr(e.expense_date, 1, 10) AS date, c.name AS category_name,
+				` + rank("e") + ` AS rank
+			FROM expenses e
+			LEFT JOIN categories c ON c.id = e.category_id AND c.deleted_at IS NULL
+			WHERE ` + where("e") + ` AND e.deleted_at IS NULL
+			UNION ALL
+			SELECT 'income' AS type, i.id, i.name, COALESCE(i.notes, '') AS snippet,
+				i.amount, substr(i.income_date, 1, 10) AS date, NULL AS category_name,
+				` + rank("i") + ` AS rank
+			FROM incomes i
+			WHERE ` + where("i") + ` AND i.deleted_at IS NULL
+		)
+		ORDER BY rank DESC, date DESC
+		LIMIT @limit`
+
+	var rows []resultRow
+	if err := db.Raw(query, args).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for i := range rows {
+		rows[i].Name = markTerms(rows[i].Name, terms)
+		rows[i].Snippet = excerpt(markTerms(rows[i].Snippet, terms), 12)
+	}
+	return rows, nil
+}
+
+// markTerms wraps every case-insensitive occurrence of the terms in match
+// markers. Text whose lowercase form changes length is left unmarked rather
+// than marked in the wrong place.
+func markTerms(text string, terms []string) string {
+	lower := strings.ToLower(text)
+	if len(lower) != len(text) {
+		return text
+	}
+	marked := make([]bool, len(text))
+	for _, term := range terms {
+		for start := 0; ; {
+			i := strings.Index(lower[start:], term)
+			if i < 0 {
+				break
+			}
+			for j := start + i; j < start+i+len(term); j++ {
+				marked[j] = true
+			}
+			start += i + len(term)
+		}
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(text); i++ {
+		if marked[i] && (i == 0 || !marked[i-1]) {
+			b.WriteString(markStart)
+		}
+		b.WriteByte(text[i])
+		if marked[i] && (i == len(text)-1 || !marked[i+1]) {
+			b.WriteString(markEnd)
+		}
+	}
+	return b.String()
+}
+
+// excerpt returns up to n words of marked text around its first match, like
+// the snippets of the full-text queries. Unmatched text is returned as is.
+func excerpt(text string, n int) string {
+	words := strings.Fields(text)
+	first := slices.IndexFunc(words, func(word string) bool {
+		return strings.Contains(word, markStart)
+	})
+	if first < 0 {
+		return text
+	}
+	start := max(0, min(first-n/3, len(words)-n))
+	end := min(len(words), start+n)
+	snippet := strings.Join(words[start:end], " ")
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(words) {
+		snippet += "…"
+	}
+	return snippet
+}
+
+// highlight escapes text for HTML and turns match markers into <mark> tags
+func highlight(text string) template.HTML {
+	escaped := html.EscapeString(text)
+	escaped = strings.ReplaceAll(escaped, markStart, "<mark>")
+	escaped = strings.ReplaceAll(escaped, markEnd, "</mark>")
+	return template.HTML(escaped)
+}
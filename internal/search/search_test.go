@@ -0,0 +1,249 @@
+package search
+
+import (
+	"html"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/g-linville/budgeting/internal/database"
+	"github.com/g-linville/budgeting/internal/database/dbtest"
+	"github.com/g-linville/budgeting/internal/models"
+	"gorm.io/gorm"
+)
+
+// seed adds the transactions searched below. SQLite indexes them through
+// FTS5 triggers and PostgreSQL through tsvector expressions, so both
+// backends must find the same rows.
+func seed(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	day := func(date string) time.Time {
+		d, err := time.ParseInLocation("2006-01-02", date, time.Local)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+
+	groceries := models.Category{Name: "Groceries", Kind: models.CategoryKindExpense}
+	if err := db.Create(&groceries).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	expenses := []models.Expense{
+		{Name: "Coffee beans", Amount: 1899, ExpenseDate: day("2024-03-02"), CategoryID: &groceries.ID},
+		{Name: "Hardware store", Amount: 4250, ExpenseDate: day("2024-03-05"), Notes: "Screws and a coffee grinder brush"},
+		{Name: "<b>Bold</b> & co", Amount: 500, ExpenseDate: day("2024-03-06")},
+		{Name: "Coffee filters", Amount: 650, ExpenseDate: day("2024-03-07")},
+	}
+	if err := db.Create(&expenses).Error; err != nil {
+		t.Fatal(err)
+	}
+	// Trashed transactions never show up
+	if err := db.Delete(&expenses[3]).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	incomes := []models.Income{
+		{Name: "Coffee shop refund", Amount: 300, IncomeDate: day("2024-03-03")},
+		{Name: "Salary", Amount: 500000, IncomeDate: day("2024-03-01"), Notes: "March payroll"},
+	}
+	if err := db.Create(&incomes).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+var searchTests = []struct {
+	text string
+	want []string // Names, in any order
+}{
+	{"coff", []string{"Coffee beans", "Coffee shop refund", "Hardware store"}},
+	{"COFFEE beans", []string{"Coffee beans"}},
+	{"coffee grinder", []string{"Hardware store"}},
+	{"payroll", []string{"Salary"}},
+	{"bold", []string{"<b>Bold</b> & co"}},
+	{"coffee tea", nil},
+	// FTS5 syntax in the input is read as plain words
+	{`coffee" OR "salary`, nil},
+	{"NEAR(coffee*", nil},
+	{"  ", nil},
+}
+
+func TestSearch(t *testing.T) {
+	dbtest.RunMigrated(t, func(t *testing.T, db *gorm.DB) {
+		seed(t, db)
+
+		for _, tt := range searchTests {
+			results, err := Search(db, tt.text, 20)
+			if err != nil {
+				t.Errorf("Search(%q): %v", tt.text, err)
+				continue
+			}
+			if names := resultNames(results); !slices.Equal(names, slices.Sorted(slices.Values(tt.want))) {
+				t.Errorf("Search(%q) = %q, want %q", tt.text, names, tt.want)
+			}
+		}
+	})
+}
+
+// The LIKE fallback must find what the full-text index finds. It is called
+// directly, since which one Search uses depends on the build tags.
+func TestSearchLike(t *testing.T) {
+	db := dbtest.Open(t, database.DriverSQLite)
+	if _, err := database.MigrateUp(db); err != nil {
+		t.Fatal(err)
+	}
+	seed(t, db)
+
+	for _, tt := range searchTests {
+		terms := searchTerms(tt.text)
+		if len(terms) == 0 {
+			continue
+		}
+		rows, err := searchLike(db, terms, 20)
+		if err != nil {
+			t.Errorf("searchLike(%q): %v", tt.text, err)
+			continue
+		}
+		var names []string
+		for _, row := range rows {
+			names = append(names, stripMarks(row.Name))
+		}
+		slices.Sort(names)
+		if !slices.Equal(names, slices.Sorted(slices.Values(tt.want))) {
+			t.Errorf("searchLike(%q) = %q, want %q", tt.text, names, tt.want)
+		}
+		if len(rows) > 0 && tt.text == "coff" && stripMarks(rows[len(rows)-1].Name) != "Hardware store" {
+			t.Errorf("searchLike(%q) = %q, want the notes match last", tt.text, names)
+		}
+	}
+}
+
+func TestMarkTerms(t *testing.T) {
+	tests := []struct {
+		text  string
+		terms []string
+		want  string
+	}{
+		{"Coffee beans", []string{"coffee"}, "[Coffee] beans"},
+		{"Coffee beans", []string{"coff", "bean"}, "[Coff]ee [bean]s"},
+		{"abab", []string{"ab"}, "[abab]"},
+		{"Salary", []string{"coffee"}, "Salary"},
+		{"İstanbul", []string{"stan"}, "İstanbul"},
+	}
+	for _, tt := range tests {
+		got := strings.NewReplacer(markStart, "[", markEnd, "]").Replace(markTerms(tt.text, tt.terms))
+		if got != tt.want {
+			t.Errorf("markTerms(%q, %q) = %q, want %q", tt.text, tt.terms, got, tt.want)
+		}
+	}
+}
+
+func TestExcerpt(t *testing.T) {
+	words := strings.Fields("one two three four five six seven eight nine ten eleven twelve thirteen fourteen fifteen sixteen")
+	tests := []struct {
+		match int // Index of the marked word, or -1
+		want  string
+	}{
+		{-1, strings.Join(words, " ")},
+		{0, "[one] two three four five six seven eight nine ten eleven twelve…"},
+		{8, "…five six seven eight [nine] ten eleven twelve thirteen fourteen fifteen sixteen"},
+		{15, "…five six seven eight nine ten eleven twelve thirteen fourteen fifteen [sixteen]"},
+	}
+	for _, tt := range tests {
+		marked := slices.Clone(words)
+		if tt.match >= 0 {
+			marked[tt.match] = markStart + marked[tt.match] + markEnd
+		}
+		got := strings.NewReplacer(markStart, "[", markEnd, "]").Replace(excerpt(strings.Join(marked, " "), 12))
+		if got != tt.want {
+			t.Errorf("excerpt around %d = %q, want %q", tt.match, got, tt.want)
+		}
+	}
+}
+
+func TestSearchHighlights(t *testing.T) {
+	dbtest.RunMigrated(t, func(t *testing.T, db *gorm.DB) {
+		seed(t, db)
+
+		results, err := Search(db, "coffee", 20)
+		if err != nil {
+			t.Fatal(err)
+		}
+		byName := make(map[string]Result)
+		for _, result := range results {
+			byName[stripMarks(string(result.Name))] = result
+		}
+
+		beans := byName["Coffee beans"]
+		if beans.Name != "<mark>Coffee</mark> beans" || beans.Snippet != "" {
+			t.Errorf("name match = %q, snippet %q", beans.Name, beans.Snippet)
+		}
+		if beans.Type != "expense" || beans.Date != "2024-03-02" || beans.Amount != 1899 ||
+			beans.Category == nil || *beans.Category != "Groceries" {
+			t.Errorf("name match = %+v", beans)
+		}
+
+		hardware := byName["Hardware store"]
+		if hardware.Name != "Hardware store" || !strings.Contains(string(hardware.Snippet), "<mark>coffee</mark>") {
+			t.Errorf("notes match = %q, snippet %q", hardware.Name, hardware.Snippet)
+		}
+
+		refund := byName["Coffee shop refund"]
+		if refund.Type != "income" || refund.Category != nil {
+			t.Errorf("income match = %+v", refund)
+		}
+
+		bold, err := Search(db, "bold", 20)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(bold) != 1 || bold[0].Name != "&lt;b&gt;<mark>Bold</mark>&lt;/b&gt; &amp; co" {
+			t.Errorf("escaped match = %+v", bold)
+		}
+	})
+}
+
+func TestSearchLimit(t *testing.T) {
+	dbtest.RunMigrated(t, func(t *testing.T, db *gorm.DB) {
+		seed(t, db)
+
+		results, err := Search(db, "coffee", 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 2 {
+			t.Errorf("got %d results, want 2", len(results))
+		}
+	})
+}
+
+// Matches in the name rank above matches in the notes
+func TestSearchRanksNameMatchesFirst(t *testing.T) {
+	dbtest.RunMigrated(t, func(t *testing.T, db *gorm.DB) {
+		seed(t, db)
+
+		results, err := Search(db, "coffee", 20)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 3 || stripMarks(string(results[2].Name)) != "Hardware store" {
+			t.Errorf("results = %+v, want the notes match last", results)
+		}
+	})
+}
+
+func resultNames(results []Result) []string {
+	var names []string
+	for _, result := range results {
+		names = append(names, stripMarks(string(result.Name)))
+	}
+	slices.Sort(names)
+	return names
+}
+
+// stripMarks turns a highlighted name back into the stored text
+func stripMarks(s string) string {
+	return html.UnescapeString(strings.NewReplacer("<mark>", "", "</mark>", "", markStart, "", markEnd, "").Replace(s))
+}
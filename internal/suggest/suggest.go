@@ -0,0 +1,221 @@
+// Package suggest learns to suggest a category from an expense's name. It
+// is a multinomial naive Bayes classifier over the words of past
+// categorized expenses, trained locally from the database on demand.
+package suggest
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/g-linville/budgeting/internal/models"
+	"github.com/g-linville/budgeting/internal/utils"
+	"gorm.io/gorm"
+)
+
+// MinConfidence is the lowest confidence at which a suggestion is offered
+const MinConfidence = 0.5
+
+// Example is a categorized expense name to learn from
+type Example struct {
+	Name       string
+	CategoryID uint
+}
+
+// Suggestion is the most likely category for a name
+type Suggestion struct {
+	CategoryID uint
+	Confidence float64 // Probability the model gives the category, 0-1
+}
+
+// Model holds word counts per category
+type Model struct {
+	docs        map[uint]int            // Examples per category
+	total       int                     // Examples overall
+	words       map[uint]map[string]int // Word counts per category
+	wordTotals  map[uint]int            // Words per category
+	vocabulary  map[string]bool
+	categoryIDs []uint // Sorted, so ties are broken the same way every time
+}
+
+// Tokenize splits a name into the words the model uses. Numbers are
+// dropped, since store and reference numbers rarely repeat.
+func Tokenize(name string) []string {
+	var words []string
+	seen := make(map[string]bool)
+	for _, word := range strings.Fields(utils.NormalizeName(name)) {
+		if seen[word] || strings.IndexFunc(word, unicode.IsLetter) < 0 {
+			continue
+		}
+		seen[word] = true
+		words = append(words, word)
+	}
+	return words
+}
+
+// Train builds a model from examples
+func Train(examples []Example) *Model {
+	m := &Model{
+		docs:       make(map[uint]int),
+		words:      make(map[uint]map[string]int),
+		wordTotals: make(map[uint]int),
+		vocabulary: make(map[string]bool),
+	}
+	for _, ex := range examples {
+		words := Tokenize(ex.Name)
+		if len(words) == 0 {
+			continue
+		}
+		if m.docs[ex.CategoryID] == 0 {
+			m.words[ex.CategoryID] = make(map[string]int)
+			m.categoryIDs = append(m.categoryIDs, ex.CategoryID)
+		}
+		m.docs[ex.CategoryID]++
+		m.total++
+		for _, word := range words {
+			m.words[ex.CategoryID][word]++
+			m.wordTotals[ex.CategoryID]++
+			m.vocabulary[word] = true
+		}
+	}
+	sort.Slice(m.categoryIDs, func(i, j int) bool { return m.categoryIDs[i] < m.categoryIDs[j] })
+	return m
+}
+
+// Predict returns the most likely category for name. It reports false when
+// the model has never seen any of the name's words.
+func (m *Model) Predict(name string) (Suggestion, bool) {
+	var words []string
+	for _, word := range Tokenize(name) {
+		if m.vocabulary[word] {
+			words = append(words, word)
+		}
+	}
+	if len(words) == 0 {
+		return Suggestion{}, false
+	}
+
+	// Log posteriors with add-one smoothing
+	vocab := float64(len(m.vocabulary))
+	scores := make([]float64, len(m.categoryIDs))
+	best := 0
+	for i, id := range m.categoryIDs {
+		score := math.Log(float64(m.docs[id]) / float64(m.total))
+		for _, word := range words {
+			score += math.Log((float64(m.words[id][word]) + 1) / (float64(m.wordTotals[id]) + vocab))
+		}
+		scores[i] = score
+		if score > scores[best] {
+			best = i
+		}
+	}
+
+	// Normalize to a probability
+	var sum float64
+	for _, score := range scores {
+		sum += math.Exp(score - scores[best])
+	}
+	return Suggestion{CategoryID: m.categoryIDs[best], Confidence: 1 / sum}, true
+}
+
+// Load reads the examples to train on: every categorized expense that is
+// not split or in the trash
+func Load(db *gorm.DB) ([]Example, error) {
+	var expenses []models.Expense
+	if err := db.Select("name", "category_id").
+		Where("category_id IS NOT NULL").
+		Where("NOT EXISTS (SELECT 1 FROM expense_splits s WHERE s.expense_id = expenses.id)").
+		Order("id").
+		Find(&expenses).Error; err != nil {
+		return nil, err
+	}
+
+	examples := make([]Example, len(expenses))
+	for i, e := range expenses {
+		examples[i] = Example{Name: e.Name, CategoryID: *e.CategoryID}
+	}
+	return examples, nil
+}
+
+// CategoryAccuracy is how well a single category is predicted
+type CategoryAccuracy struct {
+	CategoryID uint
+	Examples   int // Expenses in the category
+	Suggested  int // Of those, how many got a suggestion
+	Correct    int // Of those, how many suggestions were right
+}
+
+// Accuracy summarizes a cross-validated evaluation of the model
+type Accuracy struct {
+	CategoryAccuracy
+	Categories []CategoryAccuracy // Sorted by number of examples, largest first
+}
+
+// Evaluate measures accuracy by k-fold cross-validation: each example is
+// predicted by a model trained on the other folds. Only suggestions at or
+// above MinConfidence count, matching what the expense form shows.
+func Evaluate(examples []Example, folds int) Accuracy {
+	var acc Accuracy
+	byCategory := make(map[uint]*CategoryAccuracy)
+
+	for fold := 0; fold < folds; fold++ {
+		var training []Example
+		for i, ex := range examples {
+			if i%folds != fold {
+				training = append(training, ex)
+			}
+		}
+		m := Train(training)
+
+		for i := fold; i < len(examples); i += folds {
+			ex := examples[i]
+			c := byCategory[ex.CategoryID]
+			if c == nil {
+				c = &CategoryAccuracy{CategoryID: ex.CategoryID}
+				byCategory[ex.CategoryID] = c
+			}
+			c.Examples++
+			acc.Examples++
+
+			s, ok := m.Predict(ex.Name)
+			if !ok || s.Confidence < MinConfidence {
+				continue
+			}
+			c.Suggested++
+			acc.Suggested++
+			if s.CategoryID == ex.CategoryID {
+				c.Correct++
+				acc.Correct++
+			}
+		}
+	}
+
+	for _, c := range byCategory {
+		acc.Categories = append(acc.Categories, *c)
+	}
+	sort.Slice(acc.Categories, func(i, j int) bool {
+		if acc.Categories[i].Examples != acc.Categories[j].Examples {
+			return acc.Categories[i].Examples > acc.Categories[j].Examples
+		}
+		return acc.Categories[i].CategoryID < acc.Categories[j].CategoryID
+	})
+	return acc
+}
+
+// Coverage is the share of examples that got a suggestion, 0-100
+func (c CategoryAccuracy) Coverage() float64 {
+	return percent(c.Suggested, c.Examples)
+}
+
+// Precision is the share of suggestions that were right, 0-100
+func (c CategoryAccuracy) Precision() float64 {
+	return percent(c.Correct, c.Suggested)
+}
+
+func percent(n, of int) float64 {
+	if of == 0 {
+		return 0
+	}
+	return float64(n) / float64(of) * 100
+}
@@ -0,0 +1,204 @@
+package trash
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/g-linville/budgeting/internal/audit"
+	"github.com/g-linville/budgeting/internal/models"
+	"gorm.io/gorm"
+)
+
+// Actions recorded in the audit log for trash operations
+const (
+	ActionRestore = "restore"
+	ActionPurge   = "purge"
+)
+
+var (
+	// ErrNotTrashed is returned when restoring or purging a record that is not in the trash
+	ErrNotTrashed = errors.New("record is not in the trash")
+	// ErrNameTaken is returned when restoring a category whose name is now in use
+	ErrNameTaken = errors.New("a category with this name already exists")
+)
+
+// Item is a trashed record prepared for display
+type Item struct {
+	Type      string // audit entity type: "expense", "income" or "category"
+	ID        uint
+	Name      string
+	Amount    int // Minor units; zero for categories
+	DeletedAt time.Time
+}
+
+// trashable maps each trashable entity type to a constructor for its model
+var trashable = map[string]func() interface{}{
+	audit.EntityExpense:  func() interface{} { return &models.Expense{} },
+	audit.EntityIncome:   func() interface{} { return &models.Income{} },
+	audit.EntityCategory: func() interface{} { return &models.Category{} },
+}
+
+// IsTrashable reports whether records of entityType can be trashed
+func IsTrashable(entityType string) bool {
+	_, ok := trashable[entityType]
+	return ok
+}
+
+// List returns everything in the trash, most recently deleted first
+func List(db *gorm.DB) ([]Item, error) {
+	var items []Item
+
+	var expenses []models.Expense
+	if err := db.Unscoped().Where("deleted_at IS NOT NULL").Find(&expenses).Error; err != nil {
+		return nil, err
+	}
+	for _, e := range expenses {
+		items = append(items, Item{Type: audit.EntityExpense, ID: e.ID, Name: e.Name, Amount: e.Amount, DeletedAt: e.DeletedAt.Time})
+	}
+
+	var incomes []models.Income
+	if err := db.Unscoped().Where("deleted_at IS NOT NULL").Find(&incomes).Error; err != nil {
+		return nil, err
+	}
+	for _, i := range incomes {
+		items = append(items, Item{Type: audit.EntityIncome, ID: i.ID, Name: i.Name, Amount: i.Amount, DeletedAt: i.DeletedAt.Time})
+	}
+
+	var categories []models.Category
+	if err := db.Unscoped().Where("deleted_at IS NOT NULL").Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	for _, c := range categories {
+		items = append(items, Item{Type: audit.EntityCategory, ID: c.ID, Name: c.Name, DeletedAt: c.DeletedAt.Time})
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].DeletedAt.After(items[j].DeletedAt)
+	})
+
+	return items, nil
+}
+
+// Restore takes a record out of the trash
+func Restore(db *gorm.DB, entityType string, id uint) error {
+	newModel, ok := trashable[entityType]
+	if !ok {
+		return fmt.Errorf("unknown trash type %q", entityType)
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		record := newModel()
+		if err := tx.Unscoped().Where("deleted_at IS NOT NULL").First(record, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNotTrashed
+			}
+			return err
+		}
+
+		if category, ok := record.(*models.Category); ok {
+			var count int64
+			if err := tx.Model(&models.Category{}).
+				Where("kind = ? AND LOWER(name) = LOWER(?)", category.Kind, category.Name).
+				Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				return ErrNameTaken
+			}
+		}
+
+		before, err := audit.Snapshot(tx, entityType, id)
+		if err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(record).Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		return audit.Record(tx, entityType, id, ActionRestore, before)
+	})
+}
+
+// Purge permanently deletes a trashed record
+func Purge(db *gorm.DB, entityType string, id uint) error {
+	newModel, ok := trashable[entityType]
+	if !ok {
+		return fmt.Errorf("unknown trash type %q", entityType)
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		record := newModel()
+		if err := tx.Unscoped().Where("deleted_at IS NOT NULL").First(record, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNotTrashed
+			}
+			return err
+		}
+
+		return purgeRecord(tx, entityType, id, record)
+	})
+}
+
+// purgeRecord hard-deletes a record and records the purge in the audit log
+func purgeRecord(tx *gorm.DB, entityType string, id uint, record interface{}) error {
+	before, err := audit.Snapshot(tx, entityType, id)
+	if err != nil {
+		return err
+	}
+	if err := tx.Unscoped().Delete(record, id).Error; err != nil {
+		return err
+	}
+	return audit.Record(tx, entityType, id, ActionPurge, before)
+}
+
+// PurgeOlderThan permanently deletes everything trashed before cutoff and
+// returns how many records were removed
+func PurgeOlderThan(db *gorm.DB, cutoff time.Time) (int, error) {
+	count := 0
+
+	// Transactions first so that purging a category never has to null out
+	// references from rows that are about to be purged anyway
+	for _, entityType := range []string{audit.EntityExpense, audit.EntityIncome, audit.EntityCategory} {
+		newModel := trashable[entityType]
+
+		var ids []uint
+		if err := db.Unscoped().Model(newModel()).
+			Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+			Pluck("id", &ids).Error; err != nil {
+			return count, err
+		}
+
+		for _, id := range ids {
+			err := db.Transaction(func(tx *gorm.DB) error {
+				return purgeRecord(tx, entityType, id, newModel())
+			})
+			if err != nil {
+				return count, err
+			}
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// Run purges expired trash once an hour until ctx is cancelled
+func Run(ctx context.Context, db *gorm.DB, retention time.Duration) {
+	for {
+		count, err := PurgeOlderThan(db, time.Now().Add(-retention))
+		if err != nil {
+			log.Printf("Error purging trash: %v", err)
+		} else if count > 0 {
+			log.Printf("Purged %d expired record(s) from the trash", count)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Hour):
+		}
+	}
+}
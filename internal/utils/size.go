@@ -0,0 +1,16 @@
+package utils
+
+import "fmt"
+
+// FormatBytes formats a file size for display
+// Examples: 512 -> "512 B", 2048 -> "2.0 KB", 3145728 -> "3.0 MB"
+func FormatBytes(n int64) string {
+	switch {
+	case n < 1<<10:
+		return fmt.Sprintf("%d B", n)
+	case n < 1<<20:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	}
+}
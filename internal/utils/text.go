@@ -0,0 +1,16 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+// NormalizeName folds case and collapses whitespace and punctuation so that
+// transaction names compare equal however they were typed or exported
+// Examples: "AMAZON.COM" -> "amazon com", "  Joe's  Cafe" -> "joe s cafe"
+func NormalizeName(name string) string {
+	fields := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	return strings.Join(fields, " ")
+}
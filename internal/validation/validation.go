@@ -1,11 +1,18 @@
 package validation
 
 import (
+	"errors"
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/g-linville/budgeting/internal/currency"
+	"github.com/g-linville/budgeting/internal/locale"
+	"github.com/g-linville/budgeting/internal/models"
+	"github.com/g-linville/budgeting/internal/payees"
+	"github.com/g-linville/budgeting/internal/rules"
 	"github.com/g-linville/budgeting/internal/utils"
 )
 
@@ -36,9 +43,44 @@ func (v ValidationErrors) HasErrors() bool {
 	return len(v) > 0
 }
 
-// ValidateExpense validates expense input data
-// Returns the amount in cents and any validation errors
-func ValidateExpense(name, amountStr, dateStr string) (int, time.Time, ValidationErrors) {
+// parseAmount parses a positive amount in c written as in loc, either
+// plainly or as arithmetic such as "84.20/2". The error explains what is
+// wrong in terms suited to the user.
+func parseAmount(amountStr string, c currency.Currency, loc locale.Locale) (int, error) {
+	amount, err := loc.ParseExpression(amountStr, c)
+	switch {
+	case errors.Is(err, locale.ErrExpression):
+		return 0, fmt.Errorf("Cannot calculate amount: %s", strings.TrimPrefix(err.Error(), locale.ErrExpression.Error()+": "))
+	case errors.Is(err, currency.ErrPrecision) && c.Exponent == 0:
+		return 0, fmt.Errorf("%s amounts cannot have decimal places", c.Code)
+	case errors.Is(err, currency.ErrPrecision):
+		return 0, fmt.Errorf("%s amounts can have at most %d decimal places", c.Code, c.Exponent)
+	case errors.Is(err, currency.ErrOverflow):
+		return 0, fmt.Errorf("Amount is too large")
+	case err != nil || !amount.IsPositive():
+		return 0, fmt.Errorf("Amount must be a positive number")
+	}
+	return amount.Minor, nil
+}
+
+// PreviewAmount evaluates an amount typed into a form so it can be shown
+// before submission. It returns "" for a blank or plain amount, which
+// needs no preview.
+func PreviewAmount(amountStr string, c currency.Currency, loc locale.Locale) (string, error) {
+	if strings.TrimSpace(amountStr) == "" || !loc.IsExpression(amountStr, c) {
+		return "", nil
+	}
+	minor, err := parseAmount(amountStr, c, loc)
+	if err != nil {
+		return "", err
+	}
+	return loc.Format(minor, c), nil
+}
+
+// ValidateExpense validates expense input data for an amount entered in c,
+// written as in loc
+// Returns the amount in minor units of c and any validation errors
+func ValidateExpense(name, amountStr, dateStr string, c currency.Currency, loc locale.Locale) (int, time.Time, ValidationErrors) {
 	var errors ValidationErrors
 
 	// Validate name
@@ -63,11 +105,11 @@ func ValidateExpense(name, amountStr, dateStr string) (int, time.Time, Validatio
 			Message: "Amount is required",
 		})
 	} else {
-		cents, err := utils.DollarsToCents(amountStr)
+		cents, err := parseAmount(amountStr, c, loc)
 		if err != nil {
 			errors = append(errors, ValidationError{
 				Field:   "amount",
-				Message: "Amount must be a positive number",
+				Message: err.Error(),
 			})
 		} else {
 			amountCents = cents
@@ -94,11 +136,21 @@ func ValidateExpense(name, amountStr, dateStr string) (int, time.Time, Validatio
 	return amountCents, date, errors
 }
 
-// ValidateIncome validates income input data
-// Returns the amount in cents and any validation errors
-func ValidateIncome(name, amountStr, dateStr string) (int, time.Time, ValidationErrors) {
-	// Income validation is identical to expense validation (just no category)
-	return ValidateExpense(name, amountStr, dateStr)
+// ValidateIncome validates income input data for an amount entered in c,
+// written as in loc
+// Returns the amount in minor units of c and any validation errors
+func ValidateIncome(name, amountStr, dateStr string, c currency.Currency, loc locale.Locale) (int, time.Time, ValidationErrors) {
+	// The fields are checked like an expense's; the handlers check the category
+	// against the database
+	return ValidateExpense(name, amountStr, dateStr, c, loc)
+}
+
+// ValidateCategoryKind validates that a category is for expenses or income
+func ValidateCategoryKind(kind string) ValidationErrors {
+	if kind != models.CategoryKindExpense && kind != models.CategoryKindIncome {
+		return ValidationErrors{{Field: "kind", Message: "Category must be for expenses or income"}}
+	}
+	return nil
 }
 
 // ValidateCategory validates category input data
@@ -146,3 +198,428 @@ func ValidateName(name string) error {
 	}
 	return nil
 }
+
+// ValidateCategoryParent checks that nesting a category under parentID keeps
+// the categories a tree. categoryID is 0 for a new category; parentOf maps
+// every existing category to its parent (nil for top-level ones).
+func ValidateCategoryParent(categoryID, parentID uint, parentOf map[uint]*uint) ValidationErrors {
+	var errors ValidationErrors
+
+	if _, ok := parentOf[parentID]; !ok {
+		return append(errors, ValidationError{
+			Field:   "parent_id",
+			Message: "Parent category not found",
+		})
+	}
+
+	// Walk up from the new parent; reaching the category itself means it
+	// would become its own ancestor. The step limit guards against a cycle
+	// already in the data.
+	current := &parentID
+	for steps := 0; current != nil && steps <= len(parentOf); steps++ {
+		if *current == categoryID {
+			return append(errors, ValidationError{
+				Field:   "parent_id",
+				Message: "A category cannot be nested under itself or one of its subcategories",
+			})
+		}
+		current = parentOf[*current]
+	}
+
+	return errors
+}
+
+// ValidateTags parses a comma-separated list of tags. Tags are normalized to
+// lowercase with runs of whitespace replaced by "-", and duplicates dropped.
+func ValidateTags(input string) ([]string, ValidationErrors) {
+	var errors ValidationErrors
+	var tags []string
+	seen := make(map[string]bool)
+
+	for _, raw := range strings.Split(input, ",") {
+		tag := strings.ToLower(strings.Join(strings.Fields(raw), "-"))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if len(tag) > 50 {
+			errors = append(errors, ValidationError{
+				Field:   "tags",
+				Message: fmt.Sprintf("Tag %q must be 50 characters or less", tag),
+			})
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+
+	if len(tags) > 20 {
+		errors = append(errors, ValidationError{
+			Field:   "tags",
+			Message: "No more than 20 tags are allowed",
+		})
+	}
+
+	return tags, errors
+}
+
+// Split is a validated line of a split expense
+type Split struct {
+	CategoryID *uint
+	Amount     int // Minor units of the expense's currency
+}
+
+// ValidateSplits validates the lines of a split expense, given as parallel
+// lists of category IDs ("" for uncategorized) and amounts in c, written as
+// in loc. Lines with neither are ignored. No lines means the expense is not
+// split; otherwise there must be at least two and they must sum to total.
+func ValidateSplits(total int, categoryIDs, amounts []string, c currency.Currency, loc locale.Locale) ([]Split, ValidationErrors) {
+	var errors ValidationErrors
+	var splits []Split
+
+	for i, amountStr := range amounts {
+		categoryIDStr := ""
+		if i < len(categoryIDs) {
+			categoryIDStr = strings.TrimSpace(categoryIDs[i])
+		}
+		if strings.TrimSpace(amountStr) == "" {
+			if categoryIDStr != "" {
+				errors = append(errors, ValidationError{
+					Field:   "split_amount",
+					Message: fmt.Sprintf("Split line %d needs an amount", i+1),
+				})
+			}
+			continue
+		}
+
+		cents, err := parseAmount(amountStr, c, loc)
+		if err != nil {
+			errors = append(errors, ValidationError{
+				Field:   "split_amount",
+				Message: fmt.Sprintf("Split line %d: %s", i+1, err),
+			})
+			continue
+		}
+
+		split := Split{Amount: cents}
+		if categoryIDStr != "" {
+			id, err := strconv.ParseUint(categoryIDStr, 10, 32)
+			if err != nil {
+				errors = append(errors, ValidationError{
+					Field:   "split_category_id",
+					Message: fmt.Sprintf("Split line %d has an invalid category", i+1),
+				})
+				continue
+			}
+			categoryID := uint(id)
+			split.CategoryID = &categoryID
+		}
+		splits = append(splits, split)
+	}
+
+	if errors.HasErrors() || len(splits) == 0 {
+		return nil, errors
+	}
+
+	if len(splits) < 2 {
+		errors = append(errors, ValidationError{
+			Field:   "split_amount",
+			Message: "A split needs at least two lines",
+		})
+	}
+
+	lineAmounts := make([]int, len(splits))
+	for i, split := range splits {
+		lineAmounts[i] = split.Amount
+	}
+	sum, err := currency.Sum(c, lineAmounts...)
+	if err != nil {
+		errors = append(errors, ValidationError{
+			Field:   "split_amount",
+			Message: "Split lines add up to more than can be stored",
+		})
+	} else if sum.Minor != total {
+		errors = append(errors, ValidationError{
+			Field: "split_amount",
+			Message: fmt.Sprintf("Split lines add up to %s but the expense is %s",
+				loc.Format(sum.Minor, c), loc.Format(total, c)),
+		})
+	}
+
+	if errors.HasErrors() {
+		return nil, errors
+	}
+	return splits, nil
+}
+
+// ValidateRefund validates refund input data for an expense with remaining
+// minor units of c not yet refunded, with the amount written as in loc.
+// Returns the amount and the date.
+func ValidateRefund(kind, amountStr, dateStr string, remaining int, c currency.Currency, loc locale.Locale) (int, time.Time, ValidationErrors) {
+	var errors ValidationErrors
+
+	if kind != "refund" && kind != "reimbursement" {
+		errors = append(errors, ValidationError{
+			Field:   "kind",
+			Message: "Choose refund or reimbursement",
+		})
+	}
+
+	var amountCents int
+	if strings.TrimSpace(amountStr) == "" {
+		errors = append(errors, ValidationError{
+			Field:   "amount",
+			Message: "Amount is required",
+		})
+	} else {
+		cents, err := parseAmount(amountStr, c, loc)
+		if err != nil {
+			errors = append(errors, ValidationError{
+				Field:   "amount",
+				Message: err.Error(),
+			})
+		} else if cents > remaining {
+			errors = append(errors, ValidationError{
+				Field:   "amount",
+				Message: fmt.Sprintf("Amount cannot exceed the %s not yet refunded", loc.Format(remaining, c)),
+			})
+		} else {
+			amountCents = cents
+		}
+	}
+
+	var date time.Time
+	if strings.TrimSpace(dateStr) == "" {
+		date = time.Now()
+	} else {
+		parsedDate, err := time.ParseInLocation("2006-01-02", dateStr, time.Local)
+		if err != nil {
+			errors = append(errors, ValidationError{
+				Field:   "refund_date",
+				Message: "Invalid date format (use YYYY-MM-DD)",
+			})
+		} else {
+			date = parsedDate
+		}
+	}
+
+	return amountCents, date, errors
+}
+
+// ValidatePayee validates a payee name
+func ValidatePayee(name string) ValidationErrors {
+	var errors ValidationErrors
+
+	trimmedName := strings.TrimSpace(name)
+	if utils.NormalizeName(trimmedName) == "" {
+		errors = append(errors, ValidationError{
+			Field:   "name",
+			Message: "Payee name is required",
+		})
+	} else if len(trimmedName) > 255 {
+		errors = append(errors, ValidationError{
+			Field:   "name",
+			Message: "Payee name must be 255 characters or less",
+		})
+	}
+
+	return errors
+}
+
+// ValidatePayeeAliases parses a comma-separated list of alias patterns,
+// normalized as by payees.NormalizePattern with duplicates dropped. A
+// pattern must contain some text besides wildcards.
+func ValidatePayeeAliases(input string) ([]string, ValidationErrors) {
+	var errors ValidationErrors
+	var patterns []string
+	seen := make(map[string]bool)
+
+	for _, raw := range strings.Split(input, ",") {
+		if strings.TrimSpace(raw) == "" {
+			continue
+		}
+		pattern := payees.NormalizePattern(raw)
+		if strings.Trim(pattern, "* ") == "" {
+			errors = append(errors, ValidationError{
+				Field:   "aliases",
+				Message: fmt.Sprintf("Alias %q would match every expense", strings.TrimSpace(raw)),
+			})
+			continue
+		}
+		if len(pattern) > 255 {
+			errors = append(errors, ValidationError{
+				Field:   "aliases",
+				Message: "Aliases must be 255 characters or less",
+			})
+			continue
+		}
+		if !seen[pattern] {
+			seen[pattern] = true
+			patterns = append(patterns, pattern)
+		}
+	}
+
+	return patterns, errors
+}
+
+// ValidateExpenseTemplate validates a saved expense template with an amount
+// in c, written as in loc. The amount is optional; the result is nil when it
+// is left blank.
+func ValidateExpenseTemplate(name, amountStr string, c currency.Currency, loc locale.Locale) (*int, ValidationErrors) {
+	var errors ValidationErrors
+
+	trimmedName := strings.TrimSpace(name)
+	if trimmedName == "" {
+		errors = append(errors, ValidationError{
+			Field:   "name",
+			Message: "Name is required",
+		})
+	} else if len(trimmedName) > 255 {
+		errors = append(errors, ValidationError{
+			Field:   "name",
+			Message: "Name must be 255 characters or less",
+		})
+	}
+
+	var amount *int
+	if strings.TrimSpace(amountStr) != "" {
+		cents, err := parseAmount(amountStr, c, loc)
+		if err != nil {
+			errors = append(errors, ValidationError{
+				Field:   "amount",
+				Message: err.Error(),
+			})
+		} else {
+			amount = &cents
+		}
+	}
+
+	return amount, errors
+}
+
+// Rule is a validated categorization rule. Payee and category are checked by
+// the caller, which passes whether each was chosen.
+type Rule struct {
+	Name         string
+	Priority     int
+	NameContains string
+	NameRegex    string
+	MinAmount    *int // Minor units of the base currency
+	MaxAmount    *int // Minor units of the base currency
+	Weekday      *int
+	Tags         []string
+}
+
+// ValidateRule validates a categorization rule, with amounts in c written as
+// in loc. A rule needs at least one condition and at least one action (a
+// category or tags).
+func ValidateRule(name, priorityStr, contains, regex, minStr, maxStr, weekdayStr, tagsStr string, hasPayee, hasCategory bool, c currency.Currency, loc locale.Locale) (Rule, ValidationErrors) {
+	var errors ValidationErrors
+	rule := Rule{
+		Name:         strings.TrimSpace(name),
+		NameContains: strings.TrimSpace(contains),
+		NameRegex:    strings.TrimSpace(regex),
+	}
+
+	if rule.Name == "" {
+		errors = append(errors, ValidationError{Field: "name", Message: "Rule name is required"})
+	} else if len(rule.Name) > 255 {
+		errors = append(errors, ValidationError{Field: "name", Message: "Rule name must be 255 characters or less"})
+	}
+
+	if strings.TrimSpace(priorityStr) != "" {
+		priority, err := strconv.Atoi(strings.TrimSpace(priorityStr))
+		if err != nil {
+			errors = append(errors, ValidationError{Field: "priority", Message: "Priority must be a whole number"})
+		}
+		rule.Priority = priority
+	}
+
+	if rule.NameRegex != "" {
+		if _, err := rules.CompileRegex(rule.NameRegex); err != nil {
+			errors = append(errors, ValidationError{Field: "name_regex", Message: "Invalid regular expression: " + err.Error()})
+		}
+	}
+
+	for _, amount := range []struct {
+		field string
+		value string
+		dest  **int
+	}{{"min_amount", minStr, &rule.MinAmount}, {"max_amount", maxStr, &rule.MaxAmount}} {
+		if strings.TrimSpace(amount.value) == "" {
+			continue
+		}
+		cents, err := parseAmount(amount.value, c, loc)
+		if err != nil {
+			errors = append(errors, ValidationError{Field: amount.field, Message: err.Error()})
+			continue
+		}
+		*amount.dest = &cents
+	}
+	if rule.MinAmount != nil && rule.MaxAmount != nil && *rule.MinAmount > *rule.MaxAmount {
+		errors = append(errors, ValidationError{Field: "max_amount", Message: "Maximum amount must not be less than the minimum"})
+	}
+
+	if weekdayStr != "" {
+		weekday, err := strconv.Atoi(weekdayStr)
+		if err != nil || weekday < 0 || weekday > 6 {
+			errors = append(errors, ValidationError{Field: "weekday", Message: "Invalid weekday"})
+		} else {
+			rule.Weekday = &weekday
+		}
+	}
+
+	tags, tagErrors := ValidateTags(tagsStr)
+	errors = append(errors, tagErrors...)
+	rule.Tags = tags
+
+	hasCondition := rule.NameContains != "" || rule.NameRegex != "" || rule.MinAmount != nil ||
+		rule.MaxAmount != nil || rule.Weekday != nil || hasPayee
+	if !hasCondition {
+		errors = append(errors, ValidationError{Field: "conditions", Message: "Set at least one condition"})
+	}
+	if !hasCategory && len(rule.Tags) == 0 {
+		errors = append(errors, ValidationError{Field: "actions", Message: "Choose a category or tags to assign"})
+	}
+
+	return rule, errors
+}
+
+// ValidateCurrency validates a currency code, where "" means the base currency
+func ValidateCurrency(code string, base currency.Currency) (currency.Currency, ValidationErrors) {
+	if strings.TrimSpace(code) == "" {
+		return base, nil
+	}
+	c, ok := currency.Lookup(code)
+	if !ok {
+		return base, ValidationErrors{{Field: "currency", Message: fmt.Sprintf("Unknown currency %q", strings.TrimSpace(code))}}
+	}
+	return c, nil
+}
+
+// ValidateExchangeRate validates a manually entered exchange rate, in units
+// of the base currency per unit of the given currency
+func ValidateExchangeRate(code, dateStr, rateStr string, base currency.Currency) (currency.Rate, ValidationErrors) {
+	var errors ValidationErrors
+	var rate currency.Rate
+
+	c, ok := currency.Lookup(code)
+	if !ok {
+		errors = append(errors, ValidationError{Field: "currency", Message: "Choose a currency"})
+	} else if c.Code == base.Code {
+		errors = append(errors, ValidationError{Field: "currency", Message: fmt.Sprintf("%s is the base currency", c.Code)})
+	}
+	rate.Currency = c
+
+	date, err := time.ParseInLocation("2006-01-02", strings.TrimSpace(dateStr), time.Local)
+	if err != nil {
+		errors = append(errors, ValidationError{Field: "rate_date", Message: "Invalid date format (use YYYY-MM-DD)"})
+	}
+	rate.Date = date
+
+	if _, err := currency.ParseRate(rateStr); err != nil {
+		errors = append(errors, ValidationError{Field: "rate", Message: "Rate must be a positive number"})
+	}
+	rate.Rate = strings.TrimSpace(rateStr)
+
+	return rate, errors
+}